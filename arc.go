@@ -0,0 +1,144 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "container/list"
+
+// arcPolicy implements the Adaptive Replacement Cache. The resident
+// elements are split into t1, used once since they were added, and t2,
+// used more than once. b1 and b2 remember the keys recently evicted from
+// t1 and t2, a key coming back through them shifts the target size p of
+// t1 toward the list it was evicted from
+type arcPolicy struct {
+	lru *lruCache
+	p   int
+
+	t1, t2   *list.List
+	resident map[*list.Element]queueNode
+
+	b1, b2 *list.List
+	ghosts map[Key]queueNode
+
+	// ghost is the list that remembered the key being added, if any
+	ghost *list.List
+}
+
+// queueNode is an element in one of the queues of a policy
+type queueNode struct {
+	lst  *list.List
+	elem *list.Element
+}
+
+func newARCPolicy(lru *lruCache) *arcPolicy {
+	p := &arcPolicy{lru: lru}
+	p.reset()
+	return p
+}
+
+func (p *arcPolicy) capacity() int {
+	return p.lru.capacity()
+}
+
+func (p *arcPolicy) adding(key Key) {
+	node, ok := p.ghosts[key]
+	if !ok {
+		return
+	}
+	switch node.lst {
+	case p.b1:
+		delta := 1
+		if p.b2.Len() > p.b1.Len() {
+			delta = p.b2.Len() / p.b1.Len()
+		}
+		if p.p += delta; p.p > p.capacity() {
+			p.p = p.capacity()
+		}
+	case p.b2:
+		delta := 1
+		if p.b1.Len() > p.b2.Len() {
+			delta = p.b1.Len() / p.b2.Len()
+		}
+		if p.p -= delta; p.p < 0 {
+			p.p = 0
+		}
+	}
+	p.ghost = node.lst
+	node.lst.Remove(node.elem)
+	delete(p.ghosts, key)
+}
+
+func (p *arcPolicy) add(elem *list.Element) {
+	lst := p.t1
+	if p.ghost != nil {
+		lst = p.t2
+		p.ghost = nil
+	}
+	p.resident[elem] = queueNode{lst: lst, elem: lst.PushFront(elem)}
+}
+
+func (p *arcPolicy) hit(elem *list.Element) {
+	node, ok := p.resident[elem]
+	if !ok {
+		return
+	}
+	node.lst.Remove(node.elem)
+	p.resident[elem] = queueNode{lst: p.t2, elem: p.t2.PushFront(elem)}
+}
+
+func (p *arcPolicy) remove(elem *list.Element, reason EvictionReason) {
+	node, ok := p.resident[elem]
+	if !ok {
+		return
+	}
+	node.lst.Remove(node.elem)
+	delete(p.resident, elem)
+	if reason != ReasonCapacity {
+		return
+	}
+
+	ghost := p.b1
+	if node.lst == p.t2 {
+		ghost = p.b2
+	}
+	key := elem.Value.(*listEntry).key
+	p.ghosts[key] = queueNode{lst: ghost, elem: ghost.PushFront(key)}
+	for ghost.Len() > p.capacity() {
+		delete(p.ghosts, ghost.Remove(ghost.Back()))
+	}
+}
+
+func (p *arcPolicy) victim() *list.Element {
+	var back *list.Element
+	if p.t1.Len() > 0 && (p.t1.Len() > p.p || (p.ghost == p.b2 && p.t1.Len() == p.p) || p.t2.Len() == 0) {
+		back = p.t1.Back()
+	} else {
+		back = p.t2.Back()
+	}
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*list.Element)
+}
+
+func (p *arcPolicy) reset() {
+	p.p = 0
+	p.t1, p.t2 = list.New(), list.New()
+	p.b1, p.b2 = list.New(), list.New()
+	p.resident = map[*list.Element]queueNode{}
+	p.ghosts = map[Key]queueNode{}
+	p.ghost = nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheAuditor(t *testing.T) {
+	type record struct {
+		op  string
+		key Key
+		hit bool
+	}
+	var records []record
+	var cache Interface
+	auditor := func(op string, key Key, hit bool) {
+		// the auditor runs outside of the lock, so using the cache is fine
+		cache.Len()
+		records = append(records, record{op, key, hit})
+	}
+	cache = NewCacheWithConfig(Config{MaxLen: 2, Auditor: auditor})
+
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Minute)
+	cache.Get("testkey1")
+	cache.Get("testkey3")
+	cache.Del("testkey1")
+	cache.DelE("testkey1")
+
+	expect := []record{
+		{"Put", "testkey1", false},
+		{"Put", "testkey1", true},
+		{"PutWithTimeout", "testkey2", false},
+		{"Get", "testkey1", true},
+		{"Get", "testkey3", false},
+		{"Del", "testkey1", true},
+		{"DelE", "testkey1", false},
+	}
+	if len(records) != len(expect) {
+		t.Fatalf("test audit records failed, expect %v, got %v", expect, records)
+	}
+	for i := range expect {
+		if records[i] != expect[i] {
+			t.Fatalf("test audit record %d failed, expect %v, got %v", i, expect[i], records[i])
+		}
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "time"
+
+// PutMulti puts all the entries for the default lifetime, under a single
+// acquisition of the lock. They are stored in no particular order, so
+// which ones are evicted when they do not all fit is unspecified
+func (lru *lruCache) PutMulti(entries map[Key]Value) {
+	lru.putMulti("PutMulti", entries, 0)
+}
+
+// PutMultiWithTimeout is the same as PutMulti with the timeout of
+// PutWithTimeout
+func (lru *lruCache) PutMultiWithTimeout(entries map[Key]Value, t time.Duration) {
+	lru.putMulti("PutMultiWithTimeout", entries, clampTimeout(t))
+}
+
+func (lru *lruCache) putMulti(op string, values map[Key]Value, t time.Duration) {
+	if lru.debouncer != nil {
+		// every key is debounced on its own
+		for key, value := range values {
+			lru.audit(op, key, lru.debouncedPut(key, value, t))
+		}
+		return
+	}
+	keys := make([]Key, 0, len(values))
+	for key, value := range values {
+		if lru.saveStore(key, value) {
+			keys = append(keys, key)
+		}
+	}
+	replaced := make([]bool, len(keys))
+	if !lru.bypassed() {
+		entries := make([]*listEntry, len(keys))
+		for i, key := range keys {
+			entries[i] = lru.newEntry(key, values[key], t)
+		}
+		lru.Lock()
+		for i, entry := range entries {
+			lru.logWAL(walRecord{Op: walPut, Key: keys[i], Value: values[keys[i]], Deadline: entry.deadTime})
+			replaced[i] = lru.store(entry)
+		}
+		lru.Unlock()
+	}
+	if lru.auditor != nil {
+		for i, key := range keys {
+			lru.audit(op, key, replaced[i])
+		}
+	}
+}
+
+// GetMulti returns the live values of the keys, read under a single
+// acquisition of the lock like as many Get calls. The keys missing from
+// the cache are then loaded from the Store, if any, one by one
+func (lru *lruCache) GetMulti(keys []Key) map[Key]Value {
+	values := make(map[Key]Value, len(keys))
+	if !lru.bypassed() {
+		lru.Lock()
+		for _, key := range keys {
+			elem, _ := lru.hash.get(key)
+			if value, ok := lru.access(elem); ok {
+				values[key] = value
+			}
+		}
+		lru.Unlock()
+	}
+	for _, key := range keys {
+		_, ok := values[key]
+		if !ok && lru.backing != nil {
+			var value Value
+			if value, ok = lru.loadStore(key); ok {
+				values[key] = value
+			}
+		}
+		lru.audit("GetMulti", key, ok)
+	}
+	return values
+}
+
+// DelMulti deletes the keys atomically, under a single acquisition of the
+// lock, and returns how many of them were in the cache, expired or not
+func (lru *lruCache) DelMulti(keys []Key) int {
+	lru.cancelPuts(keys...)
+	for _, key := range keys {
+		lru.deleteStore(key)
+	}
+	lru.Lock()
+	removed := lru.delMulti(keys)
+	lru.Unlock()
+	lru.publish(keys...)
+	if lru.auditor != nil {
+		for _, key := range keys {
+			lru.audit("DelMulti", key, removed[key])
+		}
+	}
+	return len(removed)
+}
+
+// delMulti deletes the keys and returns the ones removed, the lock must be
+// held
+func (lru *lruCache) delMulti(keys []Key) map[Key]bool {
+	removed := map[Key]bool{}
+	for _, key := range keys {
+		if elem, exists := lru.hash.get(key); exists {
+			lru.logWAL(walRecord{Op: walDel, Key: key})
+			lru.removeElem(elem, ReasonDeleted)
+			removed[key] = true
+		}
+	}
+	return removed
+}
+
+// DelFunc deletes the live entries fn returns true for under one lock,
+// and returns how many there were. fn is called with the lock held, so it
+// must not use the cache. The callbacks are fired with ReasonDeleted and
+// the keys are deleted from the Store as well
+func (lru *lruCache) DelFunc(fn func(key Key, value Value) bool) int {
+	return lru.delFound("DelFunc", func() []Key {
+		return lru.matching(fn)
+	})
+}
+
+// matching returns the keys of the live entries fn returns true for, the
+// lock must be held
+func (lru *lruCache) matching(fn func(key Key, value Value) bool) []Key {
+	var keys []Key
+	now := lru.now()
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			continue
+		}
+		if fn(entry.key, value) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+// delFound deletes the keys returned by find under the same lock, then
+// deletes them from the Store. op names the deletes for the Auditor
+func (lru *lruCache) delFound(op string, find func() []Key) int {
+	lru.Lock()
+	keys := find()
+	lru.cancelPuts(keys...)
+	removed := lru.delMulti(keys)
+	lru.Unlock()
+	found := make([]Key, 0, len(removed))
+	for _, key := range keys {
+		lru.deleteStore(key)
+		if removed[key] {
+			found = append(found, key)
+		}
+	}
+	lru.publish(found...)
+	if lru.auditor != nil {
+		for _, key := range keys {
+			lru.audit(op, key, removed[key])
+		}
+	}
+	return len(removed)
+}
+
+// DelFunc locks all the shards, in order, so that the entries are matched
+// and deleted atomically across the shards
+func (s *shardedCache) DelFunc(fn func(key Key, value Value) bool) int {
+	for _, shard := range s.shards {
+		shard.Lock()
+	}
+	keys := make([][]Key, len(s.shards))
+	removed := make([]map[Key]bool, len(s.shards))
+	n := 0
+	for i, shard := range s.shards {
+		keys[i] = shard.matching(fn)
+		shard.cancelPuts(keys[i]...)
+		removed[i] = shard.delMulti(keys[i])
+		n += len(removed[i])
+	}
+	for _, shard := range s.shards {
+		shard.Unlock()
+	}
+	found := make([]Key, 0, n)
+	for i, shard := range s.shards {
+		for _, key := range keys[i] {
+			shard.deleteStore(key)
+			shard.audit("DelFunc", key, removed[i][key])
+			found = append(found, key)
+		}
+	}
+	s.shards[0].publish(found...)
+	return n
+}
+
+// splitEntries groups the entries by shard
+func (s *shardedCache) splitEntries(entries map[Key]Value) []map[Key]Value {
+	parts := make([]map[Key]Value, len(s.shards))
+	for key, value := range entries {
+		i := hashKey(key) % uint64(len(s.shards))
+		if parts[i] == nil {
+			parts[i] = map[Key]Value{}
+		}
+		parts[i][key] = value
+	}
+	return parts
+}
+
+// PutMulti locks every shard once, one after the other
+func (s *shardedCache) PutMulti(entries map[Key]Value) {
+	for i, part := range s.splitEntries(entries) {
+		if part != nil {
+			s.shards[i].PutMulti(part)
+		}
+	}
+}
+
+func (s *shardedCache) PutMultiWithTimeout(entries map[Key]Value, t time.Duration) {
+	for i, part := range s.splitEntries(entries) {
+		if part != nil {
+			s.shards[i].PutMultiWithTimeout(part, t)
+		}
+	}
+}
+
+// splitKeys groups the keys by shard
+func (s *shardedCache) splitKeys(keys []Key) [][]Key {
+	parts := make([][]Key, len(s.shards))
+	for _, key := range keys {
+		i := hashKey(key) % uint64(len(s.shards))
+		parts[i] = append(parts[i], key)
+	}
+	return parts
+}
+
+func (s *shardedCache) GetMulti(keys []Key) map[Key]Value {
+	values := make(map[Key]Value, len(keys))
+	for i, part := range s.splitKeys(keys) {
+		if len(part) == 0 {
+			continue
+		}
+		for key, value := range s.shards[i].GetMulti(part) {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// DelMulti locks all the shards holding the keys, in order, so that the
+// keys are deleted atomically across the shards
+func (s *shardedCache) DelMulti(keys []Key) int {
+	parts := s.splitKeys(keys)
+	for i, part := range parts {
+		s.shards[i].cancelPuts(part...)
+		for _, key := range part {
+			s.shards[i].deleteStore(key)
+		}
+	}
+	for i, part := range parts {
+		if len(part) > 0 {
+			s.shards[i].Lock()
+		}
+	}
+	removed := make([]map[Key]bool, len(parts))
+	n := 0
+	for i, part := range parts {
+		if len(part) > 0 {
+			removed[i] = s.shards[i].delMulti(part)
+			n += len(removed[i])
+		}
+	}
+	for i, part := range parts {
+		if len(part) > 0 {
+			s.shards[i].Unlock()
+		}
+	}
+	s.shards[0].publish(keys...)
+	for i, part := range parts {
+		for _, key := range part {
+			s.shards[i].audit("DelMulti", key, removed[i][key])
+		}
+	}
+	return n
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestPutMulti(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		var audited int
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, Auditor: func(op string, key Key, hit bool) {
+			if op == "PutMulti" {
+				audited++
+			}
+		}})
+		cache.Put("testkey1", "old")
+		cache.PutMulti(map[Key]Value{"testkey1": "testvalue1", "testkey2": "testvalue2", 3: "testvalue3"})
+		cache.PutMultiWithTimeout(map[Key]Value{"testkey4": "testvalue4"}, NoExpiration)
+
+		tests := []struct {
+			key   Key
+			value Value
+		}{
+			{"testkey1", "testvalue1"},
+			{"testkey2", "testvalue2"},
+			{3, "testvalue3"},
+			{"testkey4", "testvalue4"},
+		}
+		for _, test := range tests {
+			if v, ok := cache.Get(test.key); !ok || v != test.value {
+				t.Fatalf("test %d shards key %v failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+		}
+		if d, _ := cache.TTL("testkey4"); d != NoExpiration {
+			t.Fatalf("test %d shards key %s ttl failed, expect %v, got %v", shards, "testkey4", NoExpiration, d)
+		}
+		if d, _ := cache.TTL("testkey2"); d <= 0 || d > DefaultCacheTime {
+			t.Fatalf("test %d shards key %s ttl failed, expect at most %v, got %v", shards, "testkey2", DefaultCacheTime, d)
+		}
+		if audited != 3 {
+			t.Fatalf("test %d shards audit failed, expect %v, got %v", shards, 3, audited)
+		}
+		cache.Close()
+	}
+}
+
+func TestPutMultiEviction(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	defer cache.Close()
+	cache.PutMultiWithTimeout(map[Key]Value{1: 1, 2: 2, 3: 3}, time.Minute)
+	if report := cache.(Inspector).Inspect(); !report.Consistent || report.ListLen != 2 {
+		t.Fatalf("test len failed, expect %v, got %+v", 2, report)
+	}
+}
+
+func TestGetMulti(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		cache.PutMulti(map[Key]Value{"testkey1": "testvalue1", 2: "testvalue2"})
+		cache.PutWithTimeout("testkey3", "testvalue3", time.Second)
+		time.Sleep(1100 * time.Millisecond)
+
+		values := cache.GetMulti([]Key{"testkey1", 2, "testkey3", "testkey4"})
+		expect := map[Key]Value{"testkey1": "testvalue1", 2: "testvalue2"}
+		if len(values) != len(expect) || values["testkey1"] != expect["testkey1"] || values[2] != expect[2] {
+			t.Fatalf("test %d shards values failed, expect %v, got %v", shards, expect, values)
+		}
+		if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Expirations != 1 {
+			t.Fatalf("test %d shards stats failed, got %+v", shards, stats)
+		}
+		cache.Close()
+	}
+}
+
+func TestTieredGetMulti(t *testing.T) {
+	l1 := NewCacheWithConfig(Config{MaxLen: 10})
+	l2 := NewCacheWithConfig(Config{MaxLen: 10})
+	cache := NewTiered(l1, l2)
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	l2.PutWithTimeout("testkey2", "testvalue2", time.Minute)
+
+	values := cache.GetMulti([]Key{"testkey1", "testkey2", "testkey3"})
+	if len(values) != 2 || values["testkey2"] != "testvalue2" {
+		t.Fatalf("test values failed, expect %v, got %v", 2, values)
+	}
+	if d, ok := l1.TTL("testkey2"); !ok || d > time.Minute {
+		t.Fatalf("test promoted key %s failed, expect at most %v, got %v", "testkey2", time.Minute, d)
+	}
+}
+
+func TestDelMulti(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		var evicted []Key
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+			if reason == ReasonDeleted {
+				evicted = append(evicted, key)
+			}
+		}})
+		cache.PutMulti(map[Key]Value{"testkey1": 1, "testkey2": 2, 3: 3, "testkey4": 4})
+
+		if n := cache.DelMulti([]Key{"testkey1", 3, "testkey5", "testkey1"}); n != 2 {
+			t.Fatalf("test %d shards removed failed, expect %v, got %v", shards, 2, n)
+		}
+		if len(evicted) != 2 || cache.Len() != 2 || cache.Contains("testkey1") || cache.Contains(3) {
+			t.Fatalf("test %d shards deleted keys failed, expect %v left, got %v", shards, 2, cache.Keys())
+		}
+		cache.Close()
+	}
+}
+
+func TestDelFunc(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		var deleted int
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+			if reason == ReasonDeleted {
+				deleted++
+			}
+		}})
+		for i := 0; i < 10; i++ {
+			cache.Put(i, i)
+		}
+		cache.PutWithDeadline(10, 10, time.Now().Add(10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+
+		even := func(key Key, value Value) bool { return value.(int)%2 == 0 }
+		if n := cache.DelFunc(even); n != 5 {
+			t.Fatalf("test %d shards delete failed, expect %v, got %v", shards, 5, n)
+		}
+		if n := cache.DelFunc(even); n != 0 {
+			t.Fatalf("test %d shards delete failed, expect %v, got %v", shards, 0, n)
+		}
+		if deleted != 5 {
+			t.Fatalf("test %d shards callbacks failed, expect %v, got %v", shards, 5, deleted)
+		}
+		for i := 1; i < 10; i += 2 {
+			if v, _ := cache.Get(i); v != i {
+				t.Fatalf("test %d shards key %v failed, expect %v, got %v", shards, i, i, v)
+			}
+		}
+		cache.Close()
+	}
+}
@@ -0,0 +1,1101 @@
+//
+//Copyright 2020 leopoldxx@gmail.com.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: cache.proto
+
+package cachegrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Cache_Get_FullMethodName            = "/leopoldxx.cache.Cache/Get"
+	Cache_Contains_FullMethodName       = "/leopoldxx.cache.Cache/Contains"
+	Cache_TTL_FullMethodName            = "/leopoldxx.cache.Cache/TTL"
+	Cache_Touch_FullMethodName          = "/leopoldxx.cache.Cache/Touch"
+	Cache_Put_FullMethodName            = "/leopoldxx.cache.Cache/Put"
+	Cache_PutNotFound_FullMethodName    = "/leopoldxx.cache.Cache/PutNotFound"
+	Cache_GetOrStore_FullMethodName     = "/leopoldxx.cache.Cache/GetOrStore"
+	Cache_Add_FullMethodName            = "/leopoldxx.cache.Cache/Add"
+	Cache_Replace_FullMethodName        = "/leopoldxx.cache.Cache/Replace"
+	Cache_CompareAndSwap_FullMethodName = "/leopoldxx.cache.Cache/CompareAndSwap"
+	Cache_IncrementInt64_FullMethodName = "/leopoldxx.cache.Cache/IncrementInt64"
+	Cache_PutTagged_FullMethodName      = "/leopoldxx.cache.Cache/PutTagged"
+	Cache_InvalidateTag_FullMethodName  = "/leopoldxx.cache.Cache/InvalidateTag"
+	Cache_Del_FullMethodName            = "/leopoldxx.cache.Cache/Del"
+	Cache_DelPrefix_FullMethodName      = "/leopoldxx.cache.Cache/DelPrefix"
+	Cache_RemoveOldest_FullMethodName   = "/leopoldxx.cache.Cache/RemoveOldest"
+	Cache_GetOldest_FullMethodName      = "/leopoldxx.cache.Cache/GetOldest"
+	Cache_GetNewest_FullMethodName      = "/leopoldxx.cache.Cache/GetNewest"
+	Cache_GetEntry_FullMethodName       = "/leopoldxx.cache.Cache/GetEntry"
+	Cache_Keys_FullMethodName           = "/leopoldxx.cache.Cache/Keys"
+	Cache_Range_FullMethodName          = "/leopoldxx.cache.Cache/Range"
+	Cache_Snapshot_FullMethodName       = "/leopoldxx.cache.Cache/Snapshot"
+	Cache_Stats_FullMethodName          = "/leopoldxx.cache.Cache/Stats"
+	Cache_Resize_FullMethodName         = "/leopoldxx.cache.Cache/Resize"
+	Cache_Flush_FullMethodName          = "/leopoldxx.cache.Cache/Flush"
+	Cache_Purge_FullMethodName          = "/leopoldxx.cache.Cache/Purge"
+)
+
+// CacheClient is the client API for Cache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CacheClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	Contains(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*ContainsResponse, error)
+	TTL(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*TTLResponse, error)
+	Touch(ctx context.Context, in *TouchRequest, opts ...grpc.CallOption) (*TouchResponse, error)
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Empty, error)
+	// PutNotFound puts cache.NotFound for the negative lifetime of the
+	// served cache
+	PutNotFound(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetOrStore(ctx context.Context, in *GetOrStoreRequest, opts ...grpc.CallOption) (*GetOrStoreResponse, error)
+	// Add ignores the timeout and deadline of the request, the key is added
+	// for the default lifetime
+	Add(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*AddResponse, error)
+	// Replace ignores the timeout and deadline of the request as well
+	Replace(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*ReplaceResponse, error)
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error)
+	// IncrementInt64 fails with FAILED_PRECONDITION if the value is not an
+	// integer
+	IncrementInt64(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	// PutTagged ignores the timeout and deadline of the request as well
+	PutTagged(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Empty, error)
+	InvalidateTag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*InvalidateTagResponse, error)
+	Del(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*DelResponse, error)
+	DelPrefix(ctx context.Context, in *PrefixRequest, opts ...grpc.CallOption) (*DelPrefixResponse, error)
+	RemoveOldest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RemoveOldestResponse, error)
+	GetOldest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntryResponse, error)
+	GetNewest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntryResponse, error)
+	// GetEntry returns the entry of the key with its metadata, it is not
+	// counted as a read
+	GetEntry(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*EntryResponse, error)
+	Keys(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*KeysResponse, error)
+	// Range streams the live entries
+	Range(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Cache_RangeClient, error)
+	// Snapshot returns the live entries copied at once
+	Snapshot(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SnapshotResponse, error)
+	Stats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatsResponse, error)
+	Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*Empty, error)
+	Flush(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Purge(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type cacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCacheClient(cc grpc.ClientConnInterface) CacheClient {
+	return &cacheClient{cc}
+}
+
+func (c *cacheClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, Cache_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Contains(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*ContainsResponse, error) {
+	out := new(ContainsResponse)
+	err := c.cc.Invoke(ctx, Cache_Contains_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) TTL(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*TTLResponse, error) {
+	out := new(TTLResponse)
+	err := c.cc.Invoke(ctx, Cache_TTL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Touch(ctx context.Context, in *TouchRequest, opts ...grpc.CallOption) (*TouchResponse, error) {
+	out := new(TouchResponse)
+	err := c.cc.Invoke(ctx, Cache_Touch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_Put_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) PutNotFound(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_PutNotFound_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) GetOrStore(ctx context.Context, in *GetOrStoreRequest, opts ...grpc.CallOption) (*GetOrStoreResponse, error) {
+	out := new(GetOrStoreResponse)
+	err := c.cc.Invoke(ctx, Cache_GetOrStore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Add(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*AddResponse, error) {
+	out := new(AddResponse)
+	err := c.cc.Invoke(ctx, Cache_Add_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Replace(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*ReplaceResponse, error) {
+	out := new(ReplaceResponse)
+	err := c.cc.Invoke(ctx, Cache_Replace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error) {
+	out := new(CompareAndSwapResponse)
+	err := c.cc.Invoke(ctx, Cache_CompareAndSwap_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) IncrementInt64(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, Cache_IncrementInt64_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) PutTagged(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_PutTagged_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) InvalidateTag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*InvalidateTagResponse, error) {
+	out := new(InvalidateTagResponse)
+	err := c.cc.Invoke(ctx, Cache_InvalidateTag_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Del(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*DelResponse, error) {
+	out := new(DelResponse)
+	err := c.cc.Invoke(ctx, Cache_Del_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) DelPrefix(ctx context.Context, in *PrefixRequest, opts ...grpc.CallOption) (*DelPrefixResponse, error) {
+	out := new(DelPrefixResponse)
+	err := c.cc.Invoke(ctx, Cache_DelPrefix_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) RemoveOldest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RemoveOldestResponse, error) {
+	out := new(RemoveOldestResponse)
+	err := c.cc.Invoke(ctx, Cache_RemoveOldest_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) GetOldest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntryResponse, error) {
+	out := new(EntryResponse)
+	err := c.cc.Invoke(ctx, Cache_GetOldest_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) GetNewest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntryResponse, error) {
+	out := new(EntryResponse)
+	err := c.cc.Invoke(ctx, Cache_GetNewest_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) GetEntry(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*EntryResponse, error) {
+	out := new(EntryResponse)
+	err := c.cc.Invoke(ctx, Cache_GetEntry_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Keys(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*KeysResponse, error) {
+	out := new(KeysResponse)
+	err := c.cc.Invoke(ctx, Cache_Keys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Range(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Cache_RangeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Cache_ServiceDesc.Streams[0], Cache_Range_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cache_RangeClient interface {
+	Recv() (*Entry, error)
+	grpc.ClientStream
+}
+
+type cacheRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheRangeClient) Recv() (*Entry, error) {
+	m := new(Entry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheClient) Snapshot(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, Cache_Snapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Stats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, Cache_Stats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_Resize_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Flush(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_Flush_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheClient) Purge(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Cache_Purge_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CacheServer is the server API for Cache service.
+// All implementations must embed UnimplementedCacheServer
+// for forward compatibility
+type CacheServer interface {
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	Contains(context.Context, *KeyRequest) (*ContainsResponse, error)
+	TTL(context.Context, *KeyRequest) (*TTLResponse, error)
+	Touch(context.Context, *TouchRequest) (*TouchResponse, error)
+	Put(context.Context, *PutRequest) (*Empty, error)
+	// PutNotFound puts cache.NotFound for the negative lifetime of the
+	// served cache
+	PutNotFound(context.Context, *KeyRequest) (*Empty, error)
+	GetOrStore(context.Context, *GetOrStoreRequest) (*GetOrStoreResponse, error)
+	// Add ignores the timeout and deadline of the request, the key is added
+	// for the default lifetime
+	Add(context.Context, *PutRequest) (*AddResponse, error)
+	// Replace ignores the timeout and deadline of the request as well
+	Replace(context.Context, *PutRequest) (*ReplaceResponse, error)
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error)
+	// IncrementInt64 fails with FAILED_PRECONDITION if the value is not an
+	// integer
+	IncrementInt64(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	// PutTagged ignores the timeout and deadline of the request as well
+	PutTagged(context.Context, *PutRequest) (*Empty, error)
+	InvalidateTag(context.Context, *TagRequest) (*InvalidateTagResponse, error)
+	Del(context.Context, *KeyRequest) (*DelResponse, error)
+	DelPrefix(context.Context, *PrefixRequest) (*DelPrefixResponse, error)
+	RemoveOldest(context.Context, *Empty) (*RemoveOldestResponse, error)
+	GetOldest(context.Context, *Empty) (*EntryResponse, error)
+	GetNewest(context.Context, *Empty) (*EntryResponse, error)
+	// GetEntry returns the entry of the key with its metadata, it is not
+	// counted as a read
+	GetEntry(context.Context, *KeyRequest) (*EntryResponse, error)
+	Keys(context.Context, *Empty) (*KeysResponse, error)
+	// Range streams the live entries
+	Range(*Empty, Cache_RangeServer) error
+	// Snapshot returns the live entries copied at once
+	Snapshot(context.Context, *Empty) (*SnapshotResponse, error)
+	Stats(context.Context, *Empty) (*StatsResponse, error)
+	Resize(context.Context, *ResizeRequest) (*Empty, error)
+	Flush(context.Context, *Empty) (*Empty, error)
+	Purge(context.Context, *Empty) (*Empty, error)
+	mustEmbedUnimplementedCacheServer()
+}
+
+// UnimplementedCacheServer must be embedded to have forward compatible implementations.
+type UnimplementedCacheServer struct {
+}
+
+func (UnimplementedCacheServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCacheServer) Contains(context.Context, *KeyRequest) (*ContainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Contains not implemented")
+}
+func (UnimplementedCacheServer) TTL(context.Context, *KeyRequest) (*TTLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TTL not implemented")
+}
+func (UnimplementedCacheServer) Touch(context.Context, *TouchRequest) (*TouchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Touch not implemented")
+}
+func (UnimplementedCacheServer) Put(context.Context, *PutRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
+func (UnimplementedCacheServer) PutNotFound(context.Context, *KeyRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutNotFound not implemented")
+}
+func (UnimplementedCacheServer) GetOrStore(context.Context, *GetOrStoreRequest) (*GetOrStoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrStore not implemented")
+}
+func (UnimplementedCacheServer) Add(context.Context, *PutRequest) (*AddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedCacheServer) Replace(context.Context, *PutRequest) (*ReplaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Replace not implemented")
+}
+func (UnimplementedCacheServer) CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
+func (UnimplementedCacheServer) IncrementInt64(context.Context, *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncrementInt64 not implemented")
+}
+func (UnimplementedCacheServer) PutTagged(context.Context, *PutRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutTagged not implemented")
+}
+func (UnimplementedCacheServer) InvalidateTag(context.Context, *TagRequest) (*InvalidateTagResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvalidateTag not implemented")
+}
+func (UnimplementedCacheServer) Del(context.Context, *KeyRequest) (*DelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Del not implemented")
+}
+func (UnimplementedCacheServer) DelPrefix(context.Context, *PrefixRequest) (*DelPrefixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DelPrefix not implemented")
+}
+func (UnimplementedCacheServer) RemoveOldest(context.Context, *Empty) (*RemoveOldestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveOldest not implemented")
+}
+func (UnimplementedCacheServer) GetOldest(context.Context, *Empty) (*EntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOldest not implemented")
+}
+func (UnimplementedCacheServer) GetNewest(context.Context, *Empty) (*EntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNewest not implemented")
+}
+func (UnimplementedCacheServer) GetEntry(context.Context, *KeyRequest) (*EntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntry not implemented")
+}
+func (UnimplementedCacheServer) Keys(context.Context, *Empty) (*KeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Keys not implemented")
+}
+func (UnimplementedCacheServer) Range(*Empty, Cache_RangeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Range not implemented")
+}
+func (UnimplementedCacheServer) Snapshot(context.Context, *Empty) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (UnimplementedCacheServer) Stats(context.Context, *Empty) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedCacheServer) Resize(context.Context, *ResizeRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resize not implemented")
+}
+func (UnimplementedCacheServer) Flush(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedCacheServer) Purge(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Purge not implemented")
+}
+func (UnimplementedCacheServer) mustEmbedUnimplementedCacheServer() {}
+
+// UnsafeCacheServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CacheServer will
+// result in compilation errors.
+type UnsafeCacheServer interface {
+	mustEmbedUnimplementedCacheServer()
+}
+
+func RegisterCacheServer(s grpc.ServiceRegistrar, srv CacheServer) {
+	s.RegisterService(&Cache_ServiceDesc, srv)
+}
+
+func _Cache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Contains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Contains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Contains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Contains(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_TTL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).TTL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_TTL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).TTL(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Touch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TouchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Touch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Touch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Touch(ctx, req.(*TouchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Put_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_PutNotFound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).PutNotFound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_PutNotFound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).PutNotFound(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_GetOrStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrStoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).GetOrStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_GetOrStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).GetOrStore(ctx, req.(*GetOrStoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Add(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Replace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Replace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Replace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Replace(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_CompareAndSwap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_IncrementInt64_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).IncrementInt64(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_IncrementInt64_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).IncrementInt64(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_PutTagged_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).PutTagged(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_PutTagged_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).PutTagged(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_InvalidateTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).InvalidateTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_InvalidateTag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).InvalidateTag(ctx, req.(*TagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Del_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Del(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Del_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Del(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_DelPrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).DelPrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_DelPrefix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).DelPrefix(ctx, req.(*PrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_RemoveOldest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).RemoveOldest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_RemoveOldest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).RemoveOldest(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_GetOldest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).GetOldest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_GetOldest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).GetOldest(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_GetNewest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).GetNewest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_GetNewest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).GetNewest(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_GetEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).GetEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_GetEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).GetEntry(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Keys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Keys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Keys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Keys(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Range_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheServer).Range(m, &cacheRangeServer{stream})
+}
+
+type Cache_RangeServer interface {
+	Send(*Entry) error
+	grpc.ServerStream
+}
+
+type cacheRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheRangeServer) Send(m *Entry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Cache_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Snapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Snapshot(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Stats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Resize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Resize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Resize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Resize(ctx, req.(*ResizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Flush_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Flush(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cache_Purge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServer).Purge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cache_Purge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServer).Purge(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Cache_ServiceDesc is the grpc.ServiceDesc for Cache service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Cache_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "leopoldxx.cache.Cache",
+	HandlerType: (*CacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _Cache_Get_Handler,
+		},
+		{
+			MethodName: "Contains",
+			Handler:    _Cache_Contains_Handler,
+		},
+		{
+			MethodName: "TTL",
+			Handler:    _Cache_TTL_Handler,
+		},
+		{
+			MethodName: "Touch",
+			Handler:    _Cache_Touch_Handler,
+		},
+		{
+			MethodName: "Put",
+			Handler:    _Cache_Put_Handler,
+		},
+		{
+			MethodName: "PutNotFound",
+			Handler:    _Cache_PutNotFound_Handler,
+		},
+		{
+			MethodName: "GetOrStore",
+			Handler:    _Cache_GetOrStore_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _Cache_Add_Handler,
+		},
+		{
+			MethodName: "Replace",
+			Handler:    _Cache_Replace_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _Cache_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "IncrementInt64",
+			Handler:    _Cache_IncrementInt64_Handler,
+		},
+		{
+			MethodName: "PutTagged",
+			Handler:    _Cache_PutTagged_Handler,
+		},
+		{
+			MethodName: "InvalidateTag",
+			Handler:    _Cache_InvalidateTag_Handler,
+		},
+		{
+			MethodName: "Del",
+			Handler:    _Cache_Del_Handler,
+		},
+		{
+			MethodName: "DelPrefix",
+			Handler:    _Cache_DelPrefix_Handler,
+		},
+		{
+			MethodName: "RemoveOldest",
+			Handler:    _Cache_RemoveOldest_Handler,
+		},
+		{
+			MethodName: "GetOldest",
+			Handler:    _Cache_GetOldest_Handler,
+		},
+		{
+			MethodName: "GetNewest",
+			Handler:    _Cache_GetNewest_Handler,
+		},
+		{
+			MethodName: "GetEntry",
+			Handler:    _Cache_GetEntry_Handler,
+		},
+		{
+			MethodName: "Keys",
+			Handler:    _Cache_Keys_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _Cache_Snapshot_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Cache_Stats_Handler,
+		},
+		{
+			MethodName: "Resize",
+			Handler:    _Cache_Resize_Handler,
+		},
+		{
+			MethodName: "Flush",
+			Handler:    _Cache_Flush_Handler,
+		},
+		{
+			MethodName: "Purge",
+			Handler:    _Cache_Purge_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Range",
+			Handler:       _Cache_Range_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cache.proto",
+}
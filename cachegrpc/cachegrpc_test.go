@@ -0,0 +1,313 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachegrpc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/cachegrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestCache serves a cache over an in-memory listener and returns the
+// served cache with a client of it
+func newTestCache(t *testing.T) (Interface, Interface) {
+	served := NewCacheWithConfig(Config{MaxLen: 100})
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	cachegrpc.RegisterCacheServer(server, cachegrpc.NewServer(served, nil))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("test dial failed, got %v", err)
+	}
+	return served, cachegrpc.New(cachegrpc.Config{Conn: conn, OnError: func(err error) {
+		t.Fatalf("test call failed, got %v", err)
+	}})
+}
+
+func TestGRPCCache(t *testing.T) {
+	served, cache := newTestCache(t)
+	defer served.Close()
+	defer cache.Close()
+
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", 2, time.Second)
+	cache.PutWithTimeout("testkey3", []byte("testvalue3"), NoExpiration)
+	cache.PutInt(4, "testvalue4")
+
+	tests := []struct {
+		key    Key
+		value  Value
+		exists bool
+	}{
+		{"testkey1", "testvalue1", true},
+		{"testkey2", 2, true},
+		{int64(4), "testvalue4", true},
+		{"testkey5", nil, false},
+	}
+	for _, test := range tests {
+		v, ok := cache.Get(test.key)
+		if ok != test.exists || v != test.value {
+			t.Fatalf("test key %v failed, expect %v, got %v", test.key, test.value, v)
+		}
+	}
+	// the process serving the cache sees the values as they were put
+	if v, _ := served.Peek("testkey2"); v != 2 {
+		t.Fatalf("test served key %s failed, expect %v, got %v", "testkey2", 2, v)
+	}
+	if d, ok := cache.TTL("testkey3"); !ok || d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey3", NoExpiration, d)
+	}
+	if cache.Len() != 4 || len(cache.Keys()) != 4 {
+		t.Fatalf("test len failed, expect %v, got %v", 4, cache.Len())
+	}
+	if !cache.Touch("testkey2", time.Minute) || !cache.Contains("testkey2") {
+		t.Fatalf("test touched key %s failed, expect %v, got %v", "testkey2", true, false)
+	}
+
+	if v, loaded := cache.GetOrStore("testkey1", "testvalue6", time.Second); !loaded || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if v, loaded := cache.GetOrStore("testkey6", "testvalue6", time.Second); loaded || v != "testvalue6" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey6", "testvalue6", v)
+	}
+	if v, ok := cache.DelE("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test deleted key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if _, ok := cache.DelE("testkey1"); ok {
+		t.Fatalf("test deleted key %s failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if stats := cache.Stats(); stats.Hits != 4 || stats.Misses != 2 || stats.Len != 4 {
+		t.Fatalf("test stats failed, got %+v", stats)
+	}
+
+	n := 0
+	cache.Range(func(key Key, value Value) bool {
+		n++
+		return true
+	})
+	if n != 4 {
+		t.Fatalf("test range failed, expect %v, got %v", 4, n)
+	}
+
+	// the entries can be moved to and from the other caches
+	var buf bytes.Buffer
+	if err := cache.(Persister).SaveTo(&buf); err != nil {
+		t.Fatalf("test save failed, got %v", err)
+	}
+	local := NewCacheWithConfig(Config{MaxLen: 10})
+	defer local.Close()
+	local.(Persister).ReplayWAL(&buf)
+	if v, _ := local.Get("4"); v != "testvalue4" {
+		t.Fatalf("test saved key %s failed, expect %v, got %v", "4", "testvalue4", v)
+	}
+	local.Put("testkey7", "testvalue7")
+	buf.Reset()
+	local.(Persister).SaveTo(&buf)
+	if err := cache.(Persister).ReplayWAL(&buf); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	if v, _ := cache.Get("testkey7"); v != "testvalue7" {
+		t.Fatalf("test replayed key %s failed, expect %v, got %v", "testkey7", "testvalue7", v)
+	}
+
+	cache.PutWithTimeout("testkey11", 11, time.Minute)
+	if cache.CompareAndSwap("testkey11", 12, 13) || !cache.CompareAndSwap("testkey11", 11, 12) {
+		t.Fatalf("test swap key %s failed, expect %v, got %v", "testkey11", 12, 11)
+	}
+	if v, _ := cache.Get("testkey11"); v != 12 {
+		t.Fatalf("test swapped key %s failed, expect %v, got %v", "testkey11", 12, v)
+	}
+	if d, ok := cache.TTL("testkey11"); !ok || d <= 0 || d > time.Minute {
+		t.Fatalf("test swapped key %s ttl failed, expect %v, got %v", "testkey11", time.Minute, d)
+	}
+
+	if !cache.Add("testkey12", 12) || cache.Add("testkey12", 13) {
+		t.Fatalf("test add key %s failed, expect %v, got %v", "testkey12", 12, 13)
+	}
+	if v, _ := cache.Get("testkey12"); v != 12 {
+		t.Fatalf("test added key %s failed, expect %v, got %v", "testkey12", 12, v)
+	}
+
+	if cache.Replace("testkey13", 13) || !cache.Replace("testkey12", 13) {
+		t.Fatalf("test replace key %s failed, expect %v, got %v", "testkey12", 13, 12)
+	}
+	if v, _ := cache.Get("testkey12"); v != 13 {
+		t.Fatalf("test replaced key %s failed, expect %v, got %v", "testkey12", 13, v)
+	}
+	if cache.Contains("testkey13") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey13", false, true)
+	}
+
+	if n, err := cache.IncrementInt64("testkey14", 14); n != 14 || err != nil {
+		t.Fatalf("test increment key %s failed, expect %v, got %v %v", "testkey14", 14, n, err)
+	}
+	if n, err := cache.DecrementInt64("testkey14", 4); n != 10 || err != nil {
+		t.Fatalf("test decrement key %s failed, expect %v, got %v %v", "testkey14", 10, n, err)
+	}
+	cache.Put("testkey15", "testvalue15")
+	if _, err := cache.IncrementInt64("testkey15", 1); err != ErrNotInteger {
+		t.Fatalf("test increment key %s failed, expect %v, got %v", "testkey15", ErrNotInteger, err)
+	}
+
+	for cache.Len() > 0 {
+		cache.(Evicter).RemoveOldest()
+	}
+	cache.Put("testkey16", 16)
+	if entry, ok := cache.(Evicter).GetNewest(); !ok || entry.Key != "testkey16" || entry.Value != 16 || entry.Deadline.IsZero() {
+		t.Fatalf("test newest entry failed, expect %v, got %+v", "testkey16", entry)
+	}
+	if key, value, ok := cache.(Evicter).RemoveOldest(); !ok || key != "testkey16" || value != 16 {
+		t.Fatalf("test remove oldest failed, expect %v, got %v %v", "testkey16", key, value)
+	}
+	if cache.Contains("testkey16") {
+		t.Fatalf("test removed key %s failed, expect %v, got %v", "testkey16", false, true)
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("test purge failed, expect %v, got %v", 0, cache.Len())
+	}
+
+	users := cache.Namespace("users")
+	cache.Put("testkey17", "testvalue17")
+	users.Put("testkey17", "user17")
+	if v, _ := cache.Get("testkey17"); v != "testvalue17" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey17", "testvalue17", v)
+	}
+	if v, _ := users.Get("testkey17"); v != "user17" {
+		t.Fatalf("test namespace key %s failed, expect %v, got %v", "testkey17", "user17", v)
+	}
+	if keys := users.Keys(); len(keys) != 1 || keys[0] != "testkey17" {
+		t.Fatalf("test namespace keys failed, expect %v, got %v", "[testkey17]", keys)
+	}
+	users.Purge()
+	if users.Len() != 0 || !cache.Contains("testkey17") {
+		t.Fatalf("test namespace purge failed, expect %v, got %v", 0, users.Len())
+	}
+
+	cache.PutTagged("testkey18", "testvalue18", "tag18")
+	cache.PutTagged("testkey19", "testvalue19", "tag18", "tag19")
+	if n := cache.InvalidateTag("tag18"); n != 2 {
+		t.Fatalf("test tag %s failed, expect %v, got %v", "tag18", 2, n)
+	}
+	if cache.Contains("testkey19") || cache.InvalidateTag("tag18") != 0 {
+		t.Fatalf("test invalidated key %s failed, expect %v, got %v", "testkey19", false, true)
+	}
+
+	cache.Put("user:[1]:name", "testvalue20")
+	cache.Put("user:[1]:mail", "testvalue20")
+	cache.Put("user:1:name", "testvalue20")
+	if n := cache.DelPrefix("user:[1]"); n != 2 {
+		t.Fatalf("test prefix %s failed, expect %v, got %v", "user:[1]", 2, n)
+	}
+	if !cache.Contains("user:1:name") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "user:1:name", true, false)
+	}
+
+	cache.Put("testkey21", 21)
+	cache.Put("testkey22", 22)
+	if n := cache.DelFunc(func(key Key, value Value) bool { return value == 21 }); n != 1 {
+		t.Fatalf("test delete failed, expect %v, got %v", 1, n)
+	}
+	if cache.Contains("testkey21") || !cache.Contains("testkey22") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey21", false, true)
+	}
+
+	notFound := func(key Key) (Value, error) { return nil, ErrNotFound }
+	if _, err := cache.GetOrLoad("testkey23", notFound); err != ErrNotFound {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey23", ErrNotFound, err)
+	}
+	if v, ok := cache.Get("testkey23"); !ok || v != NotFound {
+		t.Fatalf("test negative key %s failed, expect %v, got %v", "testkey23", NotFound, v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if v, err := cache.GetOrLoadCtx(ctx, "testkey24", func(ctx context.Context, key Key) (Value, error) {
+		return "testvalue24", nil
+	}); err != nil || v != "testvalue24" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v", "testkey24", "testvalue24", v, err)
+	}
+	cancel()
+	if _, _, err := cache.GetCtx(ctx, "testkey24"); err != context.Canceled {
+		t.Fatalf("test cancelled key %s failed, expect %v, got %v", "testkey24", context.Canceled, err)
+	}
+	if err := cache.PutCtx(ctx, "testkey25", "testvalue25"); err != context.Canceled {
+		t.Fatalf("test cancelled key %s failed, expect %v, got %v", "testkey25", context.Canceled, err)
+	}
+
+	entries := cache.(Snapshotter).Snapshot()
+	if len(entries) != cache.Len() {
+		t.Fatalf("test snapshot len failed, expect %v, got %v", cache.Len(), len(entries))
+	}
+	for _, entry := range entries {
+		if v, _ := cache.Peek(entry.Key); v != entry.Value {
+			t.Fatalf("test snapshot key %v failed, expect %v, got %v", entry.Key, v, entry.Value)
+		}
+	}
+
+	clone := cache.Clone()
+	if v, _ := clone.Get("testkey22"); v != 22 {
+		t.Fatalf("test cloned key %s failed, expect %v, got %v", "testkey22", 22, v)
+	}
+	clone.Del("testkey22")
+	if !cache.Contains("testkey22") {
+		t.Fatalf("test cloned key %s failed, expect %v, got %v", "testkey22", true, false)
+	}
+
+	cache.Get("testkey22")
+	if entry, ok := cache.GetEntry("testkey22"); !ok || entry.Key != "testkey22" || entry.Value != 22 || entry.Hits == 0 || entry.Created.IsZero() || entry.LastAccess.IsZero() {
+		t.Fatalf("test entry key %s failed, expect %v with its metadata, got %+v", "testkey22", 22, entry)
+	}
+}
+
+func TestGRPCCacheGetOrLoadPanic(t *testing.T) {
+	served, cache := newTestCache(t)
+	defer served.Close()
+	defer cache.Close()
+
+	var p *LoaderPanic
+	_, err := cache.GetOrLoad("testkey1", func(key Key) (Value, error) {
+		panic("testpanic1")
+	})
+	if !errors.As(err, &p) || p.Value != "testpanic1" {
+		t.Fatalf("test key %s panic failed, expect %v, got %v", "testkey1", "testpanic1", err)
+	}
+	// the key is not stuck once its loader panicked
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if v, err := cache.GetOrLoadCtx(ctx, "testkey1", func(ctx context.Context, key Key) (Value, error) {
+		return "testvalue1", nil
+	}); err != nil || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v", "testkey1", "testvalue1", v, err)
+	}
+}
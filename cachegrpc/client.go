@@ -0,0 +1,960 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachegrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/leopoldxx/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTimeout bounds every call of the client
+const DefaultTimeout = 5 * time.Second
+
+// Config of the gRPC client
+type Config struct {
+	Conn *grpc.ClientConn
+	// Timeout of every call, DefaultTimeout if not set
+	Timeout time.Duration
+	// Codec of the values, GobCodec if nil. It must be the one of the
+	// server
+	Codec Codec
+	// OnError will be called with the errors of the calls, which the
+	// methods without an error result turn into misses
+	OnError func(err error)
+}
+
+type client struct {
+	conn    *grpc.ClientConn
+	rpc     CacheClient
+	timeout time.Duration
+	codec   Codec
+	onError func(err error)
+
+	// prefix is prepended to the keys of a namespace, view is set on
+	// the namespaces, which share the connection of their parent
+	prefix string
+	view   bool
+
+	bypass int32
+	closed int32
+	loads  cache.LoadGroup
+	locks  *cache.StripedLocks
+}
+
+// New will create a cache over the connection of the config. The keys are
+// sent as their fmt.Sprint string, so the methods listing them, like Keys
+// and Range, return strings whatever the type they were put with. Closing
+// the cache closes the connection
+func New(config Config) cache.Interface {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	return &client{
+		conn:    config.Conn,
+		rpc:     NewCacheClient(config.Conn),
+		timeout: config.Timeout,
+		codec:   config.Codec,
+		onError: config.OnError,
+		locks:   new(cache.StripedLocks),
+	}
+}
+
+func (c *client) name(key cache.Key) string {
+	return c.prefix + fmt.Sprint(key)
+}
+
+// own reports whether a key listed by the server belongs to the
+// namespace of the client, and returns it without the prefix
+func (c *client) own(key string) (string, bool) {
+	if !strings.HasPrefix(key, c.prefix) {
+		return "", false
+	}
+	return key[len(c.prefix):], true
+}
+
+func (c *client) ctx() (context.Context, context.CancelFunc) {
+	return c.ctxFrom(context.Background())
+}
+
+// ctxFrom bounds the calls made on behalf of the context of a caller
+func (c *client) ctxFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, c.timeout)
+}
+
+func (c *client) fail(err error) {
+	if err != nil && c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *client) bypassed() bool {
+	return atomic.LoadInt32(&c.bypass) == 1 || atomic.LoadInt32(&c.closed) == 1
+}
+
+func (c *client) decode(data []byte, found bool) (cache.Value, bool) {
+	if !found {
+		return nil, false
+	}
+	value, err := c.codec.Unmarshal(data)
+	if err != nil {
+		c.fail(err)
+		return nil, false
+	}
+	return value, true
+}
+
+// put reports the errors to OnError, except the one of parent being done
+func (c *client) put(parent context.Context, req *PutRequest, value cache.Value) {
+	if c.bypassed() {
+		return
+	}
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	req.Value = data
+	ctx, cancel := c.ctxFrom(parent)
+	defer cancel()
+	if _, err = c.rpc.Put(ctx, req); parent.Err() == nil {
+		c.fail(err)
+	}
+}
+
+// Put caches the value for the default lifetime of the server's cache
+func (c *client) Put(key cache.Key, value cache.Value) {
+	c.put(context.Background(), &PutRequest{Key: c.name(key)}, value)
+}
+
+// PutCtx returns the error of ctx if it is done before the call completes
+func (c *client) PutCtx(ctx context.Context, key cache.Key, value cache.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.put(ctx, &PutRequest{Key: c.name(key)}, value)
+	return ctx.Err()
+}
+
+// PutNotFound puts cache.NotFound for the negative lifetime of the
+// server's cache
+func (c *client) PutNotFound(key cache.Key) {
+	if c.bypassed() {
+		return
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	_, err := c.rpc.PutNotFound(ctx, &KeyRequest{Key: c.name(key)})
+	c.fail(err)
+}
+
+func (c *client) PutWithTimeout(key cache.Key, value cache.Value, t time.Duration) {
+	c.put(context.Background(), &PutRequest{Key: c.name(key), Timeout: int64(t)}, value)
+}
+
+func (c *client) PutWithDeadline(key cache.Key, value cache.Value, deadline time.Time) {
+	if deadline.IsZero() {
+		c.PutWithTimeout(key, value, cache.NoExpiration)
+		return
+	}
+	c.put(context.Background(), &PutRequest{Key: c.name(key), Deadline: deadline.UnixNano()}, value)
+}
+
+func (c *client) PutString(key string, value cache.Value) {
+	c.Put(key, value)
+}
+
+func (c *client) PutInt(key int64, value cache.Value) {
+	c.Put(key, value)
+}
+
+// PutMulti puts the entries with one call each
+func (c *client) PutMulti(entries map[cache.Key]cache.Value) {
+	for key, value := range entries {
+		c.Put(key, value)
+	}
+}
+
+func (c *client) PutMultiWithTimeout(entries map[cache.Key]cache.Value, t time.Duration) {
+	for key, value := range entries {
+		c.PutWithTimeout(key, value, t)
+	}
+}
+
+// get reports the errors to OnError, except the one of parent being done
+func (c *client) get(parent context.Context, key cache.Key, peek bool) (cache.Value, bool) {
+	if c.bypassed() {
+		return nil, false
+	}
+	ctx, cancel := c.ctxFrom(parent)
+	defer cancel()
+	resp, err := c.rpc.Get(ctx, &GetRequest{Key: c.name(key), Peek: peek})
+	if err != nil {
+		if parent.Err() == nil {
+			c.fail(err)
+		}
+		return nil, false
+	}
+	return c.decode(resp.Value, resp.Found)
+}
+
+// GetCtx returns the error of ctx if it is done before the call completes
+func (c *client) GetCtx(ctx context.Context, key cache.Key) (cache.Value, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, ok := c.get(ctx, key, false)
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return value, ok, nil
+}
+
+func (c *client) Get(key cache.Key) (cache.Value, bool) {
+	return c.get(context.Background(), key, false)
+}
+
+// GetMulti reads the keys with one call each
+func (c *client) GetMulti(keys []cache.Key) map[cache.Key]cache.Value {
+	values := make(map[cache.Key]cache.Value, len(keys))
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func (c *client) Peek(key cache.Key) (cache.Value, bool) {
+	return c.get(context.Background(), key, true)
+}
+
+func (c *client) GetString(key string) (cache.Value, bool) {
+	return c.Get(key)
+}
+
+func (c *client) GetInt(key int64) (cache.Value, bool) {
+	return c.Get(key)
+}
+
+func (c *client) Contains(key cache.Key) bool {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Contains(ctx, &KeyRequest{Key: c.name(key)})
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return resp.Found
+}
+
+func (c *client) TTL(key cache.Key) (time.Duration, bool) {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.TTL(ctx, &KeyRequest{Key: c.name(key)})
+	if err != nil {
+		c.fail(err)
+		return 0, false
+	}
+	return time.Duration(resp.Ttl), resp.Found
+}
+
+func (c *client) Touch(key cache.Key, d time.Duration) bool {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Touch(ctx, &TouchRequest{Key: c.name(key), Timeout: int64(d)})
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return resp.Ok
+}
+
+func (c *client) GetOrStore(key cache.Key, def cache.Value, t time.Duration) (cache.Value, bool) {
+	if c.bypassed() {
+		return def, false
+	}
+	data, err := c.codec.Marshal(def)
+	if err != nil {
+		c.fail(err)
+		return def, false
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.GetOrStore(ctx, &GetOrStoreRequest{Key: c.name(key), Value: data, Timeout: int64(t)})
+	if err != nil {
+		c.fail(err)
+		return def, false
+	}
+	if !resp.Loaded {
+		return def, false
+	}
+	if value, ok := c.decode(resp.Value, true); ok {
+		return value, true
+	}
+	return def, false
+}
+
+// LockKey serializes the callers of this process by key, the other
+// processes are not locked out
+func (c *client) LockKey(key cache.Key) func() {
+	return c.locks.LockKey(c.name(key))
+}
+
+func (c *client) Add(key cache.Key, value cache.Value) bool {
+	if c.bypassed() {
+		return false
+	}
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Add(ctx, &PutRequest{Key: c.name(key), Value: data})
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return resp.Added
+}
+
+func (c *client) Replace(key cache.Key, value cache.Value) bool {
+	if c.bypassed() {
+		return false
+	}
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Replace(ctx, &PutRequest{Key: c.name(key), Value: data})
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return resp.Replaced
+}
+
+func (c *client) CompareAndSwap(key cache.Key, old, new cache.Value) bool {
+	if c.bypassed() {
+		return false
+	}
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.CompareAndSwap(ctx, &CompareAndSwapRequest{Key: c.name(key), Old: oldData, New: newData})
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return resp.Swapped
+}
+
+// IncrementInt64 returns cache.ErrNotInteger if the value of the key on
+// the server is not an integer
+func (c *client) IncrementInt64(key cache.Key, delta int64) (int64, error) {
+	if c.bypassed() {
+		return delta, nil
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.IncrementInt64(ctx, &IncrementRequest{Key: c.name(key), Delta: delta})
+	if status.Code(err) == codes.FailedPrecondition {
+		return 0, cache.ErrNotInteger
+	}
+	if err != nil {
+		c.fail(err)
+		return 0, err
+	}
+	return resp.Value, nil
+}
+
+func (c *client) DecrementInt64(key cache.Key, delta int64) (int64, error) {
+	return c.IncrementInt64(key, -delta)
+}
+
+// GetOrLoad shares the loads of the same key within this process only
+func (c *client) GetOrLoad(key cache.Key, load cache.LoadFunc) (cache.Value, error) {
+	return c.GetOrLoadCtx(context.Background(), key, func(ctx context.Context, key cache.Key) (cache.Value, error) {
+		return load(key)
+	})
+}
+
+// GetOrLoadCtx is GetOrLoad with a context, a caller waiting for the load
+// of another one stops waiting when its ctx is done. A panic of load is
+// returned as a *cache.LoaderPanic
+func (c *client) GetOrLoadCtx(ctx context.Context, key cache.Key, load cache.LoadCtxFunc) (cache.Value, error) {
+	value, ok, err := c.GetCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if value == cache.NotFound {
+			return nil, cache.ErrNotFound
+		}
+		return value, nil
+	}
+	return c.loads.Do(ctx, c.name(key), func() (cache.Value, error) {
+		value, err := load(ctx, key)
+		if err == nil {
+			c.put(ctx, &PutRequest{Key: c.name(key)}, value)
+		} else if errors.Is(err, cache.ErrNotFound) {
+			c.PutNotFound(key)
+		}
+		return value, err
+	})
+}
+
+func (c *client) Del(key cache.Key) cache.Value {
+	value, _ := c.DelE(key)
+	return value
+}
+
+func (c *client) DelE(key cache.Key) (cache.Value, bool) {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Del(ctx, &KeyRequest{Key: c.name(key)})
+	if err != nil {
+		c.fail(err)
+		return nil, false
+	}
+	return c.decode(resp.Value, resp.Found)
+}
+
+// DelMulti deletes the keys with one call each, it is not atomic
+func (c *client) DelMulti(keys []cache.Key) int {
+	n := 0
+	for _, key := range keys {
+		if _, ok := c.DelE(key); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// DelPrefix deletes the keys of the server starting with prefix, which
+// are strings whatever the type they were put with
+func (c *client) DelPrefix(prefix string) int {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.DelPrefix(ctx, &PrefixRequest{Prefix: c.prefix + prefix})
+	if err != nil {
+		c.fail(err)
+		return 0
+	}
+	return int(resp.Count)
+}
+
+// DelFunc deletes the keys fn returns true for in a Range, fn can not be
+// sent to the server, so the keys put or deleted meanwhile may or may not
+// be matched
+func (c *client) DelFunc(fn func(key cache.Key, value cache.Value) bool) int {
+	var keys []cache.Key
+	c.Range(func(key cache.Key, value cache.Value) bool {
+		if fn(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return c.DelMulti(keys)
+}
+
+// PutTagged tags the key with the tags prefixed like the keys, so the
+// namespaces have tags of their own
+func (c *client) PutTagged(key cache.Key, value cache.Value, tags ...string) {
+	if c.bypassed() {
+		return
+	}
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = c.prefix + tag
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	_, err = c.rpc.PutTagged(ctx, &PutRequest{Key: c.name(key), Value: data, Tags: names})
+	c.fail(err)
+}
+
+func (c *client) InvalidateTag(tag string) int {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.InvalidateTag(ctx, &TagRequest{Tag: c.prefix + tag})
+	if err != nil {
+		c.fail(err)
+		return 0
+	}
+	return int(resp.Count)
+}
+
+// RemoveOldest returns the key as its string, like Keys
+func (c *client) RemoveOldest() (cache.Key, cache.Value, bool) {
+	if c.prefix != "" {
+		for {
+			entry, ok := c.GetOldest()
+			if !ok {
+				return nil, nil, false
+			}
+			if value, ok := c.DelE(entry.Key); ok {
+				return entry.Key, value, true
+			}
+		}
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.RemoveOldest(ctx, &Empty{})
+	if err != nil {
+		c.fail(err)
+		return nil, nil, false
+	}
+	value, ok := c.decode(resp.Value, resp.Found)
+	if !ok {
+		return nil, nil, false
+	}
+	return resp.Key, value, true
+}
+
+func (c *client) GetOldest() (cache.Entry, bool) {
+	if c.prefix != "" {
+		return c.edge(false)
+	}
+	return c.entry(c.rpc.GetOldest)
+}
+
+func (c *client) GetNewest() (cache.Entry, bool) {
+	if c.prefix != "" {
+		return c.edge(true)
+	}
+	return c.entry(c.rpc.GetNewest)
+}
+
+// edge finds the newest or oldest entry of a namespace by streaming the
+// entries of the server, which come from the newest to the oldest
+func (c *client) edge(newest bool) (cache.Entry, bool) {
+	var found cache.Entry
+	var ok bool
+	c.each(func(entry *Entry, value cache.Value) bool {
+		found, ok = cache.Entry{Key: entry.Key, Value: value}, true
+		if entry.Deadline != 0 {
+			found.Deadline = time.Unix(0, entry.Deadline)
+		}
+		return !newest
+	})
+	return found, ok
+}
+
+// entry calls one of the RPCs returning an entry, its key is returned as
+// its string
+func (c *client) entry(rpc func(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntryResponse, error)) (cache.Entry, bool) {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	return c.entryOf(rpc(ctx, &Empty{}))
+}
+
+// GetEntry returns the entry with its metadata, it is not counted as a
+// read by the server
+func (c *client) GetEntry(key cache.Key) (cache.Entry, bool) {
+	if c.bypassed() {
+		return cache.Entry{}, false
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	entry, ok := c.entryOf(c.rpc.GetEntry(ctx, &KeyRequest{Key: c.name(key)}))
+	if !ok {
+		return cache.Entry{}, false
+	}
+	entry.Key = key
+	return entry, true
+}
+
+// entryOf decodes the response of one of the RPCs returning an entry
+func (c *client) entryOf(resp *EntryResponse, err error) (cache.Entry, bool) {
+	if err != nil {
+		c.fail(err)
+		return cache.Entry{}, false
+	}
+	if !resp.Found {
+		return cache.Entry{}, false
+	}
+	value, ok := c.decode(resp.Entry.Value, true)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	return fromEntry(resp.Entry, resp.Entry.Key, value), true
+}
+
+// fromEntry converts an entry of the server, the times left 0 stay zero
+func fromEntry(e *Entry, key cache.Key, value cache.Value) cache.Entry {
+	entry := cache.Entry{Key: key, Value: value, Age: time.Duration(e.Age), Hits: e.Hits}
+	if e.Deadline != 0 {
+		entry.Deadline = time.Unix(0, e.Deadline)
+	}
+	if e.Created != 0 {
+		entry.Created = time.Unix(0, e.Created)
+	}
+	if e.LastAccess != 0 {
+		entry.LastAccess = time.Unix(0, e.LastAccess)
+	}
+	return entry
+}
+
+func (c *client) stats() *StatsResponse {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Stats(ctx, &Empty{})
+	if err != nil {
+		c.fail(err)
+		return &StatsResponse{}
+	}
+	return resp
+}
+
+// Len counts the keys of a namespace, which the stats of the server
+// cannot tell
+func (c *client) Len() int {
+	if c.prefix != "" {
+		return len(c.Keys())
+	}
+	return int(c.stats().Len)
+}
+
+// Resize resizes the server's cache, a namespace leaves it alone
+func (c *client) Resize(maxLen int) {
+	if c.view {
+		return
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	_, err := c.rpc.Resize(ctx, &ResizeRequest{MaxLen: int64(maxLen)})
+	c.fail(err)
+}
+
+func (c *client) Weight() int64 {
+	return c.stats().Weight
+}
+
+func (c *client) Keys() []cache.Key {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Keys(ctx, &Empty{})
+	if err != nil {
+		c.fail(err)
+		return nil
+	}
+	keys := make([]cache.Key, 0, len(resp.Keys))
+	for _, key := range resp.Keys {
+		if key, ok := c.own(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Snapshot returns the entries of the server's cache copied at once, the
+// keys are returned as their strings
+func (c *client) Snapshot() []cache.Entry {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	resp, err := c.rpc.Snapshot(ctx, &Empty{})
+	if err != nil {
+		c.fail(err)
+		return nil
+	}
+	entries := make([]cache.Entry, 0, len(resp.Entries))
+	for _, e := range resp.Entries {
+		key, ok := c.own(e.Key)
+		if !ok {
+			continue
+		}
+		value, ok := c.decode(e.Value, true)
+		if !ok {
+			continue
+		}
+		entries = append(entries, fromEntry(e, key, value))
+	}
+	return entries
+}
+
+// Stats returns the stats of the server's cache
+func (c *client) Stats() cache.Stats {
+	resp := c.stats()
+	return cache.Stats{
+		Hits:        resp.Hits,
+		Misses:      resp.Misses,
+		Evictions:   resp.Evictions,
+		Expirations: resp.Expirations,
+		Rejections:  resp.Rejections,
+		Len:         int(resp.Len),
+	}
+}
+
+// each streams the entries of the server until fn returns false
+func (c *client) each(fn func(entry *Entry, value cache.Value) bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.rpc.Range(ctx, &Empty{})
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		key, ok := c.own(entry.Key)
+		if !ok {
+			continue
+		}
+		entry.Key = key
+		value, ok := c.decode(entry.Value, true)
+		if !ok {
+			continue
+		}
+		if !fn(entry, value) {
+			return
+		}
+	}
+}
+
+// Range visits the entries the server had when the call started
+func (c *client) Range(fn func(key cache.Key, value cache.Value) bool) {
+	c.each(func(entry *Entry, value cache.Value) bool {
+		return fn(entry.Key, value)
+	})
+}
+
+func (c *client) EvictionRate() float64 {
+	return c.stats().EvictionRate
+}
+
+func (c *client) DistinctKeysEstimate() uint64 {
+	return c.stats().DistinctKeys
+}
+
+func (c *client) SetBypass(bypass bool) {
+	var v int32
+	if bypass {
+		v = 1
+	}
+	atomic.StoreInt32(&c.bypass, v)
+}
+
+// localCache is the in-process cache the entries are copied through, the
+// caches of NewCacheWithConfig implement the optional interfaces
+type localCache interface {
+	cache.Interface
+	cache.Persister
+	cache.Warmer
+}
+
+// local returns an in-process cache without bounds, used to convert the
+// entries from and to the formats of the cache package
+func (c *client) local() localCache {
+	return cache.NewCacheWithConfig(cache.Config{MaxLen: math.MaxInt}).(localCache)
+}
+
+// snapshot copies the live entries into an in-process cache
+func (c *client) snapshot() localCache {
+	local := c.local()
+	c.each(func(entry *Entry, value cache.Value) bool {
+		if entry.Deadline == 0 {
+			local.PutWithTimeout(entry.Key, value, cache.NoExpiration)
+		} else {
+			local.PutWithDeadline(entry.Key, value, time.Unix(0, entry.Deadline))
+		}
+		return true
+	})
+	return local
+}
+
+// restore puts the live entries of an in-process cache
+func (c *client) restore(local cache.Interface) {
+	now := time.Now()
+	local.Range(func(key cache.Key, value cache.Value) bool {
+		left, ok := local.TTL(key)
+		switch {
+		case !ok:
+		case left == cache.NoExpiration:
+			c.PutWithTimeout(key, value, cache.NoExpiration)
+		default:
+			c.PutWithDeadline(key, value, now.Add(left))
+		}
+		return true
+	})
+	local.Close()
+}
+
+// ReplayWAL puts the entries a write-ahead log ends up with, the deletes
+// in the log only cancel the puts before them in the log
+func (c *client) ReplayWAL(r io.Reader) error {
+	local := c.local()
+	if err := local.ReplayWAL(r); err != nil {
+		return err
+	}
+	c.restore(local)
+	return nil
+}
+
+func (c *client) SaveTo(w io.Writer) error {
+	local := c.snapshot()
+	defer local.Close()
+	return local.SaveTo(w)
+}
+
+func (c *client) ExportJSON(w io.Writer, codec cache.JSONCodec) error {
+	local := c.snapshot()
+	defer local.Close()
+	return local.ExportJSON(w, codec)
+}
+
+func (c *client) ImportJSON(r io.Reader, codec cache.JSONCodec) error {
+	local := c.local()
+	if err := local.ImportJSON(r, codec); err != nil {
+		return err
+	}
+	c.restore(local)
+	return nil
+}
+
+func (c *client) Warmup(keys []cache.Key, loader cache.Loader, parallelism int) error {
+	local := c.local()
+	err := local.Warmup(keys, loader, parallelism)
+	c.restore(local)
+	return err
+}
+
+// Flush flushes the write-behind queue of the server's cache
+func (c *client) Flush() {
+	ctx, cancel := c.ctx()
+	defer cancel()
+	_, err := c.rpc.Flush(ctx, &Empty{})
+	c.fail(err)
+}
+
+// Purge purges the server's cache, its callbacks are fired on the server
+func (c *client) Purge() {
+	if c.prefix != "" {
+		c.DelMulti(c.Keys())
+		return
+	}
+	ctx, cancel := c.ctx()
+	defer cancel()
+	_, err := c.rpc.Purge(ctx, &Empty{})
+	c.fail(err)
+}
+
+// Close closes the connection, and returns nil when called again. The
+// methods skipped in bypass mode are skipped once it is closed as well,
+// the others fail with the errors of the connection. Closing a namespace
+// does nothing
+func (c *client) Close() error {
+	if c.view {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Clone copies the entries of the server's cache into an in-process cache
+// without bounds, in the same recency order
+func (c *client) Clone() cache.Interface {
+	local := c.local()
+	entries := c.Snapshot()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Deadline.IsZero() {
+			local.PutWithTimeout(entries[i].Key, entries[i].Value, cache.NoExpiration)
+		} else {
+			local.PutWithDeadline(entries[i].Key, entries[i].Value, entries[i].Deadline)
+		}
+	}
+	return local
+}
+
+// Merge puts the live entries of other into the server's cache, like the
+// Merge of the cache package, with one call per step
+func (c *client) Merge(other cache.Interface, conflict func(key cache.Key, a, b cache.Value) cache.Value) {
+	other.Range(func(key cache.Key, theirs cache.Value) bool {
+		left, ok := other.TTL(key)
+		if !ok {
+			return true
+		}
+		value := theirs
+		if mine, ok := c.Peek(key); ok {
+			if conflict != nil {
+				value = conflict(key, mine, theirs)
+			}
+			if kept, ok := c.TTL(key); ok {
+				left = cache.Fresher(left, kept)
+			}
+		}
+		c.PutWithTimeout(key, value, left)
+		return true
+	})
+}
+
+// Namespace returns a client prefixing its keys with name and a colon on
+// the same connection, so the namespace can share the server's cache with
+// the others
+func (c *client) Namespace(name string) cache.Interface {
+	return &client{
+		conn:    c.conn,
+		rpc:     c.rpc,
+		timeout: c.timeout,
+		codec:   c.codec,
+		onError: c.onError,
+		prefix:  c.prefix + name + ":",
+		view:    true,
+		locks:   c.locks,
+	}
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cachegrpc exposes a cache.Interface over gRPC, the server
+// serves an in-process cache and the client implements cache.Interface
+// over the connection, so that sidecar processes can share one instance.
+package cachegrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/leopoldxx/cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Codec converts the values to and from the bytes sent over the wire
+type Codec interface {
+	Marshal(v cache.Value) ([]byte, error)
+	Unmarshal(data []byte) (cache.Value, error)
+}
+
+// GobCodec is the default Codec, values of custom types must be
+// registered with gob.Register on both ends
+type GobCodec struct{}
+
+// Marshal will gob encode the value as an interface
+func (GobCodec) Marshal(v cache.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+// Unmarshal will decode a value encoded by Marshal
+func (GobCodec) Unmarshal(data []byte) (cache.Value, error) {
+	var v cache.Value
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+type server struct {
+	UnimplementedCacheServer
+	cache cache.Interface
+	codec Codec
+}
+
+// NewServer will create the service of the cache, to be registered with
+// RegisterCacheServer. The keys it receives are strings, the values are
+// decoded with codec, GobCodec if nil, and stored decoded so that the
+// process owning the cache sees them as they were put
+func NewServer(c cache.Interface, codec Codec) CacheServer {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	return &server{cache: cache.Wrap(c), codec: codec}
+}
+
+func (s *server) decode(data []byte) (cache.Value, error) {
+	value, err := s.codec.Unmarshal(data)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad value: %v", err)
+	}
+	return value, nil
+}
+
+func (s *server) encode(value cache.Value) ([]byte, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not encode the value: %v", err)
+	}
+	return data, nil
+}
+
+func (s *server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	var value cache.Value
+	var found bool
+	if req.Peek {
+		value, found = s.cache.Peek(req.Key)
+	} else {
+		value, found = s.cache.Get(req.Key)
+	}
+	if !found {
+		return &GetResponse{}, nil
+	}
+	data, err := s.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return &GetResponse{Value: data, Found: true}, nil
+}
+
+func (s *server) Contains(ctx context.Context, req *KeyRequest) (*ContainsResponse, error) {
+	return &ContainsResponse{Found: s.cache.Contains(req.Key)}, nil
+}
+
+func (s *server) TTL(ctx context.Context, req *KeyRequest) (*TTLResponse, error) {
+	ttl, found := s.cache.TTL(req.Key)
+	return &TTLResponse{Ttl: int64(ttl), Found: found}, nil
+}
+
+func (s *server) Touch(ctx context.Context, req *TouchRequest) (*TouchResponse, error) {
+	return &TouchResponse{Ok: s.cache.Touch(req.Key, time.Duration(req.Timeout))}, nil
+}
+
+func (s *server) Put(ctx context.Context, req *PutRequest) (*Empty, error) {
+	value, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case req.Deadline != 0:
+		s.cache.PutWithDeadline(req.Key, value, time.Unix(0, req.Deadline))
+	case req.Timeout != 0:
+		s.cache.PutWithTimeout(req.Key, value, time.Duration(req.Timeout))
+	default:
+		s.cache.Put(req.Key, value)
+	}
+	return &Empty{}, nil
+}
+
+func (s *server) PutNotFound(ctx context.Context, req *KeyRequest) (*Empty, error) {
+	s.cache.PutNotFound(req.Key)
+	return &Empty{}, nil
+}
+
+func (s *server) GetOrStore(ctx context.Context, req *GetOrStoreRequest) (*GetOrStoreResponse, error) {
+	def, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	value, loaded := s.cache.GetOrStore(req.Key, def, time.Duration(req.Timeout))
+	if !loaded {
+		return &GetOrStoreResponse{Value: req.Value}, nil
+	}
+	data, err := s.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return &GetOrStoreResponse{Value: data, Loaded: true}, nil
+}
+
+func (s *server) Add(ctx context.Context, req *PutRequest) (*AddResponse, error) {
+	value, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &AddResponse{Added: s.cache.Add(req.Key, value)}, nil
+}
+
+func (s *server) Replace(ctx context.Context, req *PutRequest) (*ReplaceResponse, error) {
+	value, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplaceResponse{Replaced: s.cache.Replace(req.Key, value)}, nil
+}
+
+func (s *server) DelPrefix(ctx context.Context, req *PrefixRequest) (*DelPrefixResponse, error) {
+	return &DelPrefixResponse{Count: int64(s.cache.DelPrefix(req.Prefix))}, nil
+}
+
+func (s *server) PutTagged(ctx context.Context, req *PutRequest) (*Empty, error) {
+	value, err := s.decode(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.PutTagged(req.Key, value, req.Tags...)
+	return &Empty{}, nil
+}
+
+func (s *server) InvalidateTag(ctx context.Context, req *TagRequest) (*InvalidateTagResponse, error) {
+	return &InvalidateTagResponse{Count: int64(s.cache.InvalidateTag(req.Tag))}, nil
+}
+
+// CompareAndSwap compares the decoded values with the equality of the
+// served cache
+func (s *server) CompareAndSwap(ctx context.Context, req *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	old, err := s.decode(req.Old)
+	if err != nil {
+		return nil, err
+	}
+	new, err := s.decode(req.New)
+	if err != nil {
+		return nil, err
+	}
+	return &CompareAndSwapResponse{Swapped: s.cache.CompareAndSwap(req.Key, old, new)}, nil
+}
+
+func (s *server) IncrementInt64(ctx context.Context, req *IncrementRequest) (*IncrementResponse, error) {
+	n, err := s.cache.IncrementInt64(req.Key, req.Delta)
+	if err == cache.ErrNotInteger {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &IncrementResponse{Value: n}, nil
+}
+
+func (s *server) Del(ctx context.Context, req *KeyRequest) (*DelResponse, error) {
+	value, found := s.cache.DelE(req.Key)
+	if !found {
+		return &DelResponse{}, nil
+	}
+	data, err := s.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return &DelResponse{Value: data, Found: true}, nil
+}
+
+// unsupported is the error of the RPCs of the optional interfaces the
+// cache does not implement
+func unsupported(method string) error {
+	return status.Errorf(codes.Unimplemented, "the cache does not support %s", method)
+}
+
+func (s *server) RemoveOldest(ctx context.Context, req *Empty) (*RemoveOldestResponse, error) {
+	evicter, ok := s.cache.(cache.Evicter)
+	if !ok {
+		return nil, unsupported("RemoveOldest")
+	}
+	key, value, found := evicter.RemoveOldest()
+	if !found {
+		return &RemoveOldestResponse{}, nil
+	}
+	data, err := s.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoveOldestResponse{Key: fmt.Sprint(key), Value: data, Found: true}, nil
+}
+
+func (s *server) GetOldest(ctx context.Context, req *Empty) (*EntryResponse, error) {
+	evicter, ok := s.cache.(cache.Evicter)
+	if !ok {
+		return nil, unsupported("GetOldest")
+	}
+	return s.entry(evicter.GetOldest())
+}
+
+func (s *server) GetNewest(ctx context.Context, req *Empty) (*EntryResponse, error) {
+	evicter, ok := s.cache.(cache.Evicter)
+	if !ok {
+		return nil, unsupported("GetNewest")
+	}
+	return s.entry(evicter.GetNewest())
+}
+
+func (s *server) GetEntry(ctx context.Context, req *KeyRequest) (*EntryResponse, error) {
+	return s.entry(s.cache.GetEntry(req.Key))
+}
+
+func (s *server) entry(entry cache.Entry, found bool) (*EntryResponse, error) {
+	if !found {
+		return &EntryResponse{}, nil
+	}
+	e, err := s.toEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &EntryResponse{Entry: e, Found: true}, nil
+}
+
+// toEntry encodes the entry, the unknown times are left 0
+func (s *server) toEntry(entry cache.Entry) (*Entry, error) {
+	data, err := s.encode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{Key: fmt.Sprint(entry.Key), Value: data, Age: int64(entry.Age), Hits: entry.Hits}
+	if !entry.Deadline.IsZero() {
+		e.Deadline = entry.Deadline.UnixNano()
+	}
+	if !entry.Created.IsZero() {
+		e.Created = entry.Created.UnixNano()
+	}
+	if !entry.LastAccess.IsZero() {
+		e.LastAccess = entry.LastAccess.UnixNano()
+	}
+	return e, nil
+}
+
+func (s *server) Keys(ctx context.Context, req *Empty) (*KeysResponse, error) {
+	keys := s.cache.Keys()
+	resp := &KeysResponse{Keys: make([]string, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, fmt.Sprint(key))
+	}
+	return resp, nil
+}
+
+func (s *server) Snapshot(ctx context.Context, req *Empty) (*SnapshotResponse, error) {
+	snapshotter, ok := s.cache.(cache.Snapshotter)
+	if !ok {
+		return nil, unsupported("Snapshot")
+	}
+	entries := snapshotter.Snapshot()
+	resp := &SnapshotResponse{Entries: make([]*Entry, 0, len(entries))}
+	for _, entry := range entries {
+		e, err := s.toEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, e)
+	}
+	return resp, nil
+}
+
+// Range sends the entries one by one, the cache is not locked while they
+// are sent so a slow client does not hold it
+func (s *server) Range(req *Empty, stream Cache_RangeServer) error {
+	type item struct {
+		key   cache.Key
+		value cache.Value
+	}
+	var items []item
+	s.cache.Range(func(key cache.Key, value cache.Value) bool {
+		items = append(items, item{key, value})
+		return true
+	})
+	now := time.Now()
+	for _, it := range items {
+		ttl, found := s.cache.TTL(it.key)
+		if !found {
+			continue
+		}
+		data, err := s.encode(it.value)
+		if err != nil {
+			return err
+		}
+		entry := &Entry{Key: fmt.Sprint(it.key), Value: data}
+		if ttl != cache.NoExpiration {
+			entry.Deadline = now.Add(ttl).UnixNano()
+		}
+		if err := stream.Send(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) Stats(ctx context.Context, req *Empty) (*StatsResponse, error) {
+	stats := s.cache.Stats()
+	resp := &StatsResponse{
+		Hits:        stats.Hits,
+		Misses:      stats.Misses,
+		Evictions:   stats.Evictions,
+		Expirations: stats.Expirations,
+		Rejections:  stats.Rejections,
+		Len:         int64(stats.Len),
+	}
+	if resizer, ok := s.cache.(cache.Resizer); ok {
+		resp.Weight = resizer.Weight()
+	}
+	if estimator, ok := s.cache.(cache.Estimator); ok {
+		resp.EvictionRate = estimator.EvictionRate()
+		resp.DistinctKeys = estimator.DistinctKeysEstimate()
+	}
+	return resp, nil
+}
+
+func (s *server) Resize(ctx context.Context, req *ResizeRequest) (*Empty, error) {
+	resizer, ok := s.cache.(cache.Resizer)
+	if !ok {
+		return nil, unsupported("Resize")
+	}
+	resizer.Resize(int(req.MaxLen))
+	return &Empty{}, nil
+}
+
+func (s *server) Flush(ctx context.Context, req *Empty) (*Empty, error) {
+	s.cache.Flush()
+	return &Empty{}, nil
+}
+
+func (s *server) Purge(ctx context.Context, req *Empty) (*Empty, error) {
+	s.cache.Purge()
+	return &Empty{}, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cachehttp exposes a cache over HTTP for operational debugging,
+// the handler can be mounted on an existing mux with http.StripPrefix.
+//
+//	GET    /stats         the Stats of the cache as JSON
+//	GET    /keys          the keys as a JSON array of strings
+//	GET    /entries/{key} the value of a string key as JSON, with its TTL
+//	PUT    /entries/{key} puts the JSON body, for ?ttl= if given
+//	DELETE /entries/{key} deletes the key
+//
+// It does not authenticate the requests, do not expose it publicly.
+package cachehttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leopoldxx/cache"
+)
+
+// MaxBodySize is the largest value a PUT accepts
+const MaxBodySize = 1 << 20
+
+// Entry is the answer to a GET of an entry, TTL is empty if it never
+// expires
+type Entry struct {
+	Key   string      `json:"key"`
+	Value cache.Value `json:"value"`
+	TTL   string      `json:"ttl,omitempty"`
+}
+
+type handler struct {
+	cache cache.Interface
+}
+
+// NewHandler will create a handler serving the cache
+func NewHandler(c cache.Interface) http.Handler {
+	return &handler{cache: cache.Wrap(c)}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := "/" + strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case path == "/stats":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		writeJSON(w, http.StatusOK, h.cache.Stats())
+	case path == "/keys":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		keys := h.cache.Keys()
+		names := make([]string, 0, len(keys))
+		for _, key := range keys {
+			names = append(names, fmt.Sprint(key))
+		}
+		writeJSON(w, http.StatusOK, names)
+	case strings.HasPrefix(path, "/entries/") && len(path) > len("/entries/"):
+		h.serveEntry(w, r, strings.TrimPrefix(path, "/entries/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) serveEntry(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		value, ok := h.cache.Get(key)
+		if !ok || value == cache.NotFound {
+			http.NotFound(w, r)
+			return
+		}
+		entry := Entry{Key: key, Value: value}
+		if ttl, ok := h.cache.TTL(key); ok && ttl >= 0 {
+			entry.TTL = ttl.String()
+		}
+		writeJSON(w, http.StatusOK, entry)
+	case http.MethodPut:
+		var ttl time.Duration
+		if s := r.URL.Query().Get("ttl"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, "bad ttl: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			ttl = d
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) > MaxBodySize {
+			http.Error(w, "value too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		var value cache.Value
+		if err := json.Unmarshal(body, &value); err != nil {
+			http.Error(w, "bad value: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if ttl > 0 {
+			h.cache.PutWithTimeout(key, value, ttl)
+		} else {
+			h.cache.Put(key, value)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if _, ok := h.cache.DelE(key); !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
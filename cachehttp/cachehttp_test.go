@@ -0,0 +1,105 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachehttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/cachehttp"
+)
+
+func TestHandler(t *testing.T) {
+	c := NewCache()
+	defer c.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", cachehttp.NewHandler(c)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/debug/cache"+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("test %s %s failed, expect no error, got %v", method, path, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %s %s failed, expect no error, got %v", method, path, err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodPut, "/entries/a", `"A"`, http.StatusNoContent},
+		{http.MethodPut, "/entries/b", `{"n":1}`, http.StatusNoContent},
+		{http.MethodPut, "/entries/c", `3`, http.StatusNoContent},
+		{http.MethodPut, "/entries/c?ttl=bad", `3`, http.StatusBadRequest},
+		{http.MethodPut, "/entries/d", `not json`, http.StatusBadRequest},
+		{http.MethodGet, "/entries/a", ``, http.StatusOK},
+		{http.MethodGet, "/entries/d", ``, http.StatusNotFound},
+		{http.MethodDelete, "/entries/b", ``, http.StatusNoContent},
+		{http.MethodDelete, "/entries/b", ``, http.StatusNotFound},
+		{http.MethodPost, "/entries/a", ``, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/stats", ``, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/unknown", ``, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		resp := do(test.method, test.path, test.body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Fatalf("test %s %s failed, expect %v, got %v", test.method, test.path, test.status, resp.StatusCode)
+		}
+	}
+
+	resp := do(http.MethodPut, "/entries/t?ttl=1m", `"T"`)
+	resp.Body.Close()
+	resp = do(http.MethodGet, "/entries/t", "")
+	var entry cachehttp.Entry
+	json.NewDecoder(resp.Body).Decode(&entry)
+	resp.Body.Close()
+	if entry.Key != "t" || entry.Value != "T" || entry.TTL == "" {
+		t.Fatalf("test key %s entry failed, expect %v with a ttl, got %+v", "t", "T", entry)
+	}
+	if value, _ := c.Get("c"); value != float64(3) {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "c", 3, value)
+	}
+
+	resp = do(http.MethodGet, "/keys", "")
+	var keys []string
+	json.NewDecoder(resp.Body).Decode(&keys)
+	resp.Body.Close()
+	if len(keys) != 3 {
+		t.Fatalf("test keys failed, expect %v, got %v", 3, keys)
+	}
+
+	resp = do(http.MethodGet, "/stats", "")
+	var stats Stats
+	json.NewDecoder(resp.Body).Decode(&stats)
+	resp.Body.Close()
+	if stats.Len != 3 || stats.Hits == 0 {
+		t.Fatalf("test stats failed, expect %v entries with hits, got %+v", 3, stats)
+	}
+}
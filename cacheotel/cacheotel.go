@@ -0,0 +1,271 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cacheotel instruments a cache.Interface with OpenTelemetry, so
+// that its operations show up in the traces and metrics of the process.
+package cacheotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/leopoldxx/cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/leopoldxx/cache/cacheotel"
+
+// Config of the instrumentation
+type Config struct {
+	// Name is recorded as the cache.name attribute, to tell the caches of
+	// a process apart
+	Name string
+	// TracerProvider of the spans, the global one if nil
+	TracerProvider trace.TracerProvider
+	// MeterProvider of the metrics, the global one if nil
+	MeterProvider metric.MeterProvider
+}
+
+type instruments struct {
+	name       attribute.KeyValue
+	tracer     trace.Tracer
+	operations instrument.Int64Counter
+	duration   instrument.Float64Histogram
+}
+
+// Cache records a span, and the cache.operations and cache.duration
+// metrics, for each read, write and delete of the cache it wraps. Reads
+// carry a cache.hit attribute. The other methods are not recorded
+type Cache struct {
+	cache.Interface
+	ctx  context.Context
+	inst *instruments
+}
+
+// Wrap will create an instrumented cache around c. Its spans have no
+// parent, use WithContext to record them in the trace of a request
+func Wrap(c cache.Interface, config Config) (*Cache, error) {
+	inst, err := newInstruments(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Interface: cache.Wrap(c), ctx: context.Background(), inst: inst}, nil
+}
+
+// Middleware returns a cache.Middleware instrumenting the caches it wraps
+// like Wrap, to be used with cache.Chain. The caches share the metrics
+func Middleware(config Config) (cache.Middleware, error) {
+	inst, err := newInstruments(config)
+	if err != nil {
+		return nil, err
+	}
+	return func(c cache.Interface) cache.Interface {
+		return &Cache{Interface: cache.Wrap(c), ctx: context.Background(), inst: inst}
+	}, nil
+}
+
+func newInstruments(config Config) (*instruments, error) {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.MeterProvider == nil {
+		config.MeterProvider = global.MeterProvider()
+	}
+	meter := config.MeterProvider.Meter(instrumentationName)
+	operations, err := meter.Int64Counter("cache.operations",
+		instrument.WithDescription("Number of cache operations."))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("cache.duration",
+		instrument.WithUnit("ms"),
+		instrument.WithDescription("Duration of the cache operations."))
+	if err != nil {
+		return nil, err
+	}
+	return &instruments{
+		name:       attribute.String("cache.name", config.Name),
+		tracer:     config.TracerProvider.Tracer(instrumentationName),
+		operations: operations,
+		duration:   duration,
+	}, nil
+}
+
+// WithContext returns a view of the cache recording its spans as
+// children of the span of ctx
+func (c *Cache) WithContext(ctx context.Context) *Cache {
+	return &Cache{Interface: c.Interface, ctx: ctx, inst: c.inst}
+}
+
+// observe records the operation run by fn, read tells whether its result
+// is a hit or a miss
+func (c *Cache) observe(op string, read bool, fn func() (bool, error)) {
+	c.observeCtx(c.ctx, op, read, func(context.Context) (bool, error) { return fn() })
+}
+
+// observeCtx is observe with the span started as a child of the span of
+// ctx, fn is given the context of the new span
+func (c *Cache) observeCtx(ctx context.Context, op string, read bool, fn func(ctx context.Context) (bool, error)) {
+	ctx, span := c.inst.tracer.Start(ctx, "cache."+op)
+	start := time.Now()
+	hit, err := fn(ctx)
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+	attrs := []attribute.KeyValue{c.inst.name, attribute.String("cache.operation", op)}
+	if read {
+		attrs = append(attrs, attribute.Bool("cache.hit", hit))
+	}
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	c.inst.operations.Add(ctx, 1, attrs...)
+	c.inst.duration.Record(ctx, elapsed, attrs...)
+}
+
+func (c *Cache) write(op string, fn func()) {
+	c.observe(op, false, func() (bool, error) {
+		fn()
+		return false, nil
+	})
+}
+
+func (c *Cache) read(op string, fn func() (cache.Value, bool)) (value cache.Value, ok bool) {
+	c.observe(op, true, func() (bool, error) {
+		value, ok = fn()
+		return ok, nil
+	})
+	return value, ok
+}
+
+func (c *Cache) Put(key cache.Key, value cache.Value) {
+	c.write("Put", func() { c.Interface.Put(key, value) })
+}
+
+func (c *Cache) PutWithTimeout(key cache.Key, value cache.Value, t time.Duration) {
+	c.write("PutWithTimeout", func() { c.Interface.PutWithTimeout(key, value, t) })
+}
+
+func (c *Cache) PutWithDeadline(key cache.Key, value cache.Value, deadline time.Time) {
+	c.write("PutWithDeadline", func() { c.Interface.PutWithDeadline(key, value, deadline) })
+}
+
+func (c *Cache) PutString(key string, value cache.Value) {
+	c.write("PutString", func() { c.Interface.PutString(key, value) })
+}
+
+func (c *Cache) PutInt(key int64, value cache.Value) {
+	c.write("PutInt", func() { c.Interface.PutInt(key, value) })
+}
+
+func (c *Cache) Get(key cache.Key) (cache.Value, bool) {
+	return c.read("Get", func() (cache.Value, bool) { return c.Interface.Get(key) })
+}
+
+func (c *Cache) GetString(key string) (cache.Value, bool) {
+	return c.read("GetString", func() (cache.Value, bool) { return c.Interface.GetString(key) })
+}
+
+func (c *Cache) GetInt(key int64) (cache.Value, bool) {
+	return c.read("GetInt", func() (cache.Value, bool) { return c.Interface.GetInt(key) })
+}
+
+func (c *Cache) Peek(key cache.Key) (cache.Value, bool) {
+	return c.read("Peek", func() (cache.Value, bool) { return c.Interface.Peek(key) })
+}
+
+func (c *Cache) GetEntry(key cache.Key) (entry cache.Entry, ok bool) {
+	c.read("GetEntry", func() (cache.Value, bool) {
+		entry, ok = c.Interface.GetEntry(key)
+		return entry.Value, ok
+	})
+	return entry, ok
+}
+
+func (c *Cache) Contains(key cache.Key) (ok bool) {
+	c.read("Contains", func() (cache.Value, bool) {
+		ok = c.Interface.Contains(key)
+		return nil, ok
+	})
+	return ok
+}
+
+// GetOrStore records a hit if the key already had a value
+func (c *Cache) GetOrStore(key cache.Key, def cache.Value, t time.Duration) (cache.Value, bool) {
+	return c.read("GetOrStore", func() (cache.Value, bool) { return c.Interface.GetOrStore(key, def, t) })
+}
+
+// GetOrLoad records a hit if the value was not loaded, it can not tell
+// the loads shared with another caller apart from the hits
+func (c *Cache) GetOrLoad(key cache.Key, load cache.LoadFunc) (value cache.Value, err error) {
+	c.observe("GetOrLoad", true, func() (bool, error) {
+		loaded := false
+		value, err = c.Interface.GetOrLoad(key, func(key cache.Key) (cache.Value, error) {
+			loaded = true
+			return load(key)
+		})
+		return !loaded && err == nil, err
+	})
+	return value, err
+}
+
+func (c *Cache) GetCtx(ctx context.Context, key cache.Key) (value cache.Value, ok bool, err error) {
+	c.observeCtx(ctx, "GetCtx", true, func(ctx context.Context) (bool, error) {
+		value, ok, err = c.Interface.GetCtx(ctx, key)
+		return ok, err
+	})
+	return value, ok, err
+}
+
+func (c *Cache) PutCtx(ctx context.Context, key cache.Key, value cache.Value) (err error) {
+	c.observeCtx(ctx, "PutCtx", false, func(ctx context.Context) (bool, error) {
+		err = c.Interface.PutCtx(ctx, key, value)
+		return false, err
+	})
+	return err
+}
+
+// GetOrLoadCtx records like GetOrLoad, the span is a child of the span of
+// ctx and the context given to load carries it
+func (c *Cache) GetOrLoadCtx(ctx context.Context, key cache.Key, load cache.LoadCtxFunc) (value cache.Value, err error) {
+	c.observeCtx(ctx, "GetOrLoadCtx", true, func(ctx context.Context) (bool, error) {
+		loaded := false
+		value, err = c.Interface.GetOrLoadCtx(ctx, key, func(ctx context.Context, key cache.Key) (cache.Value, error) {
+			loaded = true
+			return load(ctx, key)
+		})
+		return !loaded && err == nil, err
+	})
+	return value, err
+}
+
+func (c *Cache) Del(key cache.Key) (value cache.Value) {
+	c.write("Del", func() { value = c.Interface.Del(key) })
+	return value
+}
+
+func (c *Cache) DelE(key cache.Key) (value cache.Value, ok bool) {
+	c.write("DelE", func() { value, ok = c.Interface.DelE(key) })
+	return value, ok
+}
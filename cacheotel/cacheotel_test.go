@@ -0,0 +1,132 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cacheotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/cacheotel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCache(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	cache, err := cacheotel.Wrap(NewCache(), cacheotel.Config{
+		Name:           "users",
+		TracerProvider: tracerProvider,
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("test wrap failed, got %v", err)
+	}
+	defer cache.Close()
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "request")
+	cache.WithContext(ctx).Put("testkey1", "testvalue1")
+	parent.End()
+	cache.Get("testkey1")
+	cache.Get("testkey2")
+	cache.GetOrLoad("testkey3", func(key Key) (Value, error) {
+		return nil, errors.New("load failed")
+	})
+
+	tests := []struct {
+		name   string
+		hit    attribute.Value
+		parent bool
+		failed bool
+	}{
+		{"cache.Put", attribute.Value{}, true, false},
+		{"request", attribute.Value{}, false, false},
+		{"cache.Get", attribute.BoolValue(true), false, false},
+		{"cache.Get", attribute.BoolValue(false), false, false},
+		{"cache.GetOrLoad", attribute.BoolValue(false), false, true},
+	}
+	ended := spans.Ended()
+	if len(ended) != len(tests) {
+		t.Fatalf("test spans failed, expect %v, got %v", len(tests), len(ended))
+	}
+	for i, test := range tests {
+		span := ended[i]
+		if span.Name() != test.name {
+			t.Fatalf("test span %d failed, expect %v, got %v", i, test.name, span.Name())
+		}
+		var hit attribute.Value
+		for _, attr := range span.Attributes() {
+			if attr.Key == "cache.hit" {
+				hit = attr.Value
+			}
+		}
+		if hit != test.hit {
+			t.Fatalf("test span %s hit failed, expect %v, got %v", test.name, test.hit.Emit(), hit.Emit())
+		}
+		if span.Parent().IsValid() != test.parent {
+			t.Fatalf("test span %s parent failed, expect %v, got %v", test.name, test.parent, span.Parent().IsValid())
+		}
+		if (len(span.Events()) > 0) != test.failed {
+			t.Fatalf("test span %s error failed, expect %v, got %v", test.name, test.failed, span.Events())
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("test collect failed, got %v", err)
+	}
+	var operations int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "cache.operations" {
+				for _, dp := range sum.DataPoints {
+					operations += dp.Value
+				}
+			}
+		}
+	}
+	if operations != 4 {
+		t.Fatalf("test operations failed, expect %v, got %v", 4, operations)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	mw, err := cacheotel.Middleware(cacheotel.Config{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(),
+	})
+	if err != nil {
+		t.Fatalf("test middleware failed, got %v", err)
+	}
+	cache := Chain(NewCache(), mw, ReadOnly)
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	cache.Get("testkey1")
+	if ended := spans.Ended(); len(ended) != 2 || ended[1].Name() != "cache.Get" {
+		t.Fatalf("test middleware spans failed, expect %v, got %v", 2, len(ended))
+	}
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", false, ok)
+	}
+}
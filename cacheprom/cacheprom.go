@@ -0,0 +1,98 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cacheprom exports the stats of a cache to Prometheus.
+package cacheprom
+
+import (
+	"strconv"
+
+	"github.com/leopoldxx/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// shardStater is implemented by the sharded caches
+type shardStater interface {
+	ShardStats() []cache.Stats
+}
+
+type collector struct {
+	cache cache.Interface
+
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	hitRatio    *prometheus.Desc
+	entries     *prometheus.Desc
+	evictions   *prometheus.Desc
+	expirations *prometheus.Desc
+	contentions *prometheus.Desc
+}
+
+// NewCollector will create a collector of the stats of the cache, its
+// metrics are prefixed with namespace and labelled with cache="name", so
+// that several caches can be registered side by side. The lock
+// contentions are labelled with the shard they happened on, shard="0"
+// for a cache that is not sharded
+func NewCollector(namespace, name string, c cache.Interface) prometheus.Collector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric, help string, variable ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", metric), help, variable, labels)
+	}
+	return &collector{
+		cache:       c,
+		hits:        desc("hits_total", "Number of reads that found a live entry."),
+		misses:      desc("misses_total", "Number of reads that found no live entry."),
+		hitRatio:    desc("hit_ratio", "Ratio of the reads that hit since the cache was created."),
+		entries:     desc("entries", "Current number of entries."),
+		evictions:   desc("evictions_total", "Number of entries evicted to make room."),
+		expirations: desc("expirations_total", "Number of entries removed because they expired."),
+		contentions: desc("lock_contentions_total", "Number of times a lock had to be waited for.", "shard"),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.hitRatio
+	ch <- c.entries
+	ch <- c.evictions
+	ch <- c.expirations
+	ch <- c.contentions
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	var shards []cache.Stats
+	if s, ok := c.cache.(shardStater); ok {
+		shards = s.ShardStats()
+	} else {
+		shards = []cache.Stats{c.cache.Stats()}
+	}
+	var stats cache.Stats
+	for i, st := range shards {
+		stats.Hits += st.Hits
+		stats.Misses += st.Misses
+		stats.Evictions += st.Evictions
+		stats.Expirations += st.Expirations
+		stats.Len += st.Len
+		ch <- prometheus.MustNewConstMetric(c.contentions, prometheus.CounterValue, float64(st.Contentions), strconv.Itoa(i))
+	}
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, stats.HitRatio())
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Len))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+}
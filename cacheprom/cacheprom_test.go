@@ -0,0 +1,79 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cacheprom_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/cacheprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	cache.Get("testkey3")
+	cache.Get("testkey1")
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(cacheprom.NewCollector("test", "users", cache))
+	expected := `
+# HELP test_cache_entries Current number of entries.
+# TYPE test_cache_entries gauge
+test_cache_entries{cache="users"} 2
+# HELP test_cache_evictions_total Number of entries evicted to make room.
+# TYPE test_cache_evictions_total counter
+test_cache_evictions_total{cache="users"} 1
+# HELP test_cache_hit_ratio Ratio of the reads that hit since the cache was created.
+# TYPE test_cache_hit_ratio gauge
+test_cache_hit_ratio{cache="users"} 0.5
+# HELP test_cache_hits_total Number of reads that found a live entry.
+# TYPE test_cache_hits_total counter
+test_cache_hits_total{cache="users"} 1
+# HELP test_cache_misses_total Number of reads that found no live entry.
+# TYPE test_cache_misses_total counter
+test_cache_misses_total{cache="users"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"test_cache_entries", "test_cache_evictions_total", "test_cache_hit_ratio", "test_cache_hits_total", "test_cache_misses_total")
+	if err != nil {
+		t.Fatalf("test metrics failed, got %v", err)
+	}
+}
+
+func TestCollectorShards(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: 4})
+	defer cache.Close()
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(cacheprom.NewCollector("test", "users", cache))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("test gather failed, got %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "test_cache_lock_contentions_total" && len(family.GetMetric()) != 4 {
+			t.Fatalf("test shards failed, expect %v, got %v", 4, len(family.GetMetric()))
+		}
+	}
+}
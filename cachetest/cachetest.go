@@ -0,0 +1,350 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cachetest provides a fake cache.Interface for the tests of the
+// code using a cache, it records the calls and lets the tests script the
+// results of the keys.
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leopoldxx/cache"
+)
+
+// Call is a call recorded by Fake, Value is the value written, or the one
+// returned by a read
+type Call struct {
+	Method string
+	Key    cache.Key
+	Value  cache.Value
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s(%v) %v", c.Method, c.Key, c.Value)
+}
+
+type script struct {
+	value cache.Value
+	hit   bool
+	err   error
+}
+
+// Fake is a cache.Interface backed by an in-memory cache on a
+// ManualClock. The reads, writes and deletes of single keys are recorded,
+// and the results of the keys scripted with Hit, Miss or Fail override
+// the backing cache. The other methods go to the backing cache and are
+// not recorded
+type Fake struct {
+	cache.Interface
+	// Clock of the backing cache, advance it to expire the entries
+	Clock *cache.ManualClock
+
+	calls   []Call
+	scripts map[cache.Key]script
+	sync.Mutex
+}
+
+// New will create an empty fake, its clock starts at the Unix epoch
+func New() *Fake {
+	clock := cache.NewManualClock(time.Unix(0, 0))
+	return &Fake{
+		Interface: cache.NewCacheWithConfig(cache.Config{MaxLen: cache.DefaultMaxLen, CacheTime: cache.DefaultCacheTime, Clock: clock}),
+		Clock:     clock,
+		scripts:   map[cache.Key]script{},
+	}
+}
+
+// Hit makes the reads of key return value, whatever the backing cache
+// holds
+func (f *Fake) Hit(key cache.Key, value cache.Value) {
+	f.setScript(key, script{value: value, hit: true})
+}
+
+// Miss makes the reads of key miss, the loads of GetOrLoad are called but
+// their values are not kept
+func (f *Fake) Miss(key cache.Key) {
+	f.setScript(key, script{})
+}
+
+// Fail makes the methods of key returning an error return err, the reads
+// without an error miss and the writes are dropped
+func (f *Fake) Fail(key cache.Key, err error) {
+	f.setScript(key, script{err: err})
+}
+
+func (f *Fake) setScript(key cache.Key, s script) {
+	f.Lock()
+	f.scripts[key] = s
+	f.Unlock()
+}
+
+func (f *Fake) script(key cache.Key) (script, bool) {
+	f.Lock()
+	defer f.Unlock()
+	s, ok := f.scripts[key]
+	return s, ok
+}
+
+// Reset forgets the calls and the scripts, the backing cache is kept
+func (f *Fake) Reset() {
+	f.Lock()
+	f.calls = nil
+	f.scripts = map[cache.Key]script{}
+	f.Unlock()
+}
+
+// Calls returns the calls recorded, in order
+func (f *Fake) Calls() []Call {
+	f.Lock()
+	defer f.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *Fake) called(method string, key cache.Key) bool {
+	for _, call := range f.Calls() {
+		if call.Method == method && reflect.DeepEqual(call.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectCalled fails t unless method was called with key
+func (f *Fake) ExpectCalled(t testing.TB, method string, key cache.Key) {
+	t.Helper()
+	if !f.called(method, key) {
+		t.Errorf("test call %s(%v) failed, expect called, got %v", method, key, f.Calls())
+	}
+}
+
+// ExpectNotCalled fails t if method was called with key
+func (f *Fake) ExpectNotCalled(t testing.TB, method string, key cache.Key) {
+	t.Helper()
+	if f.called(method, key) {
+		t.Errorf("test call %s(%v) failed, expect not called, got %v", method, key, f.Calls())
+	}
+}
+
+// ExpectCalls fails t unless exactly calls were recorded, in that order
+func (f *Fake) ExpectCalls(t testing.TB, calls ...Call) {
+	t.Helper()
+	if got := f.Calls(); !reflect.DeepEqual(got, calls) && (len(got) > 0 || len(calls) > 0) {
+		t.Errorf("test calls failed, expect %v, got %v", calls, got)
+	}
+}
+
+func (f *Fake) record(method string, key cache.Key, value cache.Value) {
+	f.Lock()
+	f.calls = append(f.calls, Call{Method: method, Key: key, Value: value})
+	f.Unlock()
+}
+
+func (f *Fake) read(method string, key cache.Key, get func() (cache.Value, bool)) (value cache.Value, ok bool) {
+	if s, scripted := f.script(key); scripted {
+		value, ok = s.value, s.hit
+	} else {
+		value, ok = get()
+	}
+	f.record(method, key, value)
+	return value, ok
+}
+
+// write records the call and runs put, unless key is scripted to fail
+func (f *Fake) write(method string, key cache.Key, value cache.Value, put func()) error {
+	f.record(method, key, value)
+	if s, _ := f.script(key); s.err != nil {
+		return s.err
+	}
+	put()
+	return nil
+}
+
+func (f *Fake) Put(key cache.Key, value cache.Value) {
+	f.write("Put", key, value, func() { f.Interface.Put(key, value) })
+}
+
+func (f *Fake) PutWithTimeout(key cache.Key, value cache.Value, t time.Duration) {
+	f.write("PutWithTimeout", key, value, func() { f.Interface.PutWithTimeout(key, value, t) })
+}
+
+func (f *Fake) PutWithDeadline(key cache.Key, value cache.Value, deadline time.Time) {
+	f.write("PutWithDeadline", key, value, func() { f.Interface.PutWithDeadline(key, value, deadline) })
+}
+
+func (f *Fake) PutCtx(ctx context.Context, key cache.Key, value cache.Value) (err error) {
+	if failed := f.write("PutCtx", key, value, func() { err = f.Interface.PutCtx(ctx, key, value) }); failed != nil {
+		return failed
+	}
+	return err
+}
+
+func (f *Fake) PutString(key string, value cache.Value) {
+	f.write("PutString", key, value, func() { f.Interface.PutString(key, value) })
+}
+
+func (f *Fake) PutInt(key int64, value cache.Value) {
+	f.write("PutInt", key, value, func() { f.Interface.PutInt(key, value) })
+}
+
+func (f *Fake) PutNotFound(key cache.Key) {
+	f.write("PutNotFound", key, cache.NotFound, func() { f.Interface.PutNotFound(key) })
+}
+
+func (f *Fake) PutTagged(key cache.Key, value cache.Value, tags ...string) {
+	f.write("PutTagged", key, value, func() { f.Interface.PutTagged(key, value, tags...) })
+}
+
+func (f *Fake) Get(key cache.Key) (cache.Value, bool) {
+	return f.read("Get", key, func() (cache.Value, bool) { return f.Interface.Get(key) })
+}
+
+// GetCtx returns the error of a key scripted to fail
+func (f *Fake) GetCtx(ctx context.Context, key cache.Key) (value cache.Value, ok bool, err error) {
+	if s, _ := f.script(key); s.err != nil {
+		f.record("GetCtx", key, nil)
+		return nil, false, s.err
+	}
+	value, ok = f.read("GetCtx", key, func() (cache.Value, bool) {
+		var value cache.Value
+		var ok bool
+		value, ok, err = f.Interface.GetCtx(ctx, key)
+		return value, ok
+	})
+	return value, ok, err
+}
+
+func (f *Fake) GetString(key string) (cache.Value, bool) {
+	return f.read("GetString", key, func() (cache.Value, bool) { return f.Interface.GetString(key) })
+}
+
+func (f *Fake) GetInt(key int64) (cache.Value, bool) {
+	return f.read("GetInt", key, func() (cache.Value, bool) { return f.Interface.GetInt(key) })
+}
+
+func (f *Fake) Peek(key cache.Key) (cache.Value, bool) {
+	return f.read("Peek", key, func() (cache.Value, bool) { return f.Interface.Peek(key) })
+}
+
+// GetEntry returns only the key and the value of a scripted hit
+func (f *Fake) GetEntry(key cache.Key) (cache.Entry, bool) {
+	var entry cache.Entry
+	value, ok := f.read("GetEntry", key, func() (cache.Value, bool) {
+		var ok bool
+		entry, ok = f.Interface.GetEntry(key)
+		return entry.Value, ok
+	})
+	if !ok {
+		return cache.Entry{}, false
+	}
+	if entry.Key == nil {
+		entry = cache.Entry{Key: key}
+	}
+	entry.Value = value
+	return entry, true
+}
+
+func (f *Fake) Contains(key cache.Key) bool {
+	_, ok := f.read("Contains", key, func() (cache.Value, bool) { return nil, f.Interface.Contains(key) })
+	return ok
+}
+
+// GetOrStore does not store def for a scripted key
+func (f *Fake) GetOrStore(key cache.Key, def cache.Value, t time.Duration) (cache.Value, bool) {
+	if s, scripted := f.script(key); scripted {
+		f.record("GetOrStore", key, s.value)
+		if s.hit {
+			return s.value, true
+		}
+		return def, false
+	}
+	value, loaded := f.Interface.GetOrStore(key, def, t)
+	f.record("GetOrStore", key, value)
+	return value, loaded
+}
+
+// GetOrLoad returns the error of a key scripted to fail without calling
+// load
+func (f *Fake) GetOrLoad(key cache.Key, load cache.LoadFunc) (cache.Value, error) {
+	return f.getOrLoad(context.Background(), "GetOrLoad", key, func(ctx context.Context, key cache.Key) (cache.Value, error) {
+		return load(key)
+	})
+}
+
+func (f *Fake) GetOrLoadCtx(ctx context.Context, key cache.Key, load cache.LoadCtxFunc) (cache.Value, error) {
+	return f.getOrLoad(ctx, "GetOrLoadCtx", key, load)
+}
+
+func (f *Fake) getOrLoad(ctx context.Context, method string, key cache.Key, load cache.LoadCtxFunc) (value cache.Value, err error) {
+	s, scripted := f.script(key)
+	switch {
+	case !scripted:
+		value, err = f.Interface.GetOrLoadCtx(ctx, key, load)
+	case s.err != nil:
+		err = s.err
+	case s.hit:
+		value = s.value
+	default:
+		value, err = load(ctx, key)
+	}
+	f.record(method, key, value)
+	return value, err
+}
+
+// Add fails for a key scripted to fail
+func (f *Fake) Add(key cache.Key, value cache.Value) (added bool) {
+	f.write("Add", key, value, func() { added = f.Interface.Add(key, value) })
+	return added
+}
+
+// Replace fails for a key scripted to fail
+func (f *Fake) Replace(key cache.Key, value cache.Value) (replaced bool) {
+	f.write("Replace", key, value, func() { replaced = f.Interface.Replace(key, value) })
+	return replaced
+}
+
+// IncrementInt64 returns the error of a key scripted to fail
+func (f *Fake) IncrementInt64(key cache.Key, delta int64) (n int64, err error) {
+	if failed := f.write("IncrementInt64", key, delta, func() { n, err = f.Interface.IncrementInt64(key, delta) }); failed != nil {
+		return 0, failed
+	}
+	return n, err
+}
+
+// DecrementInt64 returns the error of a key scripted to fail
+func (f *Fake) DecrementInt64(key cache.Key, delta int64) (n int64, err error) {
+	if failed := f.write("DecrementInt64", key, delta, func() { n, err = f.Interface.DecrementInt64(key, delta) }); failed != nil {
+		return 0, failed
+	}
+	return n, err
+}
+
+func (f *Fake) Del(key cache.Key) cache.Value {
+	value := f.Interface.Del(key)
+	f.record("Del", key, value)
+	return value
+}
+
+func (f *Fake) DelE(key cache.Key) (cache.Value, bool) {
+	value, ok := f.Interface.DelE(key)
+	f.record("DelE", key, value)
+	return value, ok
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cachetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/cachetest"
+)
+
+// recorder counts the failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures int
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures++
+}
+
+func TestFake(t *testing.T) {
+	fake := cachetest.New()
+	fake.Put("testkey1", "testvalue1")
+	if v, ok := fake.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	fake.Del("testkey1")
+	fake.ExpectCalls(t,
+		cachetest.Call{Method: "Put", Key: "testkey1", Value: "testvalue1"},
+		cachetest.Call{Method: "Get", Key: "testkey1", Value: "testvalue1"},
+		cachetest.Call{Method: "Del", Key: "testkey1", Value: "testvalue1"},
+	)
+	fake.ExpectCalled(t, "Get", "testkey1")
+	fake.ExpectNotCalled(t, "Get", "testkey2")
+
+	r := &recorder{TB: t}
+	fake.ExpectCalled(r, "Get", "testkey2")
+	fake.ExpectNotCalled(r, "Put", "testkey1")
+	fake.ExpectCalls(r)
+	if r.failures != 3 {
+		t.Fatalf("test expectations failed, expect %v, got %v", 3, r.failures)
+	}
+
+	fake.Reset()
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Fatalf("test reset failed, expect %v, got %v", 0, len(calls))
+	}
+}
+
+func TestFakeScript(t *testing.T) {
+	fake := cachetest.New()
+	failed := errors.New("cache down")
+	fake.Put("testkey2", "testvalue2")
+	fake.Hit("testkey1", "testvalue1")
+	fake.Miss("testkey2")
+	fake.Fail("testkey3", failed)
+
+	if v, ok := fake.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test hit key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if v, ok := fake.Get("testkey2"); ok {
+		t.Fatalf("test miss key %s failed, expect %v, got %v", "testkey2", nil, v)
+	}
+	if _, _, err := fake.GetCtx(context.Background(), "testkey3"); err != failed {
+		t.Fatalf("test fail key %s failed, expect %v, got %v", "testkey3", failed, err)
+	}
+	if err := fake.PutCtx(context.Background(), "testkey3", "testvalue3"); err != failed {
+		t.Fatalf("test fail key %s failed, expect %v, got %v", "testkey3", failed, err)
+	}
+	if _, err := fake.GetOrLoad("testkey3", func(key Key) (Value, error) {
+		t.Fatalf("test fail key %s failed, load called", "testkey3")
+		return nil, nil
+	}); err != failed {
+		t.Fatalf("test fail key %s failed, expect %v, got %v", "testkey3", failed, err)
+	}
+	if v, err := fake.GetOrLoad("testkey2", func(key Key) (Value, error) { return "testvalue4", nil }); err != nil || v != "testvalue4" {
+		t.Fatalf("test miss key %s failed, expect %v, got %v/%v", "testkey2", "testvalue4", v, err)
+	}
+
+	fake.Reset()
+	if v, ok := fake.Get("testkey2"); !ok || v != "testvalue2" {
+		t.Fatalf("test unscripted key %s failed, expect %v, got %v", "testkey2", "testvalue2", v)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	fake := cachetest.New()
+	fake.PutWithTimeout("testkey1", "testvalue1", time.Minute)
+	fake.Clock.Advance(time.Minute + time.Second)
+	if v, ok := fake.Get("testkey1"); ok {
+		t.Fatalf("test expired key %s failed, expect %v, got %v", "testkey1", nil, v)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "reflect"
+
+// DefaultEqual compares the values with reflect.DeepEqual, so that values
+// which are not comparable with ==, like []byte, can be swapped as well
+func DefaultEqual(a, b Value) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// CompareAndSwap replaces the value of the key with new if its live value
+// is equal to old, as told by Config.Equal, and reports whether it did.
+// The entry keeps its deadline. With a Store the new value is saved with
+// the lock held, and it is not swapped if it can not be saved
+func (lru *lruCache) CompareAndSwap(key Key, old, new Value) bool {
+	swapped := lru.compareAndSwap(key, old, new)
+	lru.audit("CompareAndSwap", key, swapped)
+	return swapped
+}
+
+func (lru *lruCache) compareAndSwap(key Key, old, new Value) bool {
+	if lru.bypassed() {
+		return false
+	}
+	entry := lru.newEntry(key, new, 0)
+	lru.Lock()
+	defer lru.Unlock()
+	current, value, ok := lru.live(key)
+	if !ok || !lru.equal(value, old) || !lru.saveStore(key, new) {
+		releaseEntry(entry)
+		return false
+	}
+	entry.keepDeadline(current.Value.(*listEntry))
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: new, Deadline: entry.deadTime})
+	lru.store(entry)
+	return true
+}
+
+func (s *shardedCache) CompareAndSwap(key Key, old, new Value) bool {
+	return s.shard(key).CompareAndSwap(key, old, new)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		cache.PutWithTimeout("testkey1", "testvalue1", time.Minute)
+		cache.Put("testkey2", []byte("testvalue2"))
+		cache.PutWithDeadline("testkey3", "testvalue3", time.Now().Add(10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+
+		tests := []struct {
+			key     Key
+			old     Value
+			new     Value
+			swapped bool
+			value   Value
+		}{
+			{"testkey1", "other", "testvalue1-2", false, "testvalue1"},
+			{"testkey1", "testvalue1", "testvalue1-2", true, "testvalue1-2"},
+			{"testkey2", []byte("testvalue2"), "testvalue2-2", true, "testvalue2-2"},
+			{"testkey3", "testvalue3", "testvalue3-2", false, nil},
+			{"testkey4", nil, "testvalue4", false, nil},
+		}
+		for _, test := range tests {
+			if swapped := cache.CompareAndSwap(test.key, test.old, test.new); swapped != test.swapped {
+				t.Fatalf("test %d shards key %s swap failed, expect %v, got %v", shards, test.key, test.swapped, swapped)
+			}
+			if v, _ := cache.Get(test.key); v != test.value {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+		}
+		if d, ok := cache.TTL("testkey1"); !ok || d > time.Minute || d < 50*time.Second {
+			t.Fatalf("test %d shards key %s ttl failed, expect about %v, got %v", shards, "testkey1", time.Minute, d)
+		}
+		cache.Close()
+	}
+}
+
+func TestCompareAndSwapEqual(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100, Equal: func(a, b Value) bool {
+		as, _ := a.(string)
+		bs, _ := b.(string)
+		return strings.EqualFold(as, bs)
+	}})
+	defer cache.Close()
+	cache.Put("testkey", "testvalue")
+	if !cache.CompareAndSwap("testkey", "TESTVALUE", "testvalue2") {
+		t.Fatalf("test key %s swap failed, expect %v, got %v", "testkey", true, false)
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	cache.Put("counter", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					v, _ := cache.Get("counter")
+					if cache.CompareAndSwap("counter", v, v.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := cache.Get("counter"); v != 800 {
+		t.Fatalf("test key %s failed, expect %v, got %v", "counter", 800, v)
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "container/list"
+
+// clockPolicy is CLOCK in its second chance form, the list is kept in
+// insertion order and a hit only sets the reference bit of the entry.
+// Looking for a victim from the oldest entry, the referenced ones get
+// their bit cleared and are moved to the front as if just inserted, the
+// first one found unreferenced is the victim
+type clockPolicy struct {
+	lst *list.List
+}
+
+func (p clockPolicy) adding(key Key)                                   {}
+func (p clockPolicy) add(elem *list.Element)                           {}
+func (p clockPolicy) remove(elem *list.Element, reason EvictionReason) {}
+func (p clockPolicy) reset()                                           {}
+
+func (p clockPolicy) hit(elem *list.Element) {
+	elem.Value.(*listEntry).referenced = true
+}
+
+func (p clockPolicy) victim() *list.Element {
+	// after a full turn all the bits are cleared
+	for i := 0; i < p.lst.Len(); i++ {
+		back := p.lst.Back()
+		entry := back.Value.(*listEntry)
+		if !entry.referenced {
+			return back
+		}
+		entry.referenced = false
+		p.lst.MoveToFront(back)
+	}
+	return p.lst.Back()
+}
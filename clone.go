@@ -0,0 +1,71 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// Clone returns an independent cache with the same config, entries,
+// deadlines and recency order. The clone has no SnapshotPath nor WAL,
+// which keep a single owner, its Store and Invalidator are shared
+func (lru *lruCache) Clone() Interface {
+	clone := NewCacheWithConfig(cloneConfig(lru.config)).(*lruCache)
+	lru.Lock()
+	clone.Lock()
+	lru.copyTo(clone)
+	clone.Unlock()
+	lru.Unlock()
+	return clone
+}
+
+// Clone copies the shards one by one, but locks them all at once
+func (s *shardedCache) Clone() Interface {
+	clone := newShardedCache(cloneConfig(s.config))
+	for _, shard := range s.shards {
+		shard.Lock()
+	}
+	for i, shard := range s.shards {
+		clone.shards[i].Lock()
+		shard.copyTo(clone.shards[i])
+		clone.shards[i].Unlock()
+	}
+	for _, shard := range s.shards {
+		shard.Unlock()
+	}
+	return clone
+}
+
+func cloneConfig(config Config) Config {
+	config.SnapshotPath = ""
+	config.WAL = nil
+	return config
+}
+
+// copyTo copies the entries to an empty cache of the same config, from
+// the oldest so that they end up in the same order. Both locks must be
+// held
+func (lru *lruCache) copyTo(clone *lruCache) {
+	clone.maxLen = lru.maxLen
+	for elem := lru.lst.Back(); elem != nil; elem = elem.Prev() {
+		entry := acquireEntry()
+		*entry = *elem.Value.(*listEntry)
+		entry.tags = append([]string(nil), entry.tags...)
+		clone.policy.adding(entry.key)
+		copied := clone.lst.PushFront(entry)
+		clone.hash.set(entry.key, copied)
+		clone.tag(entry)
+		clone.policy.add(copied)
+		clone.weight += entry.weight
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheClone(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		clock := NewManualClock(time.Unix(0, 0))
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, Clock: clock})
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithTimeout("testkey2", "testvalue2", time.Hour)
+		cache.PutTagged("testkey3", "testvalue3", "testtag")
+		cache.Get("testkey1")
+
+		clone := cache.Clone()
+		if keys, cloned := cache.Keys(), clone.Keys(); !reflect.DeepEqual(keys, cloned) {
+			t.Fatalf("test shards %d cloned keys failed, expect %v, got %v", shards, keys, cloned)
+		}
+		for key, value := range map[Key]Value{"testkey1": "testvalue1", "testkey2": "testvalue2", "testkey3": "testvalue3"} {
+			left, _ := cache.TTL(key)
+			if v, _ := clone.Get(key); v != value {
+				t.Fatalf("test shards %d cloned key %s failed, expect %v, got %v", shards, key, value, v)
+			}
+			if cloned, _ := clone.TTL(key); cloned != left {
+				t.Fatalf("test shards %d cloned key %s ttl failed, expect %v, got %v", shards, key, left, cloned)
+			}
+		}
+
+		clone.Put("testkey4", "testvalue4")
+		cache.Del("testkey1")
+		if cache.Contains("testkey4") || !clone.Contains("testkey1") {
+			t.Fatalf("test shards %d clone independence failed, expect %v, got %v", shards, true, false)
+		}
+		if n := clone.InvalidateTag("testtag"); n != 1 || !cache.Contains("testkey3") {
+			t.Fatalf("test shards %d cloned tag failed, expect %v, got %v", shards, 1, n)
+		}
+		cache.Close()
+		clone.Close()
+	}
+}
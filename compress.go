@@ -0,0 +1,90 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+)
+
+// Compressor compresses the []byte values stored in the cache
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// FlateCompressor is the default Compressor, backed by compress/flate
+type FlateCompressor struct {
+	Level int
+}
+
+// Compress will deflate the data with the configured level
+func (f FlateCompressor) Compress(data []byte) ([]byte, error) {
+	level := f.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress will inflate the data compressed by Compress
+func (f FlateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// compress returns the compressed form of value if it is a []byte longer
+// than the threshold and compression actually shrinks it
+func (lru *lruCache) compress(value Value) (Value, bool) {
+	if lru.compressThreshold <= 0 {
+		return value, false
+	}
+	data, ok := value.([]byte)
+	if !ok || len(data) <= lru.compressThreshold {
+		return value, false
+	}
+	compressed, err := lru.compressor.Compress(data)
+	if err != nil || len(compressed) >= len(data) {
+		return value, false
+	}
+	return compressed, true
+}
+
+// valueOf returns the value of the entry as it was put into the cache
+func (lru *lruCache) valueOf(entry *listEntry) (Value, error) {
+	if !entry.compressed {
+		return entry.value, nil
+	}
+	data, err := lru.compressor.Decompress(entry.value.([]byte))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
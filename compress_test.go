@@ -0,0 +1,106 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+type countingCompressor struct {
+	FlateCompressor
+	compressed   int
+	decompressed int
+}
+
+func (c *countingCompressor) Compress(data []byte) ([]byte, error) {
+	c.compressed++
+	return c.FlateCompressor.Compress(data)
+}
+
+func (c *countingCompressor) Decompress(data []byte) ([]byte, error) {
+	c.decompressed++
+	return c.FlateCompressor.Decompress(data)
+}
+
+func TestCacheCompress(t *testing.T) {
+	compressor := &countingCompressor{}
+	cache := NewCacheWithConfig(Config{MaxLen: 2, CompressThreshold: 1024, Compressor: compressor})
+
+	large := bytes.Repeat([]byte("testvalue"), 1024)
+	cache.Put("large", large)
+	cache.Put("small", []byte("testvalue"))
+
+	val, ok := cache.Get("large")
+	if !ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "large", true, ok)
+	}
+	if !bytes.Equal(val.([]byte), large) {
+		t.Fatalf("test key %s value failed, round trip mismatch", "large")
+	}
+	val, ok = cache.Get("small")
+	if !ok || !bytes.Equal(val.([]byte), []byte("testvalue")) {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "small", "testvalue", val)
+	}
+	if compressor.compressed != 1 || compressor.decompressed != 1 {
+		t.Fatalf("test compressor calls failed, expect 1/1, got %v/%v", compressor.compressed, compressor.decompressed)
+	}
+}
+
+type brokenCompressor struct {
+	FlateCompressor
+}
+
+func (c brokenCompressor) Decompress(data []byte) ([]byte, error) {
+	return nil, errors.New("broken data")
+}
+
+func TestCacheCorrupted(t *testing.T) {
+	var evicted []Key
+	var corrupted []Key
+	cache := NewCacheWithConfig(Config{
+		MaxLen:            2,
+		CompressThreshold: 1024,
+		Compressor:        brokenCompressor{},
+		Callback: func(key Key, value Value) {
+			if value != nil {
+				t.Fatalf("test key %s evicted value failed, expect %v, got %v", key, nil, value)
+			}
+			evicted = append(evicted, key)
+		},
+		OnCorruption: func(key Key, err error) {
+			corrupted = append(corrupted, key)
+		},
+	})
+
+	cache.Put("large", bytes.Repeat([]byte("testvalue"), 1024))
+	if _, ok := cache.Get("large"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "large", false, ok)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("test len failed, expect %v, got %v", 0, cache.Len())
+	}
+	if len(evicted) != 1 || evicted[0] != "large" {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", []Key{"large"}, evicted)
+	}
+	if len(corrupted) != 1 || corrupted[0] != "large" {
+		t.Fatalf("test corrupted keys failed, expect %v, got %v", []Key{"large"}, corrupted)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "container/list"
+
+// Add puts the key for the default lifetime only if it has no live value,
+// and reports whether it did. It counts neither as a hit nor as a miss
+// and leaves an existing entry where it is, so that the cache can serve
+// as a dedupe or lock table. With a Store the value is saved with the
+// lock held, and it is not added if it can not be saved
+func (lru *lruCache) Add(key Key, value Value) bool {
+	added := lru.add(key, value)
+	lru.audit("Add", key, !added)
+	return added
+}
+
+func (lru *lruCache) add(key Key, value Value) bool {
+	if lru.bypassed() {
+		return false
+	}
+	entry := lru.newEntry(key, value, 0)
+	lru.Lock()
+	defer lru.Unlock()
+	if _, _, ok := lru.live(key); ok || !lru.saveStore(key, value) {
+		releaseEntry(entry)
+		return false
+	}
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: value, Deadline: entry.deadTime})
+	lru.store(entry)
+	return true
+}
+
+// Replace puts the key for the default lifetime only if it has a live
+// value, and reports whether it did, so that a deleted or expired key is
+// not cached again. With a Store the value is saved with the lock held,
+// and it is not replaced if it can not be saved
+func (lru *lruCache) Replace(key Key, value Value) bool {
+	replaced := lru.replace(key, value)
+	lru.audit("Replace", key, replaced)
+	return replaced
+}
+
+func (lru *lruCache) replace(key Key, value Value) bool {
+	if lru.bypassed() {
+		return false
+	}
+	entry := lru.newEntry(key, value, 0)
+	lru.Lock()
+	defer lru.Unlock()
+	if _, _, ok := lru.live(key); !ok || !lru.saveStore(key, value) {
+		releaseEntry(entry)
+		return false
+	}
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: value, Deadline: entry.deadTime})
+	lru.store(entry)
+	return true
+}
+
+// keepDeadline gives the entry the deadline and the tags of the entry it
+// replaces
+func (e *listEntry) keepDeadline(old *listEntry) {
+	e.deadTime, e.lifetime, e.probation = old.deadTime, old.lifetime, old.probation
+	e.tags = old.tags
+}
+
+// live returns the element and value of the key if it has a live value,
+// it removes the entry if it has expired or is corrupted. The lock must
+// be held
+func (lru *lruCache) live(key Key) (*list.Element, Value, bool) {
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		return nil, nil, false
+	}
+	entry := elem.Value.(*listEntry)
+	if entry.expired(lru.now()) {
+		lru.expire(elem)
+		return nil, nil, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		lru.corrupted(elem, err)
+		return nil, nil, false
+	}
+	return elem, value, true
+}
+
+func (s *shardedCache) Add(key Key, value Value) bool {
+	return s.shard(key).Add(key, value)
+}
+
+func (s *shardedCache) Replace(key Key, value Value) bool {
+	return s.shard(key).Replace(key, value)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestAdd(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithDeadline("testkey2", "testvalue2", time.Now().Add(10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+
+		tests := []struct {
+			key   Key
+			added bool
+		}{
+			{"testkey1", false},
+			{"testkey2", true},
+			{"testkey3", true},
+		}
+		for _, test := range tests {
+			if added := cache.Add(test.key, test.key.(string)+"-added"); added != test.added {
+				t.Fatalf("test %d shards key %s add failed, expect %v, got %v", shards, test.key, test.added, added)
+			}
+		}
+		for _, test := range tests[1:] {
+			if v, _ := cache.Get(test.key); v != test.key.(string)+"-added" {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, test.key, test.key.(string)+"-added", v)
+			}
+		}
+		if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", "testvalue1", v)
+		}
+		if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 0 {
+			t.Fatalf("test %d shards stats failed, got %+v", shards, stats)
+		}
+		cache.Close()
+	}
+}
+
+func TestReplace(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		var events []EventType
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, Listeners: []Listener{func(e Event) {
+			events = append(events, e.Type)
+		}}})
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithDeadline("testkey2", "testvalue2", time.Now().Add(10*time.Millisecond))
+		cache.Put("testkey3", "testvalue3")
+		cache.Del("testkey3")
+		time.Sleep(20 * time.Millisecond)
+
+		tests := []struct {
+			key      Key
+			replaced bool
+			value    Value
+		}{
+			{"testkey1", true, "testkey1-replaced"},
+			{"testkey2", false, nil},
+			{"testkey3", false, nil},
+			{"testkey4", false, nil},
+		}
+		for _, test := range tests {
+			if replaced := cache.Replace(test.key, test.key.(string)+"-replaced"); replaced != test.replaced {
+				t.Fatalf("test %d shards key %s replace failed, expect %v, got %v", shards, test.key, test.replaced, replaced)
+			}
+			if v, _ := cache.Peek(test.key); v != test.value {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+		}
+		expected := []EventType{EventInsert, EventInsert, EventInsert, EventDelete, EventUpdate, EventExpire}
+		if len(events) != len(expected) {
+			t.Fatalf("test %d shards events failed, expect %v, got %v", shards, expected, events)
+		}
+		for i := range expected {
+			if events[i] != expected[i] {
+				t.Fatalf("test %d shards events failed, expect %v, got %v", shards, expected, events)
+			}
+		}
+		cache.Close()
+	}
+}
+
+func TestAddConcurrent(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	var added int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if cache.Add("lock", i) {
+				atomic.AddInt32(&added, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if added != 1 {
+		t.Fatalf("test key %s failed, expect %v adds, got %v", "lock", 1, added)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNotInteger is returned by IncrementInt64 and DecrementInt64 when the
+// key has a value which is not an integer
+var ErrNotInteger = errors.New("cache: value is not an integer")
+
+// ErrNotSaved is returned when a value can not be saved to the Store, the
+// error of the Store is passed to OnStoreError
+var ErrNotSaved = errors.New("cache: value not saved to the store")
+
+// IncrementInt64 adds delta to the integer value of the key with the lock
+// held and returns the result, which replaces the value as an int64 and
+// keeps the deadline of the entry. A key without a live value starts from
+// 0 for the default lifetime, like INCRBY of Redis. In bypass mode nothing
+// is stored and delta is returned
+func (lru *lruCache) IncrementInt64(key Key, delta int64) (int64, error) {
+	n, err := lru.incrementInt64(key, delta)
+	lru.audit("IncrementInt64", key, err == nil)
+	return n, err
+}
+
+// DecrementInt64 is IncrementInt64 with -delta
+func (lru *lruCache) DecrementInt64(key Key, delta int64) (int64, error) {
+	n, err := lru.incrementInt64(key, -delta)
+	lru.audit("DecrementInt64", key, err == nil)
+	return n, err
+}
+
+func (lru *lruCache) incrementInt64(key Key, delta int64) (int64, error) {
+	if lru.bypassed() {
+		return delta, nil
+	}
+	lru.Lock()
+	defer lru.Unlock()
+	n := delta
+	current, value, ok := lru.live(key)
+	if ok {
+		i, ok := ToInt64(value)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		n += i
+	}
+	if !lru.saveStore(key, n) {
+		return 0, ErrNotSaved
+	}
+	entry := lru.newEntry(key, n, 0)
+	if current != nil {
+		entry.keepDeadline(current.Value.(*listEntry))
+	}
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: n, Deadline: entry.deadTime})
+	lru.store(entry)
+	return n, nil
+}
+
+// ToInt64 converts the values of the integer types to an int64, it
+// reports false for the other types and the unsigned values too large
+// for an int64
+func ToInt64(value Value) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func (s *shardedCache) IncrementInt64(key Key, delta int64) (int64, error) {
+	return s.shard(key).IncrementInt64(key, delta)
+}
+
+func (s *shardedCache) DecrementInt64(key Key, delta int64) (int64, error) {
+	return s.shard(key).DecrementInt64(key, delta)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestIncrementInt64(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		cache.PutWithTimeout("testkey1", 1, time.Minute)
+		cache.Put("testkey2", uint8(2))
+		cache.Put("testkey3", "testvalue3")
+
+		tests := []struct {
+			key   Key
+			delta int64
+			value int64
+			err   error
+		}{
+			{"testkey1", 10, 11, nil},
+			{"testkey1", -20, -9, nil},
+			{"testkey2", 1, 3, nil},
+			{"testkey3", 1, 0, ErrNotInteger},
+			{"testkey4", 4, 4, nil},
+		}
+		for _, test := range tests {
+			n, err := cache.IncrementInt64(test.key, test.delta)
+			if n != test.value || err != test.err {
+				t.Fatalf("test %d shards key %s failed, expect %v %v, got %v %v", shards, test.key, test.value, test.err, n, err)
+			}
+		}
+		if n, _ := cache.DecrementInt64("testkey4", 5); n != -1 {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey4", -1, n)
+		}
+		if v, _ := cache.Get("testkey1"); v != int64(-9) {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", int64(-9), v)
+		}
+		if d, _ := cache.TTL("testkey1"); d > time.Minute || d < 50*time.Second {
+			t.Fatalf("test %d shards key %s ttl failed, expect about %v, got %v", shards, "testkey1", time.Minute, d)
+		}
+		if v, _ := cache.Get("testkey3"); v != "testvalue3" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey3", "testvalue3", v)
+		}
+		cache.Close()
+	}
+}
+
+func TestIncrementInt64Concurrent(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.IncrementInt64("counter", 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := cache.Get("counter"); v != int64(800) {
+		t.Fatalf("test key %s failed, expect %v, got %v", "counter", 800, v)
+	}
+}
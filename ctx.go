@@ -0,0 +1,51 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "context"
+
+// GetCtx is Get with a context, it returns the error of ctx instead if
+// ctx is done
+func (lru *lruCache) GetCtx(ctx context.Context, key Key) (Value, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, ok := lru.Get(key)
+	return value, ok, nil
+}
+
+// PutCtx is Put with a context, nothing is put if ctx is done and its
+// error is returned
+func (lru *lruCache) PutCtx(ctx context.Context, key Key, value Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lru.Put(key, value)
+	return nil
+}
+
+func (s *shardedCache) GetCtx(ctx context.Context, key Key) (Value, bool, error) {
+	return s.shard(key).GetCtx(ctx, key)
+}
+
+func (s *shardedCache) PutCtx(ctx context.Context, key Key, value Value) error {
+	return s.shard(key).PutCtx(ctx, key, value)
+}
+
+func (s *shardedCache) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	return s.shard(key).GetOrLoadCtx(ctx, key, load)
+}
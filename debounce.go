@@ -0,0 +1,109 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type pendingPut struct {
+	value Value
+	t     time.Duration
+	timer *time.Timer
+}
+
+// debouncer holds back the puts of a key until no new put for the same
+// key arrives within the window, then applies only the last one
+type debouncer struct {
+	window  time.Duration
+	apply   func(key Key, value Value, t time.Duration)
+	pending map[Key]*pendingPut
+	sync.Mutex
+}
+
+func newDebouncer(window time.Duration, apply func(key Key, value Value, t time.Duration)) *debouncer {
+	return &debouncer{
+		window:  window,
+		apply:   apply,
+		pending: map[Key]*pendingPut{},
+	}
+}
+
+// put reports whether an earlier put of the key was still pending
+func (d *debouncer) put(key Key, value Value, t time.Duration) bool {
+	d.Lock()
+	defer d.Unlock()
+	if p, exists := d.pending[key]; exists {
+		p.value, p.t = value, t
+		p.timer.Reset(d.window)
+		return true
+	}
+	p := &pendingPut{value: value, t: t}
+	p.timer = time.AfterFunc(d.window, func() { d.fire(key, p) })
+	d.pending[key] = p
+	return false
+}
+
+func (d *debouncer) fire(key Key, p *pendingPut) {
+	d.Lock()
+	if d.pending[key] != p {
+		d.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	value, t := p.value, p.t
+	d.Unlock()
+	d.apply(key, value, t)
+}
+
+// cancel drops the pending puts of the keys
+func (d *debouncer) cancel(keys ...Key) {
+	d.Lock()
+	defer d.Unlock()
+	for _, key := range keys {
+		if p, exists := d.pending[key]; exists {
+			p.timer.Stop()
+			delete(d.pending, key)
+		}
+	}
+}
+
+// cancelFunc drops the pending puts of the keys fn returns true for
+func (d *debouncer) cancelFunc(fn func(key Key) bool) {
+	d.Lock()
+	defer d.Unlock()
+	for key, p := range d.pending {
+		if fn(key) {
+			p.timer.Stop()
+			delete(d.pending, key)
+		}
+	}
+}
+
+// stop applies all the pending puts at once, without waiting for their
+// windows to end
+func (d *debouncer) stop() {
+	d.Lock()
+	pending := d.pending
+	d.pending = map[Key]*pendingPut{}
+	d.Unlock()
+	for key, p := range pending {
+		p.timer.Stop()
+		d.apply(key, p.value, p.t)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCachePutDebounce(t *testing.T) {
+	var lock sync.Mutex
+	var evicted []Value
+	cb := func(key Key, value Value) {
+		lock.Lock()
+		defer lock.Unlock()
+		evicted = append(evicted, value)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 1, Callback: cb, PutDebounce: 50 * time.Millisecond})
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		cache.Put("testkey1", i)
+	}
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	val, ok := cache.Get("testkey1")
+	if !ok || val != 99 {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", 99, val)
+	}
+
+	// only the coalesced value was ever stored, so only it gets evicted
+	cache.Put("testkey2", "testvalue2")
+	time.Sleep(200 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if len(evicted) != 1 || evicted[0] != 99 {
+		t.Fatalf("test evicted values failed, expect %v, got %v", []Value{99}, evicted)
+	}
+}
+
+func TestCachePutDebounceDel(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, PutDebounce: 50 * time.Millisecond})
+		tests := []struct {
+			key string
+			del func(key string)
+		}{
+			{"testkey1", func(key string) { cache.Del(key) }},
+			{"testkey2", func(key string) { cache.DelMulti([]Key{key}) }},
+			{"testkey3", func(key string) { cache.DelPrefix(key) }},
+		}
+		for _, test := range tests {
+			cache.Put(test.key, "testvalue")
+			test.del(test.key)
+		}
+		// a pending put does not overwrite the tagged entry put after it
+		cache.Put("testkey4", "testvalue4")
+		cache.PutTagged("testkey4", "testvalue5", "testtag")
+
+		time.Sleep(150 * time.Millisecond)
+		for _, test := range tests {
+			if v, ok := cache.Get(test.key); ok {
+				t.Fatalf("test shards %d deleted key %s failed, expect %v, got %v", shards, test.key, nil, v)
+			}
+		}
+		if n := cache.InvalidateTag("testtag"); n != 1 {
+			t.Fatalf("test shards %d tagged key %s failed, expect %v, got %v", shards, "testkey4", 1, n)
+		}
+		cache.Close()
+	}
+}
+
+func TestCachePutDebounceStore(t *testing.T) {
+	store := newMapStore()
+	cache := NewCacheWithConfig(Config{MaxLen: 2, Store: store, PutDebounce: 50 * time.Millisecond})
+
+	for i := 0; i < 100; i++ {
+		cache.Put("testkey1", i)
+	}
+	time.Sleep(200 * time.Millisecond)
+	store.Lock()
+	if store.saves != 1 || store.data["testkey1"] != 99 {
+		t.Fatalf("test store saves failed, expect %v, got %v", 1, store.saves)
+	}
+	store.Unlock()
+
+	// the puts still pending are applied by Close, not dropped
+	cache.Put("testkey2", "testvalue2")
+	cache.Close()
+	store.Lock()
+	defer store.Unlock()
+	if store.data["testkey2"] != "testvalue2" {
+		t.Fatalf("test key %s store value failed, expect %v, got %v", "testkey2", "testvalue2", store.data["testkey2"])
+	}
+}
+
+func TestCachePutDebounceCloseSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	cache := NewCacheWithConfig(Config{MaxLen: 2, SnapshotPath: path, PutDebounce: time.Minute})
+	cache.Put("testkey1", "testvalue1")
+	cache.Close()
+
+	cache = NewCacheWithConfig(Config{MaxLen: 2, SnapshotPath: path})
+	defer cache.Close()
+	if val, ok := cache.Get("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// EventType tells what happened to an entry
+type EventType int
+
+const (
+	// EventInsert is fired when a key without a live entry is put
+	EventInsert EventType = iota + 1
+	// EventUpdate is fired when the value of an existing entry is put
+	EventUpdate
+	// EventDelete is fired when an entry is removed explicitly
+	EventDelete
+	// EventExpire is fired when an entry is removed past its deadline
+	EventExpire
+	// EventEvict is fired when an entry is removed to make room, or
+	// because its value is corrupted
+	EventEvict
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	}
+	return "unknown"
+}
+
+// Event describes a change of an entry, Value is the new value for the
+// inserts and updates and the removed one otherwise. Reason is set for
+// the removals only
+type Event struct {
+	Type   EventType
+	Key    Key
+	Value  Value
+	Reason EvictionReason
+}
+
+// Listener func will be called for every event of the entries
+type Listener func(event Event)
+
+// eventOf returns the event of an entry removed for reason
+func eventOf(reason EvictionReason) EventType {
+	switch reason {
+	case ReasonDeleted, ReasonPurged:
+		return EventDelete
+	case ReasonExpired:
+		return EventExpire
+	}
+	return EventEvict
+}
+
+// emit calls the listeners in the order they were configured, the lock
+// must be held
+func (lru *lruCache) emit(event Event) {
+	for _, listener := range lru.listeners {
+		lru.callListener(listener, event)
+	}
+}
+
+func (lru *lruCache) callListener(listener Listener, event Event) {
+	defer recoverCallback(event.Key, lru.callbackFailed)
+	listener(event)
+}
+
+// emitStored reports the put of an entry
+func (lru *lruCache) emitStored(typ EventType, entry *listEntry) {
+	if len(lru.listeners) == 0 {
+		return
+	}
+	value, _ := lru.valueOf(entry)
+	lru.emit(Event{Type: typ, Key: entry.key, Value: value})
+}
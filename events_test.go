@@ -0,0 +1,97 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestListeners(t *testing.T) {
+	var events, logged []string
+	cache := NewCacheWithConfig(Config{MaxLen: 2, Listeners: []Listener{
+		func(event Event) {
+			events = append(events, fmt.Sprintf("%v %v %v", event.Type, event.Key, event.Value))
+		},
+		func(event Event) {
+			logged = append(logged, event.Type.String())
+		},
+	}})
+	defer cache.Close()
+
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey1", "testvalue2")
+	cache.PutWithTimeout("testkey2", "testvalue3", time.Second)
+	// evicts testkey1, the least recently used one
+	cache.Put("testkey3", "testvalue4")
+	cache.Del("testkey3")
+	time.Sleep(1100 * time.Millisecond)
+	cache.Get("testkey2")
+
+	expect := []string{
+		"insert testkey1 testvalue1",
+		"update testkey1 testvalue2",
+		"insert testkey2 testvalue3",
+		"evict testkey1 testvalue2",
+		"insert testkey3 testvalue4",
+		"delete testkey3 testvalue4",
+		"expire testkey2 testvalue3",
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Fatalf("test events failed, expect %v, got %v", expect, events)
+	}
+	if len(logged) != len(expect) {
+		t.Fatalf("test second listener failed, expect %v events, got %v", len(expect), logged)
+	}
+}
+
+func TestCallbackPanic(t *testing.T) {
+	var panics []Key
+	cache := NewCacheWithConfig(Config{
+		MaxLen: 1,
+		Callback: func(key Key, value Value) {
+			panic("callback")
+		},
+		Listeners: []Listener{func(event Event) {
+			panic("listener")
+		}},
+		OnCallbackError: func(key Key, err error) {
+			var p *CallbackPanic
+			if !errors.As(err, &p) || len(p.Stack) == 0 {
+				t.Fatalf("test key %v panic failed, expect a *CallbackPanic, got %v", key, err)
+			}
+			panics = append(panics, key)
+		},
+	})
+	defer cache.Close()
+
+	cache.Put("testkey1", "testvalue1")
+	// evicts testkey1, both callbacks panic
+	cache.Put("testkey2", "testvalue2")
+	if v, ok := cache.Get("testkey2"); !ok || v != "testvalue2" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", "testvalue2", v)
+	}
+	expect := []Key{"testkey1", "testkey1", "testkey1", "testkey2"}
+	if !reflect.DeepEqual(panics, expect) {
+		t.Fatalf("test panics failed, expect %v, got %v", expect, panics)
+	}
+}
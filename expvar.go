@@ -0,0 +1,35 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "expvar"
+
+// expvarStats is the published value, with the hit ratio computed
+type expvarStats struct {
+	Stats
+	HitRatio float64
+}
+
+// PublishExpvar publishes the Stats of c with their hit ratio under name
+// in expvar, they are read again every time /debug/vars is served. It
+// panics like expvar.Publish if the name is already taken
+func PublishExpvar(name string, c Interface) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		stats := c.Stats()
+		return expvarStats{stats, stats.HitRatio()}
+	}))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashKey returns a well mixed 64 bits hash of the key. Common key types
+// are hashed directly, any other key is hashed through its %v format, so
+// distinct keys of such types must format differently
+func hashKey(key Key) uint64 {
+	switch k := key.(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return hashUint64(uint64(k))
+	case int64:
+		return hashUint64(uint64(k))
+	case int32:
+		return hashUint64(uint64(k))
+	case uint:
+		return hashUint64(uint64(k))
+	case uint64:
+		return hashUint64(k)
+	case uint32:
+		return hashUint64(uint64(k))
+	case NamespacedKey:
+		return mix64(hashString(k.Namespace) ^ hashKey(k.Key) ^ hashString(k.Parents))
+	default:
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%T:%v", key, key)
+		return mix64(h.Sum64())
+	}
+}
+
+// hashString is fnv-1a, written out so that it does not allocate
+func hashString(s string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return mix64(h)
+}
+
+func hashUint64(v uint64) uint64 {
+	return mix64(v)
+}
+
+// mix64 is the finalizer of MurmurHash3, it spreads the entropy of the
+// input over all the bits
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
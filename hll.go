@@ -0,0 +1,92 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hllPrecision of 12 bits gives 4096 registers of one byte each, and a
+// standard error of 1.04/sqrt(4096), about 1.6%
+const hllPrecision = 12
+
+// hyperLogLog estimates the number of distinct keys added to it in a
+// fixed amount of memory, it is not safe for concurrent use
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+// add reports whether the estimate may have changed
+func (h *hyperLogLog) add(key Key) bool {
+	hash := hashKey(key)
+	idx := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+		return true
+	}
+	return false
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+// trackKey adds the key to the cardinality estimate, the lock must be held
+func (lru *lruCache) trackKey(key Key) {
+	if lru.cardinality == nil || !lru.cardinality.add(key) {
+		return
+	}
+	if lru.cardinalityExceeded || lru.onCardinalityExceeded == nil || lru.cardinalityThreshold == 0 {
+		return
+	}
+	if estimate := lru.cardinality.estimate(); estimate > lru.cardinalityThreshold {
+		lru.cardinalityExceeded = true
+		lru.onCardinalityExceeded(estimate)
+	}
+}
+
+// DistinctKeysEstimate returns the estimated number of distinct keys ever
+// put into the cache, it is always 0 unless TrackCardinality is set
+func (lru *lruCache) DistinctKeysEstimate() uint64 {
+	lru.Lock()
+	defer lru.Unlock()
+	if lru.cardinality == nil {
+		return 0
+	}
+	return lru.cardinality.estimate()
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheDistinctKeysEstimate(t *testing.T) {
+	var alerted uint64
+	cache := NewCacheWithConfig(Config{
+		MaxLen:                100,
+		TrackCardinality:      true,
+		CardinalityThreshold:  50000,
+		OnCardinalityExceeded: func(estimate uint64) { alerted = estimate },
+	})
+
+	const distinct = 100000
+	for i := 0; i < distinct; i++ {
+		cache.Put("testkey"+strconv.Itoa(i), i)
+		// repeated keys must not count twice
+		cache.Put("testkey"+strconv.Itoa(i/2), i)
+	}
+	estimate := cache.(Estimator).DistinctKeysEstimate()
+	if diff := math.Abs(float64(estimate)-distinct) / distinct; diff > 0.05 {
+		t.Fatalf("test estimate failed, expect about %v, got %v", distinct, estimate)
+	}
+	if alerted <= 50000 || alerted > distinct {
+		t.Fatalf("test cardinality alert failed, expect an estimate above %v, got %v", 50000, alerted)
+	}
+	if cache.Len() != 100 {
+		t.Fatalf("test len failed, expect %v, got %v", 100, cache.Len())
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"strings"
+)
+
+// keyIndex maps the keys to their list elements. string and int64 keys
+// are kept in typed maps, so that the typed methods can look them up
+// without converting the key to an interface first
+type keyIndex struct {
+	any  map[Key]*list.Element
+	str  map[string]*list.Element
+	ints map[int64]*list.Element
+}
+
+func newKeyIndex() keyIndex {
+	return keyIndex{
+		any:  map[Key]*list.Element{},
+		str:  map[string]*list.Element{},
+		ints: map[int64]*list.Element{},
+	}
+}
+
+func (idx *keyIndex) get(key Key) (*list.Element, bool) {
+	var elem *list.Element
+	var exists bool
+	switch k := key.(type) {
+	case string:
+		elem, exists = idx.str[k]
+	case int64:
+		elem, exists = idx.ints[k]
+	default:
+		elem, exists = idx.any[key]
+	}
+	return elem, exists
+}
+
+func (idx *keyIndex) set(key Key, elem *list.Element) {
+	switch k := key.(type) {
+	case string:
+		idx.str[k] = elem
+	case int64:
+		idx.ints[k] = elem
+	default:
+		idx.any[key] = elem
+	}
+}
+
+func (idx *keyIndex) remove(key Key) {
+	switch k := key.(type) {
+	case string:
+		delete(idx.str, k)
+	case int64:
+		delete(idx.ints, k)
+	default:
+		delete(idx.any, key)
+	}
+}
+
+func (idx *keyIndex) len() int {
+	return len(idx.any) + len(idx.str) + len(idx.ints)
+}
+
+func (idx *keyIndex) each(fn func(key Key, elem *list.Element)) {
+	for key, elem := range idx.any {
+		fn(key, elem)
+	}
+	for key, elem := range idx.str {
+		fn(key, elem)
+	}
+	for key, elem := range idx.ints {
+		fn(key, elem)
+	}
+}
+
+// PutString is the same as Put with a string key
+func (lru *lruCache) PutString(key string, value Value) {
+	replaced := lru.debouncedPut(key, value, 0)
+	lru.audit("PutString", key, replaced)
+}
+
+// GetString is the same as Get with a string key, but it looks the key up
+// without boxing it into an interface, which saves an allocation per call
+func (lru *lruCache) GetString(key string) (Value, bool) {
+	var value Value
+	var ok bool
+	if !lru.bypassed() {
+		done := false
+		if lru.reads != nil {
+			lru.RLock()
+			elem := lru.hash.str[key]
+			value, ok, done = lru.sharedAccess(elem)
+			lru.RUnlock()
+			if ok {
+				lru.recordRead(elem)
+			}
+		}
+		if !done {
+			lru.Lock()
+			value, ok = lru.access(lru.hash.str[key])
+			lru.Unlock()
+		}
+	}
+	if !ok && lru.backing != nil {
+		value, ok = lru.loadStore(key)
+	}
+	if lru.auditor != nil {
+		lru.audit("GetString", key, ok)
+	}
+	return value, ok
+}
+
+// PutInt is the same as Put with an int64 key. Keys of other integer
+// types, like int, are distinct from the int64 ones
+func (lru *lruCache) PutInt(key int64, value Value) {
+	replaced := lru.debouncedPut(key, value, 0)
+	lru.audit("PutInt", key, replaced)
+}
+
+// GetInt is the same as Get with an int64 key, but it looks the key up
+// without boxing it into an interface, which saves an allocation per call
+func (lru *lruCache) GetInt(key int64) (Value, bool) {
+	var value Value
+	var ok bool
+	if !lru.bypassed() {
+		done := false
+		if lru.reads != nil {
+			lru.RLock()
+			elem := lru.hash.ints[key]
+			value, ok, done = lru.sharedAccess(elem)
+			lru.RUnlock()
+			if ok {
+				lru.recordRead(elem)
+			}
+		}
+		if !done {
+			lru.Lock()
+			value, ok = lru.access(lru.hash.ints[key])
+			lru.Unlock()
+		}
+	}
+	if !ok && lru.backing != nil {
+		value, ok = lru.loadStore(key)
+	}
+	if lru.auditor != nil {
+		lru.audit("GetInt", key, ok)
+	}
+	return value, ok
+}
+
+// DelPrefix deletes the entries whose key is a string starting with
+// prefix under one lock, and returns how many there were. It visits every
+// string key, the keys of the other types are left alone. The callbacks
+// are fired with ReasonDeleted and the keys are deleted from the Store as
+// well
+func (lru *lruCache) DelPrefix(prefix string) int {
+	if lru.debouncer != nil {
+		lru.debouncer.cancelFunc(func(key Key) bool {
+			s, ok := key.(string)
+			return ok && strings.HasPrefix(s, prefix)
+		})
+	}
+	return lru.delFound("DelPrefix", func() []Key {
+		var keys []Key
+		for key := range lru.hash.str {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	})
+}
+
+func (s *shardedCache) DelPrefix(prefix string) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.DelPrefix(prefix)
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"strconv"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheTypedKeys(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+
+	cache.PutString("testkey1", "testvalue1")
+	cache.Put(int64(2), "testvalue2")
+	cache.Put(2, "testvalue3")
+
+	if val, ok := cache.Get("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+	if val, ok := cache.GetString("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+	if val, ok := cache.GetInt(2); !ok || val != "testvalue2" {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 2, "testvalue2", val)
+	}
+	if val, ok := cache.Get(2); !ok || val != "testvalue3" {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 2, "testvalue3", val)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("test len failed, expect %v, got %v", 3, cache.Len())
+	}
+
+	// evicting the oldest entry must clean up the typed index as well
+	cache.PutInt(4, "testvalue4")
+	if _, ok := cache.GetString("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("test len failed, expect %v, got %v", 3, cache.Len())
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "testkey" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkGet(b *testing.B) {
+	keys := benchmarkKeys(1024)
+	cache := NewCacheWithConfig(Config{MaxLen: len(keys)})
+	for _, key := range keys {
+		cache.Put(key, key)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkGetString(b *testing.B) {
+	keys := benchmarkKeys(1024)
+	cache := NewCacheWithConfig(Config{MaxLen: len(keys)})
+	for _, key := range keys {
+		cache.PutString(key, key)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.GetString(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkPutEvicting(b *testing.B) {
+	keys := benchmarkKeys(4096)
+	cache := NewCacheWithConfig(Config{MaxLen: len(keys) / 4})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.PutString(keys[i%len(keys)], i)
+	}
+}
+
+func TestDelPrefix(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		cache.Put("user:1:name", "testvalue1")
+		cache.Put("user:1:mail", "testvalue2")
+		cache.Put("user:12:name", "testvalue3")
+		cache.Put("user:2:name", "testvalue4")
+		cache.Put(1, "testvalue5")
+
+		tests := []struct {
+			prefix string
+			count  int
+		}{
+			{"user:1:", 2},
+			{"user:1:", 0},
+			{"user:1", 1},
+			{"group:", 0},
+			{"", 1},
+		}
+		for _, test := range tests {
+			if n := cache.DelPrefix(test.prefix); n != test.count {
+				t.Fatalf("test %d shards prefix %q failed, expect %v, got %v", shards, test.prefix, test.count, n)
+			}
+		}
+		if v, _ := cache.Get(1); v != "testvalue5" {
+			t.Fatalf("test %d shards key %v failed, expect %v, got %v", shards, 1, "testvalue5", v)
+		}
+		cache.Close()
+	}
+}
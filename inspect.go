@@ -0,0 +1,173 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// InspectReport describes the internal state of the cache, it is meant
+// for debugging and tests only
+type InspectReport struct {
+	ListLen  int
+	IndexLen int
+	// Consistent is true if the list and the index hold exactly the same
+	// entries and no anomaly was found
+	Consistent bool
+	FrontKey   Key
+	BackKey    Key
+	// Expired counts the entries that have expired but are still resident
+	Expired   int
+	Anomalies []string
+}
+
+// Inspect walks the whole list and index under the lock to verify they
+// agree with each other. It is a diagnostic tool, far too slow for the hot
+// path
+func (lru *lruCache) Inspect() InspectReport {
+	lru.Lock()
+	defer lru.Unlock()
+
+	report := InspectReport{
+		ListLen:  lru.lst.Len(),
+		IndexLen: lru.hash.len(),
+	}
+	if front := lru.lst.Front(); front != nil {
+		if entry, ok := front.Value.(*listEntry); ok {
+			report.FrontKey = entry.key
+		}
+	}
+	if back := lru.lst.Back(); back != nil {
+		if entry, ok := back.Value.(*listEntry); ok {
+			report.BackKey = entry.key
+		}
+	}
+
+	now := lru.now()
+	inList := make(map[*list.Element]bool, lru.lst.Len())
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		inList[elem] = true
+		entry, ok := elem.Value.(*listEntry)
+		if !ok || entry == nil {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("list element holds %T instead of an entry", elem.Value))
+			continue
+		}
+		if entry.expired(now) {
+			report.Expired++
+		}
+		if indexed, exists := lru.hash.get(entry.key); !exists {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("key %v in list but not in index", entry.key))
+		} else if indexed != elem {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("key %v indexed to another element", entry.key))
+		}
+	}
+	lru.hash.each(func(key Key, elem *list.Element) {
+		if elem == nil {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("key %v indexed to a nil element", key))
+		} else if !inList[elem] {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("key %v in index but not in list", key))
+		}
+	})
+
+	report.Consistent = report.ListLen == report.IndexLen && len(report.Anomalies) == 0
+	return report
+}
+
+// Entry describes a cached entry
+type Entry struct {
+	Key   Key
+	Value Value
+	// Deadline is the zero time for the entries that never expire
+	Deadline time.Time
+	// Age is the time since Created, 0 if unknown
+	Age time.Duration
+	// Created is when the current value was put, overwriting the key
+	// resets it. It is the zero time if unknown
+	Created time.Time
+	// LastAccess is when the entry was last read, the zero time if it was
+	// never read or if unknown
+	LastAccess time.Time
+	// Hits counts the reads of the entry since it was inserted
+	Hits uint64
+}
+
+// GetEntry returns the live entry of the key with its metadata. Like Peek
+// it is not counted as a read, neither in the entry nor in the stats
+func (lru *lruCache) GetEntry(key Key) (Entry, bool) {
+	var entry Entry
+	var ok bool
+	if !lru.bypassed() {
+		lru.Lock()
+		entry, ok = lru.getEntry(key)
+		lru.Unlock()
+	}
+	lru.audit("GetEntry", key, ok)
+	return entry, ok
+}
+
+func (lru *lruCache) getEntry(key Key) (Entry, bool) {
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		return Entry{}, false
+	}
+	entry := elem.Value.(*listEntry)
+	now := lru.now()
+	if entry.expired(now) {
+		return Entry{}, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		return Entry{}, false
+	}
+	return entry.export(value, now), true
+}
+
+// GetOldest returns the least recently used live entry without moving it,
+// or the oldest inserted one with PolicyFIFO and PolicyCLOCK. It is the
+// next entry evicted with PolicyLRU and PolicyFIFO only, the other
+// policies pick their victim by their own state, which GetOldest does not
+// look at: use RemoveOldest to evict their victim
+func (lru *lruCache) GetOldest() (Entry, bool) {
+	lru.Lock()
+	defer lru.Unlock()
+	return lru.edge(lru.lst.Back(), (*list.Element).Prev)
+}
+
+// GetNewest returns the most recently used live entry without moving it
+func (lru *lruCache) GetNewest() (Entry, bool) {
+	lru.Lock()
+	defer lru.Unlock()
+	return lru.edge(lru.lst.Front(), (*list.Element).Next)
+}
+
+// edge returns the first live entry from elem on, skipping the expired
+// and corrupted ones without removing them. The lock must be held
+func (lru *lruCache) edge(elem *list.Element, next func(*list.Element) *list.Element) (Entry, bool) {
+	now := lru.now()
+	for ; elem != nil; elem = next(elem) {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		if value, err := lru.valueOf(entry); err == nil {
+			return entry.export(value, now), true
+		}
+	}
+	return Entry{}, false
+}
@@ -16,13 +16,141 @@ limitations under the License.
 
 package cache
 
-import "time"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type Interface interface {
 	Put(key Key, value Value)
 	PutWithTimeout(key Key, value Value, t time.Duration)
+	PutWithDeadline(key Key, value Value, deadline time.Time)
+	PutCtx(ctx context.Context, key Key, value Value) error
 	Get(key Key) (Value, bool)
+	GetCtx(ctx context.Context, key Key) (Value, bool, error)
+	Peek(key Key) (Value, bool)
+	GetEntry(key Key) (Entry, bool)
+	Contains(key Key) bool
+	TTL(key Key) (time.Duration, bool)
+	Touch(key Key, d time.Duration) bool
+	GetOrStore(key Key, def Value, t time.Duration) (Value, bool)
+	GetOrLoad(key Key, load LoadFunc) (Value, error)
+	GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error)
+	LockKey(key Key) func()
+	Add(key Key, value Value) bool
+	Replace(key Key, value Value) bool
+	CompareAndSwap(key Key, old, new Value) bool
+	IncrementInt64(key Key, delta int64) (int64, error)
+	DecrementInt64(key Key, delta int64) (int64, error)
+	PutString(key string, value Value)
+	GetString(key string) (Value, bool)
+	PutInt(key int64, value Value)
+	GetInt(key int64) (Value, bool)
+	PutMulti(entries map[Key]Value)
+	PutMultiWithTimeout(entries map[Key]Value, t time.Duration)
+	PutNotFound(key Key)
+	GetMulti(keys []Key) map[Key]Value
 	Del(key Key) Value
+	DelE(key Key) (Value, bool)
+	DelMulti(keys []Key) int
+	DelPrefix(prefix string) int
+	DelFunc(fn func(key Key, value Value) bool) int
+	PutTagged(key Key, value Value, tags ...string)
+	InvalidateTag(tag string) int
 	Len() int
-	Close()
+	Keys() []Key
+	Stats() Stats
+	Range(fn func(key Key, value Value) bool)
+	SetBypass(bypass bool)
+	Flush()
+	Purge()
+	Close() error
+	Namespace(name string) Interface
+	Clone() Interface
+	Merge(other Interface, conflict func(key Key, a, b Value) Value)
 }
+
+// The optional interfaces below are checked with a type assertion, the
+// caches returned by NewCache and NewCacheWithConfig implement all of them
+
+// Evicter is implemented by the caches that keep their entries in order
+type Evicter interface {
+	RemoveOldest() (Key, Value, bool)
+	GetOldest() (Entry, bool)
+	GetNewest() (Entry, bool)
+}
+
+// Resizer is implemented by the caches whose size is bounded in process
+type Resizer interface {
+	Resize(maxLen int)
+	Weight() int64
+}
+
+// Snapshotter is implemented by the caches that can copy all their
+// entries at once
+type Snapshotter interface {
+	Snapshot() []Entry
+}
+
+// Estimator is implemented by the caches that track their evictions and
+// their distinct keys
+type Estimator interface {
+	EvictionRate() float64
+	DistinctKeysEstimate() uint64
+}
+
+// Inspector is implemented by the caches that can report on their
+// internal state
+type Inspector interface {
+	Inspect() InspectReport
+	GetWithCount(key Key) (Value, uint64, bool)
+	NextExpiry() (time.Time, bool)
+}
+
+// StaleReader is implemented by the caches that keep the expired entries
+// until they are evicted
+type StaleReader interface {
+	GetAllowStale(key Key, maxStale time.Duration) (Value, bool, bool)
+}
+
+// LockedRanger is implemented by the caches that can range over their
+// entries holding their lock, see RangeOptions
+type LockedRanger interface {
+	RangeWithOptions(fn func(key Key, value Value) bool, opts RangeOptions)
+}
+
+// Persister is implemented by the caches that can write their entries
+// out and read them back
+type Persister interface {
+	ReplayWAL(r io.Reader) error
+	SaveTo(w io.Writer) error
+	ExportJSON(w io.Writer, codec JSONCodec) error
+	ImportJSON(r io.Reader, codec JSONCodec) error
+}
+
+// Warmer is implemented by the caches that can load a list of keys in
+// parallel
+type Warmer interface {
+	Warmup(keys []Key, loader Loader, parallelism int) error
+}
+
+// fullCache is implemented by the caches of this package
+type fullCache interface {
+	Interface
+	Evicter
+	Resizer
+	Snapshotter
+	Estimator
+	Inspector
+	StaleReader
+	LockedRanger
+	Persister
+	Warmer
+	cacheClock() Clock
+}
+
+var (
+	_ fullCache = (*lruCache)(nil)
+	_ fullCache = (*shardedCache)(nil)
+)
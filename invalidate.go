@@ -0,0 +1,148 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "sync"
+
+// Invalidator carries the keys deleted from a cache to the caches of the
+// other processes, see Config.Invalidator. InvalidationBus connects the
+// caches of one process, rediscache.NewInvalidator the processes sharing
+// a Redis
+type Invalidator interface {
+	// Publish announces the keys deleted by this process
+	Publish(keys []Key) error
+	// Subscribe calls fn with the keys published by the other
+	// Invalidators, not the ones published by this one, until stop is
+	// called
+	Subscribe(fn func(keys []Key)) (stop func(), err error)
+}
+
+// invalidationBuffer is the number of publications a subscriber of an
+// InvalidationBus can lag behind before Publish waits for it
+const invalidationBuffer = 16
+
+// InvalidationBus connects Invalidators through channels, every key
+// published by one of them is delivered to the subscribers of the others
+type InvalidationBus struct {
+	subs map[*busSubscription]struct{}
+	sync.Mutex
+}
+
+type busInvalidator struct {
+	bus *InvalidationBus
+}
+
+type busSubscription struct {
+	from *busInvalidator
+	keys chan []Key
+	done chan struct{}
+}
+
+// NewInvalidationBus will create a bus without Invalidators
+func NewInvalidationBus() *InvalidationBus {
+	return &InvalidationBus{subs: map[*busSubscription]struct{}{}}
+}
+
+// Invalidator returns a new Invalidator connected to the bus
+func (b *InvalidationBus) Invalidator() Invalidator {
+	return &busInvalidator{bus: b}
+}
+
+// Publish queues the keys to the subscribers of the other Invalidators,
+// it waits while one of them lags behind
+func (i *busInvalidator) Publish(keys []Key) error {
+	i.bus.Lock()
+	defer i.bus.Unlock()
+	for sub := range i.bus.subs {
+		if sub.from != i {
+			sub.keys <- keys
+		}
+	}
+	return nil
+}
+
+// Subscribe calls fn in a goroutine of its own, stop waits for the keys
+// already queued to be handled
+func (i *busInvalidator) Subscribe(fn func(keys []Key)) (func(), error) {
+	sub := &busSubscription{from: i, keys: make(chan []Key, invalidationBuffer), done: make(chan struct{})}
+	go func() {
+		defer close(sub.done)
+		for keys := range sub.keys {
+			fn(keys)
+		}
+	}()
+	i.bus.Lock()
+	i.bus.subs[sub] = struct{}{}
+	i.bus.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			i.bus.Lock()
+			delete(i.bus.subs, sub)
+			i.bus.Unlock()
+			close(sub.keys)
+			<-sub.done
+		})
+	}, nil
+}
+
+// publish announces the deleted keys to the other processes, it must be
+// called without the lock
+func (lru *lruCache) publish(keys ...Key) {
+	if lru.invalidator == nil || len(keys) == 0 {
+		return
+	}
+	if err := lru.invalidator.Publish(keys); err != nil && lru.onInvalidatorError != nil {
+		lru.onInvalidatorError(err)
+	}
+}
+
+// subscribe starts to apply the keys published by the other processes
+// with invalidate, the function returned stops it once
+func subscribe(invalidator Invalidator, invalidate func(keys []Key), onError func(err error)) func() {
+	stop, err := invalidator.Subscribe(invalidate)
+	if err != nil {
+		if onError != nil {
+			onError(err)
+		}
+		return nil
+	}
+	var once sync.Once
+	return func() { once.Do(stop) }
+}
+
+// invalidate deletes the keys published by another process, without
+// deleting them from the Store nor publishing them again. The callbacks
+// are fired with ReasonDeleted
+func (lru *lruCache) invalidate(keys []Key) {
+	lru.Lock()
+	defer lru.Unlock()
+	for _, key := range keys {
+		if elem, exists := lru.hash.get(key); exists {
+			lru.logWAL(walRecord{Op: walDel, Key: key})
+			lru.removeElem(elem, ReasonDeleted)
+		}
+	}
+}
+
+func (s *shardedCache) invalidate(keys []Key) {
+	for i, part := range s.splitKeys(keys) {
+		if len(part) > 0 {
+			s.shards[i].invalidate(part)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+// waitDeleted waits for the keys to be deleted from the cache by the
+// invalidations of another one
+func waitDeleted(t *testing.T, deleted chan Key, keys ...Key) {
+	want := map[Key]bool{}
+	for _, key := range keys {
+		want[key] = true
+	}
+	for len(want) > 0 {
+		select {
+		case key := <-deleted:
+			if !want[key] {
+				t.Fatalf("test key %v failed, expect it to be kept, got it deleted", key)
+			}
+			delete(want, key)
+		case <-time.After(time.Second):
+			t.Fatalf("test keys %v failed, expect them deleted, got them kept", want)
+		}
+	}
+}
+
+func TestInvalidator(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		bus := NewInvalidationBus()
+		deleted := make(chan Key, 10)
+		cache1 := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, Invalidator: bus.Invalidator()})
+		cache2 := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, Invalidator: bus.Invalidator(), CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+			if reason == ReasonDeleted {
+				deleted <- key
+			}
+		}})
+		for _, cache := range []Interface{cache1, cache2} {
+			for i := 1; i <= 5; i++ {
+				cache.PutTagged(i, i, "tag")
+			}
+		}
+
+		cache1.Del(1)
+		cache1.DelMulti([]Key{2, 3})
+		waitDeleted(t, deleted, 1, 2, 3)
+		cache1.InvalidateTag("tag")
+		waitDeleted(t, deleted, 4, 5)
+
+		// the keys deleted by cache2 are not sent back to cache2
+		cache2.Put(6, 6)
+		cache2.Del(6)
+		waitDeleted(t, deleted, 6)
+		cache2.Put(6, 6)
+		if v, _ := cache2.Get(6); v != 6 {
+			t.Fatalf("test %d shards key %v failed, expect %v, got %v", shards, 6, 6, v)
+		}
+		cache1.Close()
+		cache2.Close()
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONCodec converts the keys and values of the cache to and from JSON
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	UnmarshalKey(data []byte) (Key, error)
+	UnmarshalValue(data []byte) (Value, error)
+}
+
+// StdJSONCodec is the default JSONCodec, backed by encoding/json. It
+// decodes the keys and values like json.Unmarshal into an interface{},
+// so numbers come back as float64 and structs as maps, a custom codec
+// is needed to restore other types
+type StdJSONCodec struct{}
+
+// Marshal will encode v with json.Marshal
+func (StdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// UnmarshalKey will decode the key into an interface{}
+func (StdJSONCodec) UnmarshalKey(data []byte) (Key, error) {
+	var key interface{}
+	err := json.Unmarshal(data, &key)
+	return key, err
+}
+
+// UnmarshalValue will decode the value into an interface{}
+func (StdJSONCodec) UnmarshalValue(data []byte) (Value, error) {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// jsonEntry is an entry of the JSON export, Deadline is omitted for the
+// entries that never expire
+type jsonEntry struct {
+	Key      json.RawMessage `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	Deadline *time.Time      `json:"deadline,omitempty"`
+}
+
+// ExportJSON writes the live entries to w as a JSON array, from the least
+// to the most recently used one, for debugging or to import them with
+// ImportJSON. The keys and values are encoded with codec, StdJSONCodec if
+// it is nil
+func (lru *lruCache) ExportJSON(w io.Writer, codec JSONCodec) error {
+	return exportJSON(w, codec, lru.snapshotRecords())
+}
+
+// ImportJSON puts the entries read from r, as written by ExportJSON,
+// skipping the expired ones. The keys and values are decoded with codec,
+// StdJSONCodec if it is nil
+func (lru *lruCache) ImportJSON(r io.Reader, codec JSONCodec) error {
+	if lru.closed() {
+		return ErrClosed
+	}
+	return importJSON(r, codec, func(rec walRecord) {
+		lru.Lock()
+		defer lru.Unlock()
+		lru.replay(rec)
+	})
+}
+
+func exportJSON(w io.Writer, codec JSONCodec, recs []walRecord) error {
+	if codec == nil {
+		codec = StdJSONCodec{}
+	}
+	entries := make([]jsonEntry, 0, len(recs))
+	for _, rec := range recs {
+		key, err := codec.Marshal(rec.Key)
+		if err != nil {
+			return err
+		}
+		value, err := codec.Marshal(rec.Value)
+		if err != nil {
+			return err
+		}
+		entry := jsonEntry{Key: key, Value: value}
+		if !rec.Deadline.IsZero() {
+			deadline := rec.Deadline
+			entry.Deadline = &deadline
+		}
+		entries = append(entries, entry)
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// importJSON decodes all the entries before applying any of them, so that
+// malformed input leaves the cache untouched
+func importJSON(r io.Reader, codec JSONCodec, apply func(rec walRecord)) error {
+	if codec == nil {
+		codec = StdJSONCodec{}
+	}
+	var entries []jsonEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+	recs := make([]walRecord, 0, len(entries))
+	for _, entry := range entries {
+		key, err := codec.UnmarshalKey(entry.Key)
+		if err != nil {
+			return err
+		}
+		value, err := codec.UnmarshalValue(entry.Value)
+		if err != nil {
+			return err
+		}
+		rec := walRecord{Op: walPut, Key: key, Value: value}
+		if entry.Deadline != nil {
+			rec.Deadline = *entry.Deadline
+		}
+		recs = append(recs, rec)
+	}
+	for _, rec := range recs {
+		apply(rec)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheExportJSON(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 4, Shards: 2})
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", map[string]interface{}{"field": "testvalue2"}, NoExpiration)
+	cache.Put("testkey3", 3)
+
+	var buf bytes.Buffer
+	if err := cache.(Persister).ExportJSON(&buf, nil); err != nil {
+		t.Fatalf("test export failed, got %v", err)
+	}
+	exported := buf.String()
+
+	restored := NewCacheWithConfig(Config{MaxLen: 4})
+	if err := restored.(Persister).ImportJSON(&buf, nil); err != nil {
+		t.Fatalf("test import failed, got %v, exported %s", err, exported)
+	}
+	if v, _ := restored.Get("testkey1"); v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if v, _ := restored.Get("testkey2"); v.(map[string]interface{})["field"] != "testvalue2" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", "testvalue2", v)
+	}
+	if d, _ := restored.TTL("testkey2"); d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey2", NoExpiration, d)
+	}
+	// numbers come back as float64 with StdJSONCodec
+	if v, _ := restored.Get("testkey3"); v != float64(3) {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey3", 3, v)
+	}
+
+	if err := restored.(Persister).ImportJSON(bytes.NewBufferString(`[{"key": "testkey4"`), nil); err == nil {
+		t.Fatalf("test malformed import failed, expect an error, got %v", err)
+	}
+	if restored.Len() != 3 {
+		t.Fatalf("test len failed, expect %v, got %v", 3, restored.Len())
+	}
+}
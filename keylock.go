@@ -0,0 +1,50 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "sync"
+
+// keyStripes is the number of mutexes of StripedLocks, a power of two
+const keyStripes = 256
+
+// StripedLocks serializes the callers by key with a fixed set of mutexes,
+// the keys hashed to the same mutex share it. The zero value is ready to
+// use
+type StripedLocks struct {
+	stripes [keyStripes]sync.Mutex
+}
+
+// LockKey locks the mutex of the key and returns the function unlocking
+// it. It is not reentrant, locking a key again before unlocking it, or
+// another key of the same stripe, deadlocks
+func (l *StripedLocks) LockKey(key Key) func() {
+	// the top bits, the shards are picked by the bottom ones
+	mu := &l.stripes[hashKey(key)>>56]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// LockKey lets the callers serialize the recomputation of a key without
+// blocking the cache, the puts and gets of the key are not locked. The
+// lock is local to the process
+func (lru *lruCache) LockKey(key Key) func() {
+	return lru.keyLocks.LockKey(key)
+}
+
+func (s *shardedCache) LockKey(key Key) func() {
+	return s.shard(key).LockKey(key)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestLockKey(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock := cache.LockKey("testkey1")
+				defer unlock()
+				// without the lock the increments would be lost
+				v, _ := cache.Get("testkey1")
+				n, _ := v.(int)
+				time.Sleep(time.Millisecond)
+				cache.Put("testkey1", n+1)
+			}()
+		}
+		wg.Wait()
+		if v, _ := cache.Get("testkey1"); v != 8 {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", 8, v)
+		}
+
+		unlock := cache.LockKey("testkey1")
+		done := make(chan struct{})
+		go func() {
+			cache.LockKey("testkey2")()
+			cache.Put("testkey1", 9)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("test %d shards key %s failed, expect it not to be locked", shards, "testkey2")
+		}
+		unlock()
+		cache.Close()
+	}
+}
+
+func TestStripedLocks(t *testing.T) {
+	var locks StripedLocks
+	unlock := locks.LockKey(1)
+	locked := make(chan struct{})
+	go func() {
+		defer locks.LockKey(1)()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatalf("test key %v failed, expect it locked", 1)
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// lfuPolicy keeps the elements in a min-heap by use count, ties are
+// broken by the last use, so the victim is found in O(1) and every hit
+// costs O(log n)
+type lfuPolicy struct {
+	items lfuHeap
+	index map[*list.Element]*lfuItem
+	tick  uint64
+}
+
+type lfuItem struct {
+	elem  *list.Element
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{index: map[*list.Element]*lfuItem{}}
+}
+
+func (p *lfuPolicy) adding(key Key) {}
+
+func (p *lfuPolicy) add(elem *list.Element) {
+	p.tick++
+	item := &lfuItem{elem: elem, freq: 1, tick: p.tick}
+	p.index[elem] = item
+	heap.Push(&p.items, item)
+}
+
+func (p *lfuPolicy) hit(elem *list.Element) {
+	item, ok := p.index[elem]
+	if !ok {
+		return
+	}
+	p.tick++
+	item.freq++
+	item.tick = p.tick
+	heap.Fix(&p.items, item.index)
+}
+
+func (p *lfuPolicy) remove(elem *list.Element, reason EvictionReason) {
+	item, ok := p.index[elem]
+	if !ok {
+		return
+	}
+	delete(p.index, elem)
+	heap.Remove(&p.items, item.index)
+}
+
+func (p *lfuPolicy) victim() *list.Element {
+	if len(p.items) == 0 {
+		return nil
+	}
+	return p.items[0].elem
+}
+
+func (p *lfuPolicy) reset() {
+	p.items = nil
+	p.index = map[*list.Element]*lfuItem{}
+}
+
+// lfuHeap implements heap.Interface
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// LoadFunc loads the value of a key missing from the cache
+type LoadFunc func(key Key) (Value, error)
+
+// LoadCtxFunc is a LoadFunc for GetOrLoadCtx, ctx is the context of the
+// caller
+type LoadCtxFunc func(ctx context.Context, key Key) (Value, error)
+
+type loadCall struct {
+	done  chan struct{}
+	value Value
+	err   error
+}
+
+// LoadGroup makes the concurrent loads of the same key share one call, a
+// panic of the loader is returned to all the callers as a LoaderPanic.
+// The zero LoadGroup is ready to use
+type LoadGroup struct {
+	calls map[Key]*loadCall
+	sync.Mutex
+}
+
+// Do runs fn unless a call for the key is in flight, then it waits for
+// that call instead, until ctx is done
+func (g *LoadGroup) Do(ctx context.Context, key Key, fn func() (Value, error)) (Value, error) {
+	g.Lock()
+	if g.calls == nil {
+		g.calls = map[Key]*loadCall{}
+	}
+	if call, exists := g.calls[key]; exists {
+		g.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.Unlock()
+
+	g.run(key, call, fn)
+	return call.value, call.err
+}
+
+// start runs fn in a goroutine unless a call for the key is in flight,
+// the calls of Do for the key meanwhile wait for it. A panic of fn is
+// passed to onPanic
+func (g *LoadGroup) start(key Key, fn func() (Value, error), onPanic func(err error)) {
+	g.Lock()
+	if g.calls == nil {
+		g.calls = map[Key]*loadCall{}
+	}
+	if _, exists := g.calls[key]; exists {
+		g.Unlock()
+		return
+	}
+	call := &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.Unlock()
+
+	go func() {
+		g.run(key, call, fn)
+		var p *LoaderPanic
+		if errors.As(call.err, &p) && onPanic != nil {
+			onPanic(p)
+		}
+	}()
+}
+
+// run recovers the panics of fn, they are returned to the waiting calls as
+// a LoaderPanic
+func (g *LoadGroup) run(key Key, call *loadCall, fn func() (Value, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.value, call.err = nil, &LoaderPanic{Value: r, Stack: debug.Stack()}
+		}
+		close(call.done)
+
+		g.Lock()
+		delete(g.calls, key)
+		g.Unlock()
+	}()
+	call.value, call.err = fn()
+}
+
+// GetOrLoad returns the cached value of the key, or loads it with load and
+// caches it for the default lifetime. Concurrent calls for the same
+// missing key wait for a single load and share its result. Errors are
+// returned to all the waiting callers and are not cached, except
+// ErrNotFound which caches NotFound. A panic of load is returned as a
+// LoaderPanic. A cached NotFound is returned as ErrNotFound without
+// calling load. With StaleWhileRevalidate a value
+// expired within the window is returned at once and reloaded in the
+// background
+func (lru *lruCache) GetOrLoad(key Key, load LoadFunc) (Value, error) {
+	return lru.getOrLoad(context.Background(), "GetOrLoad", key, func(ctx context.Context, key Key) (Value, error) {
+		return load(key)
+	})
+}
+
+// GetOrLoadCtx is GetOrLoad with a context, which is passed to load. A
+// caller waiting for the load of another one stops waiting when its ctx
+// is done, and the reloads of StaleWhileRevalidate run with the values
+// of ctx but without its deadline
+func (lru *lruCache) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return lru.getOrLoad(ctx, "GetOrLoadCtx", key, load)
+}
+
+func (lru *lruCache) getOrLoad(ctx context.Context, op string, key Key, load LoadCtxFunc) (Value, error) {
+	refresh := func(key Key) (Value, error) {
+		return load(withoutCancel{ctx}, key)
+	}
+	if value, ok := lru.getOrRefresh(key, refresh); ok {
+		lru.audit(op, key, true)
+		if value == NotFound {
+			return nil, ErrNotFound
+		}
+		return value, nil
+	}
+	value, err := lru.loads.Do(ctx, key, func() (Value, error) {
+		value, err := load(ctx, key)
+		lru.putLoaded(key, value, 0, err)
+		return value, err
+	})
+	lru.audit(op, key, false)
+	return value, err
+}
+
+// withoutCancel keeps the values of a context without its deadline and
+// cancellation, for the work that outlives the caller
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (withoutCancel) Done() <-chan struct{}       { return nil }
+func (withoutCancel) Err() error                  { return nil }
+
+// getOrRefresh is get, unless StaleWhileRevalidate is set: then a value
+// expired within the window is returned as well, and reloaded by refresh
+func (lru *lruCache) getOrRefresh(key Key, load LoadFunc) (Value, bool) {
+	if lru.staleWhileRevalidate <= 0 || lru.bypassed() {
+		return lru.get(key)
+	}
+	lru.Lock()
+	value, stale, ok := lru.getAllowStale(key, lru.staleWhileRevalidate)
+	lru.Unlock()
+	if stale {
+		lru.refresh(key, load)
+	}
+	return value, ok
+}
+
+// refresh reloads the key in a background goroutine and puts it for the
+// default lifetime, unless the key is already being loaded. The errors
+// are passed to OnRefreshError
+func (lru *lruCache) refresh(key Key, load LoadFunc) {
+	lru.loads.start(key, func() (Value, error) {
+		value, err := load(key)
+		if !lru.putLoaded(key, value, 0, err) && lru.onRefreshError != nil {
+			lru.onRefreshError(key, err)
+		}
+		return value, err
+	}, lru.refreshPanicked(key))
+}
+
+// dueForRefresh tells whether RefreshAhead should reload the entry, the
+// lock must be held
+func (lru *lruCache) dueForRefresh(entry *listEntry, now time.Time) bool {
+	if lru.refreshLoader == nil || entry.deadTime.IsZero() || entry.lifetime <= 0 {
+		return false
+	}
+	return entry.deadTime.Sub(now) < time.Duration(lru.refreshAhead*float64(entry.lifetime))
+}
+
+// reloadAhead reloads the key with RefreshLoader in a background
+// goroutine, unless the key is already being loaded
+func (lru *lruCache) reloadAhead(key Key) {
+	lru.loads.start(key, func() (Value, error) {
+		value, t, err := lru.refreshLoader(key)
+		if t != 0 {
+			t = clampTimeout(t)
+		}
+		if !lru.putLoaded(key, value, t, err) && lru.onRefreshError != nil {
+			lru.onRefreshError(key, err)
+		}
+		return value, err
+	}, lru.refreshPanicked(key))
+}
+
+// refreshPanicked passes the panic of a background reload of the key to
+// OnRefreshError
+func (lru *lruCache) refreshPanicked(key Key) func(err error) {
+	return func(err error) {
+		if lru.onRefreshError != nil {
+			lru.onRefreshError(key, err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheGetOrLoad(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	var loads int32
+	loader := func(key Key) (Value, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "testvalue1", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cache.GetOrLoad("testkey1", loader)
+			if err != nil || val != "testvalue1" {
+				t.Errorf("test key %s value failed, expect %v, got %v/%v", "testkey1", "testvalue1", val, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads != 1 {
+		t.Fatalf("test loads failed, expect %v, got %v", 1, loads)
+	}
+
+	if val, err := cache.GetOrLoad("testkey1", loader); err != nil || val != "testvalue1" || loads != 1 {
+		t.Fatalf("test cached key %s failed, expect %v with 1 load, got %v/%v with %v loads", "testkey1", "testvalue1", val, err, loads)
+	}
+
+	failed := errors.New("load failed")
+	if _, err := cache.GetOrLoad("testkey2", func(key Key) (Value, error) { return nil, failed }); err != failed {
+		t.Fatalf("test load error failed, expect %v, got %v", failed, err)
+	}
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey2", false, ok)
+	}
+}
+
+func TestCacheGetOrLoadStaleWhileRevalidate(t *testing.T) {
+	var refreshErrors int32
+	cache := NewCacheWithConfig(Config{MaxLen: 10, StaleWhileRevalidate: 50 * time.Millisecond, OnRefreshError: func(key Key, err error) {
+		atomic.AddInt32(&refreshErrors, 1)
+	}})
+	defer cache.Close()
+	cache.PutWithDeadline("testkey1", "testvalue1", time.Now().Add(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(key Key) (Value, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "testvalue2", nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := cache.GetOrLoad("testkey1", load); v != "testvalue1" || err != nil {
+			t.Fatalf("test stale key %s failed, expect %v, got %v %v", "testkey1", "testvalue1", v, err)
+		}
+	}
+	close(release)
+	for i := 0; i < 100 && !cache.Contains("testkey1"); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := cache.Get("testkey1"); v != "testvalue2" {
+		t.Fatalf("test refreshed key %s failed, expect %v, got %v", "testkey1", "testvalue2", v)
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("test key %s loads failed, expect %v, got %v", "testkey1", 1, n)
+	}
+
+	// beyond the window the value is loaded before it is returned
+	cache.PutWithDeadline("testkey2", "testvalue1", time.Now().Add(10*time.Millisecond))
+	time.Sleep(70 * time.Millisecond)
+	if v, err := cache.GetOrLoad("testkey2", load); v != "testvalue2" || err != nil {
+		t.Fatalf("test expired key %s failed, expect %v, got %v %v", "testkey2", "testvalue2", v, err)
+	}
+
+	// a failed reload keeps the stale value
+	cache.PutWithDeadline("testkey3", "testvalue1", time.Now().Add(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	fail := func(key Key) (Value, error) { return nil, errors.New("load failed") }
+	if v, _ := cache.GetOrLoad("testkey3", fail); v != "testvalue1" {
+		t.Fatalf("test stale key %s failed, expect %v, got %v", "testkey3", "testvalue1", v)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&refreshErrors) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := cache.GetOrLoad("testkey3", fail); v != "testvalue1" || atomic.LoadInt32(&refreshErrors) == 0 {
+		t.Fatalf("test stale key %s failed, expect %v, got %v", "testkey3", "testvalue1", v)
+	}
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	for _, readBuffer := range []int{0, 8} {
+		var loads int32
+		cache := NewCacheWithConfig(Config{MaxLen: 10, ReadBuffer: readBuffer, RefreshAhead: 0.5, RefreshLoader: func(key Key) (Value, time.Duration, error) {
+			atomic.AddInt32(&loads, 1)
+			return "testvalue2", NoExpiration, nil
+		}})
+		cache.PutWithDeadline("testkey1", "testvalue1", time.Now().Add(100*time.Millisecond))
+		cache.PutWithTimeout("testkey2", "testvalue1", NoExpiration)
+
+		if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+			t.Fatalf("test read buffer %d key %s failed, expect %v, got %v", readBuffer, "testkey1", "testvalue1", v)
+		}
+		time.Sleep(60 * time.Millisecond)
+		cache.Get("testkey2")
+		if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+			t.Fatalf("test read buffer %d key %s failed, expect %v, got %v", readBuffer, "testkey1", "testvalue1", v)
+		}
+		for i := 0; i < 100; i++ {
+			if left, _ := cache.TTL("testkey1"); left == NoExpiration {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if v, _ := cache.Get("testkey1"); v != "testvalue2" {
+			t.Fatalf("test read buffer %d refreshed key %s failed, expect %v, got %v", readBuffer, "testkey1", "testvalue2", v)
+		}
+		if n := atomic.LoadInt32(&loads); n != 1 {
+			t.Fatalf("test read buffer %d loads failed, expect %v, got %v", readBuffer, 1, n)
+		}
+		cache.Close()
+	}
+}
+
+func TestCacheGetOrLoadPanic(t *testing.T) {
+	refreshErrs := make(chan error, 1)
+	cache := NewCacheWithConfig(Config{MaxLen: 10, RefreshAhead: 0.5, OnRefreshError: func(key Key, err error) {
+		refreshErrs <- err
+	}, RefreshLoader: func(key Key) (Value, time.Duration, error) {
+		panic("testpanic2")
+	}})
+	defer cache.Close()
+
+	var p *LoaderPanic
+	for i := 0; i < 2; i++ {
+		_, err := cache.GetOrLoad("testkey1", func(key Key) (Value, error) {
+			panic("testpanic1")
+		})
+		if !errors.As(err, &p) || p.Value != "testpanic1" {
+			t.Fatalf("test key %s panic failed, expect %v, got %v", "testkey1", "testpanic1", err)
+		}
+	}
+	// the key is not stuck once its loader panicked
+	if v, err := cache.GetOrLoad("testkey1", func(key Key) (Value, error) {
+		return "testvalue1", nil
+	}); err != nil || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v", "testkey1", "testvalue1", v, err)
+	}
+
+	// the panic of a background reload is passed to OnRefreshError
+	cache.PutWithDeadline("testkey2", "testvalue2", time.Now().Add(100*time.Millisecond))
+	time.Sleep(60 * time.Millisecond)
+	cache.Get("testkey2")
+	select {
+	case err := <-refreshErrs:
+		if !errors.As(err, &p) || p.Value != "testpanic2" {
+			t.Fatalf("test key %s refresh panic failed, expect %v, got %v", "testkey2", "testpanic2", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("test key %s refresh panic failed, expect %v, got none", "testkey2", "testpanic2")
+	}
+}
+
+type ctxKey struct{}
+
+func TestCacheGetOrLoadCtx(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 10})
+	defer cache.Close()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "testvalue1")
+	if err := cache.PutCtx(ctx, "testkey1", "testvalue1"); err != nil {
+		t.Fatalf("test put key %s failed, expect %v, got %v", "testkey1", nil, err)
+	}
+	if v, ok, err := cache.GetCtx(ctx, "testkey1"); !ok || err != nil || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v/%v", "testkey1", "testvalue1", v, ok, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := cache.PutCtx(cancelled, "testkey2", "testvalue2"); err != context.Canceled {
+		t.Fatalf("test cancelled put failed, expect %v, got %v", context.Canceled, err)
+	}
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey2", false, ok)
+	}
+	if _, _, err := cache.GetCtx(cancelled, "testkey1"); err != context.Canceled {
+		t.Fatalf("test cancelled get failed, expect %v, got %v", context.Canceled, err)
+	}
+	if _, err := cache.GetOrLoadCtx(cancelled, "testkey2", func(ctx context.Context, key Key) (Value, error) {
+		t.Fatalf("test cancelled load failed, load called")
+		return nil, nil
+	}); err != context.Canceled {
+		t.Fatalf("test cancelled load failed, expect %v, got %v", context.Canceled, err)
+	}
+
+	v, err := cache.GetOrLoadCtx(ctx, "testkey2", func(ctx context.Context, key Key) (Value, error) {
+		return ctx.Value(ctxKey{}), nil
+	})
+	if err != nil || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v", "testkey2", "testvalue1", v, err)
+	}
+
+	release := make(chan struct{})
+	loaded := make(chan Value)
+	go func() {
+		v, _ := cache.GetOrLoadCtx(ctx, "testkey3", func(ctx context.Context, key Key) (Value, error) {
+			<-release
+			return "testvalue3", nil
+		})
+		loaded <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := cache.GetOrLoadCtx(timeout, "testkey3", func(ctx context.Context, key Key) (Value, error) {
+		return "testvalue4", nil
+	}); err != context.DeadlineExceeded {
+		t.Fatalf("test waiting key %s failed, expect %v, got %v", "testkey3", context.DeadlineExceeded, err)
+	}
+	close(release)
+	if v := <-loaded; v != "testvalue3" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey3", "testvalue3", v)
+	}
+}
@@ -18,41 +18,461 @@ package cache
 
 import (
 	"container/list"
+	"errors"
+	"io"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	DefaultMaxLen    = 10000
 	DefaultCacheTime = time.Minute
+
+	// NoExpiration as a timeout keeps the entry until it is evicted or
+	// deleted
+	NoExpiration time.Duration = -1
 )
 
 type Key interface{}
 type Value interface{}
 
+// ErrClosed is returned by the methods which can not be applied to a
+// closed cache
+var ErrClosed = errors.New("cache: closed")
+
 // OnEvicted callback func will be called when the cached key expired
 type OnEvicted func(key Key, value Value)
 
+// EvictionReason tells why an entry left the cache
+type EvictionReason int
+
+const (
+	// ReasonExpired entries have passed their deadline
+	ReasonExpired EvictionReason = iota + 1
+	// ReasonCapacity entries were evicted to make room for new ones
+	ReasonCapacity
+	// ReasonDeleted entries were removed explicitly
+	ReasonDeleted
+	// ReasonReplaced entries had their value overwritten by a Put
+	ReasonReplaced
+	// ReasonCorrupted entries held a value that could not be restored
+	ReasonCorrupted
+	// ReasonRejected entries were not admitted into a full cache, because
+	// they were used less often than the entry they would have evicted
+	ReasonRejected
+	// ReasonPurged entries were removed by Purge
+	ReasonPurged
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonCorrupted:
+		return "corrupted"
+	case ReasonRejected:
+		return "rejected"
+	case ReasonPurged:
+		return "purged"
+	}
+	return "unknown"
+}
+
+// OnEvictedWithReason callback func will be called when an entry leaves
+// the cache, or its value is replaced, along with the reason
+type OnEvictedWithReason func(key Key, value Value, reason EvictionReason)
+
+// OnEvictedWithError is an OnEvictedWithReason that can fail, e.g. to
+// release a resource held by the value
+type OnEvictedWithError func(key Key, value Value, reason EvictionReason) error
+
+// Auditor callback func will be called after every keyed operation,
+// op is the name of the called method, hit reports whether the key was
+// found in the cache (for Put: whether an existing entry was replaced)
+type Auditor func(op string, key Key, hit bool)
+
+// OnCorruption callback func will be called when a cached value can not
+// be restored, e.g. it fails to decompress
+type OnCorruption func(key Key, err error)
+
 type lruCache struct {
+	// the counters are first to keep them 64-bit aligned for atomic access
+	contentions uint64
+	readHits    uint64
+	readMisses  uint64
+	// reads queues the hits served under the read lock
+	reads chan *list.Element
+
 	maxLen    int
-	onEvicted OnEvicted
-	lst       *list.List
-	hash      map[Key]*list.Element
-	cacheTime time.Duration
-	sync.Mutex
+	onEvicted OnEvictedWithReason
+	// onReplaced is set when the callbacks want to know about replaced
+	// values, which costs restoring the old value on every overwrite
+	onReplaced bool
+	lst        *list.List
+	hash       keyIndex
+	cacheTime  time.Duration
+	hitTTL     time.Duration
+
+	compressThreshold int
+	compressor        Compressor
+
+	auditor      Auditor
+	onCorruption OnCorruption
+	listeners    []Listener
+	equal        func(a, b Value) bool
+	// onCallbackError receives the panics of the callbacks and listeners
+	onCallbackError func(key Key, err error)
+	// closeErrs are the failures of the callbacks once closing
+	closeErrs     []error
+	closeErrsLock sync.Mutex
+
+	debouncer *debouncer
+	sweeper   *sweeper
+	bypass    int32
+	closing   int32
+	evictions *rateCounter
+	stats     Stats
+	loads     LoadGroup
+
+	wal        io.Writer
+	onWALError func(err error)
+
+	throttled                *throttledCallback
+	closeCallbackUnthrottled bool
+
+	cardinality           *hyperLogLog
+	cardinalityThreshold  uint64
+	onCardinalityExceeded func(estimate uint64)
+	cardinalityExceeded   bool
+
+	weigher   Weigher
+	maxWeight int64
+	weight    int64
+
+	// tags indexes the keys of the tagged entries by tag
+	tags map[string]map[Key]struct{}
+
+	snapshot     *snapshotFile
+	persistStats bool
+	policy       evictionPolicy
+	admission    *frequencySketch
+
+	invalidator        Invalidator
+	onInvalidatorError func(err error)
+	stopInvalidations  func()
+
+	keyLocks StripedLocks
+	clock    Clock
+	// config is the one the cache was created with, for Clone
+	config Config
+
+	staleWhileRevalidate time.Duration
+	onRefreshError       func(key Key, err error)
+	refreshAhead         float64
+	refreshLoader        Loader
+	negativeCacheTime    time.Duration
+
+	// backing is the Store written through to
+	backing      Store
+	onStoreError func(key Key, err error)
+	writeBehind  *writeBehind
+
+	// insertionOrder keeps the list in insertion order instead of recency
+	insertionOrder  bool
+	evictionSamples int
+	slidingTTL      bool
+	ttlJitter       float64
+	sync.RWMutex
 }
 
 type listEntry struct {
 	key      Key
 	value    Value
 	deadTime time.Time
+	// created is when the value was put
+	created time.Time
+
+	compressed bool
+	weight     int64
+	// lifetime is the timeout the deadline was set with, SlidingTTL
+	// renews it on every hit
+	lifetime time.Duration
+	// referenced is the reference bit of PolicyCLOCK
+	referenced  bool
+	accessCount uint64
+	// lastAccess is when the entry was last read, zero if never
+	lastAccess time.Time
+	// probation entries get their deadline extended to hitTTL on first hit
+	probation bool
+	tags      []string
 }
 
 // Config of the cache
 type Config struct {
-	MaxLen    int
-	Callback  OnEvicted
+	// MaxLen bounds the number of resident entries, the oldest entries are
+	// evicted before a new one is inserted, so at no instant more than
+	// MaxLen entries are held. It must be positive unless Weigher and
+	// MaxWeight bound the cache, NewCacheWithConfig does not check it and
+	// a MaxLen of 0 evicts on every insert
+	MaxLen   int
+	Callback OnEvicted
+	// CacheTime is the default lifetime of the entries, DefaultCacheTime
+	// if below a millisecond
 	CacheTime time.Duration
+
+	// CallbackWithReason is called like Callback, with the reason the entry
+	// left the cache. Unlike Callback it is also called with the old value
+	// when a Put replaces the value of an existing key
+	CallbackWithReason OnEvictedWithReason
+	// CallbackWithError is called like CallbackWithReason, its errors are
+	// passed to OnCallbackError, and the ones of the callbacks Close waits
+	// for are returned by Close
+	CallbackWithError OnEvictedWithError
+
+	// Shards splits the cache into that many independent caches, keys are
+	// assigned by hash so that concurrent operations on different keys do
+	// not contend on one lock. MaxLen, MaxWeight, CallbackRateLimit and
+	// CardinalityThreshold are divided among the shards. There are no more
+	// shards than MaxLen
+	Shards int
+	// ShardRebalance moves MaxLen between the shards every interval, so
+	// that a shard hit by more keys than its share does not keep evicting
+	// while the others have room to spare. At each round the shards that
+	// evicted nothing since the last one lend half of their free capacity
+	// to the ones that did, in proportion of their evictions, the total
+	// stays MaxLen. A round locks each shard in turn, twice, and a shard
+	// lending more than it has left evicts. It has no effect on the caches
+	// bounded by weight only
+	ShardRebalance time.Duration
+
+	// CompressThreshold enables compression of []byte values longer than
+	// the threshold, values of other types are always stored as they are.
+	// Compressed values trade CPU on every Put and Get for memory, so it
+	// only pays off for large and compressible data
+	CompressThreshold int
+	// Compressor used for the values above CompressThreshold,
+	// FlateCompressor is used if it is nil
+	Compressor Compressor
+
+	// Auditor will be called for each Get/Put/Del operation. It is called
+	// after the cache lock is released, so it may block or use the cache
+	// without deadlocking, but it runs synchronously and adds its own
+	// latency to every call
+	Auditor Auditor
+
+	// Listeners will be called in order for every insert, update, delete,
+	// expiration and eviction of an entry, so that several concerns like
+	// logging and metrics can each watch the cache. Replaced values and
+	// the new entries rejected by TinyLFU are reported to Callback only.
+	// Like Callback they are called with the cache lock held, and the
+	// listeners of a sharded cache are called concurrently by the shards
+	Listeners []Listener
+
+	// OnCallbackError will be called with a *CallbackPanic when Callback,
+	// CallbackWithReason or a listener panics, and with the errors of
+	// CallbackWithError. The panic is recovered
+	// either way, so that a buggy callback can not crash the process nor
+	// leave the cache locked, and the operation that fired it completes
+	OnCallbackError func(key Key, err error)
+
+	// OnCorruption will be called when Get fails to restore a cached value.
+	// The corrupted entry is treated as a miss, it is removed from the cache
+	// and the callbacks are fired for it with a nil value and
+	// ReasonCorrupted. Like Callback it is called with the cache lock held
+	OnCorruption OnCorruption
+
+	// InsertTTL overrides CacheTime as the lifetime of the entries added by
+	// Put, and HitTTL is the lifetime an entry earns on its first hit
+	// after being put. Together they let one-hit-wonders expire quickly
+	// while entries that are actually reused are kept longer. The
+	// extension is applied only once per Put, later hits do not extend
+	// the deadline again, and entries put with an explicit timeout are
+	// never extended. Touch sets the deadline explicitly and so ends the
+	// probation as well
+	InsertTTL time.Duration
+	HitTTL    time.Duration
+
+	// SweepInterval starts a background goroutine that removes the expired
+	// entries every interval and fires Callback for them, instead of
+	// leaving them until they are read or evicted. Each sweep walks the
+	// whole cache with the lock held. Close stops the goroutine
+	SweepInterval time.Duration
+
+	// SnapshotPath is a file the cache is loaded from when it is created,
+	// skipping the expired entries, and saved to by Close, see SaveTo. A
+	// missing file is not an error, the others are passed to
+	// OnSnapshotError
+	SnapshotPath    string
+	OnSnapshotError func(err error)
+	// PersistStats makes SaveTo write the Stats counters as well, and
+	// ReplayWAL add the counters it reads to the current ones, so that
+	// they survive the restarts with SnapshotPath. The restored counters
+	// count the reads before the restart, the rates computed from them
+	// right after a restart are skewed accordingly. Len and Contentions
+	// are not persisted
+	PersistStats bool
+
+	// Store is written through to, the puts and deletes are applied to it
+	// before the cache, even in bypass mode, and a Get, GetString or
+	// GetInt that misses loads the key from it and caches it for the
+	// default lifetime. A value that can not be saved is not cached, the
+	// errors are passed to OnStoreError. GetOrStore, GetOrLoad and Warmup
+	// only fill the cache
+	Store        Store
+	OnStoreError func(key Key, err error)
+
+	// WriteBehind queues the writes to Store and applies them in order in
+	// a background goroutine, instead of before the cache. A value is
+	// cached even if it later fails to be saved. When WriteBehindBuffer
+	// (DefaultWriteBehindBuffer if not set) writes are pending, puts and
+	// deletes wait for room. Flush waits for the pending writes, Close
+	// applies them all before it returns
+	WriteBehind       bool
+	WriteBehindBuffer int
+
+	// PutDebounce coalesces the puts of the same key, a put is only applied
+	// once no other put for the key arrives within the window, and only the
+	// last value wins, and only it is saved to the Store. Every write
+	// becomes visible at least PutDebounce late, so it suits write-heavy
+	// keys whose readers tolerate lag. Close applies the puts pending
+	PutDebounce time.Duration
+
+	// WAL receives a record of every Put and Del, so that the cache can be
+	// rebuilt with ReplayWAL after a crash. The records are written with
+	// the cache lock held, a slow writer slows down all writes
+	WAL io.Writer
+	// OnWALError will be called when a record can not be written to WAL
+	OnWALError func(err error)
+
+	// CallbackRateLimit throttles Callback to the given calls per second.
+	// The callbacks are queued and run by a background goroutine, outside
+	// of the cache lock. When CallbackBuffer (DefaultCallbackBuffer if not
+	// set) callbacks are pending, evictions wait for room while holding
+	// the cache lock, which stalls the whole cache until the queue drains.
+	// Close runs all pending callbacks before it returns, at the limited
+	// rate unless CloseCallbackUnthrottled is set
+	CallbackRateLimit        float64
+	CallbackBuffer           int
+	CloseCallbackUnthrottled bool
+
+	// AsyncCallback runs Callback and CallbackWithReason in a background
+	// goroutine, outside of the cache lock, like CallbackRateLimit without
+	// the limit. A slow callback no longer holds the whole cache, and a
+	// callback may use the cache, as long as the queue of CallbackBuffer
+	// does not fill up: then the eviction waits for room with the lock
+	// held, and a callback waiting for the lock deadlocks it. The
+	// callbacks run after the operation that fired them has returned, in
+	// the order they were fired. Listeners are not affected
+	AsyncCallback bool
+
+	// TrackCardinality estimates the number of distinct keys ever put into
+	// the cache, including the ones evicted since, with a HyperLogLog of
+	// 4KB. The estimate is typically within 2% of the real count.
+	// OnCardinalityExceeded is called once, with the cache lock held, when
+	// the estimate first exceeds CardinalityThreshold
+	TrackCardinality      bool
+	CardinalityThreshold  uint64
+	OnCardinalityExceeded func(estimate uint64)
+
+	// MaxWeight bounds the total weight of the resident entries, as
+	// returned by Weigher, the oldest entries are evicted until the cache
+	// fits again. An entry heavier than MaxWeight on its own is evicted
+	// right after it is put. MaxLen still applies if set, otherwise the
+	// number of entries is unbounded
+	Weigher   Weigher
+	MaxWeight int64
+
+	// MaxBytes bounds the approximate memory of the resident entries, it
+	// is a MaxWeight with MemoryWeigher as the Weigher unless one is set.
+	// Like for any Weigher, the compressed values are weighed as stored
+	MaxBytes int64
+
+	// Policy selects the entries to evict when the cache is full,
+	// PolicyLRU by default
+	Policy Policy
+	// EvictionSamples is the sample size of PolicySample,
+	// DefaultEvictionSamples if not set
+	EvictionSamples int
+
+	// SlidingTTL renews the deadline of an entry on every hit, with the
+	// timeout it was put or touched with, so that entries expire once
+	// they are idle for that long. The renewed deadlines are not written
+	// to WAL
+	SlidingTTL bool
+
+	// TTLJitter randomizes the timeout of each put by up to that fraction
+	// of it, earlier or later, e.g. 0.1 turns a minute into 54 to 66
+	// seconds, so that the entries put together do not all expire at once.
+	// PutWithDeadline is not affected
+	TTLJitter float64
+
+	// TinyLFU filters the new entries of a full cache by their frequency,
+	// estimated over the recent puts and hits with a sketch of 2 to 4
+	// bytes per entry. A new entry is only admitted if it was used more often
+	// than the one it would evict, otherwise it is dropped and the
+	// callbacks are fired for it with ReasonRejected. Rarely used keys do
+	// not push out the popular ones, but a new key is cached only once it
+	// has been put often enough
+	TinyLFU bool
+
+	// ReadBuffer lets Get, GetString and GetInt run concurrently under a
+	// read lock. The recency updates of their hits are queued, up to
+	// ReadBuffer of them, and applied in a batch by the next operation
+	// that takes the lock exclusively. When the queue is full and the lock
+	// busy the update is dropped, so the eviction order is approximate.
+	// The reads that change the entry, of expired entries, of entries on
+	// probation or with SlidingTTL, still take the lock exclusively
+	ReadBuffer int
+
+	// Equal compares the values for CompareAndSwap, DefaultEqual if nil
+	Equal func(a, b Value) bool
+
+	// Invalidator publishes the keys deleted by Del, DelE, DelMulti,
+	// DelPrefix, DelFunc and InvalidateTag, and the keys published by the
+	// other processes are deleted from the cache, with ReasonDeleted but
+	// without deleting them from Store. Only the keys found in the cache
+	// are published by DelPrefix, DelFunc and InvalidateTag. Purge, the
+	// evictions and the puts are not published. The errors are passed to
+	// OnInvalidatorError. Close stops the subscription
+	Invalidator        Invalidator
+	OnInvalidatorError func(err error)
+
+	// StaleWhileRevalidate lets GetOrLoad return a value that expired no
+	// more than that long ago at once, and reload it in a background
+	// goroutine with the LoadFunc it was given, one reload per key at a
+	// time. The sweeper keeps the expired entries until the window ends,
+	// but the other reads, like Get, still remove them. The errors of the
+	// reloads are passed to OnRefreshError, the stale value is returned
+	// until a reload succeeds or the window ends
+	StaleWhileRevalidate time.Duration
+	OnRefreshError       func(key Key, err error)
+
+	// RefreshAhead reloads an entry with RefreshLoader in a background
+	// goroutine when it is read with less than that fraction of its
+	// lifetime left, e.g. 0.2 reloads an entry put for a minute when it is
+	// read in its last 12 seconds, so that the keys in use never expire.
+	// One reload per key runs at a time, the errors are passed to
+	// OnRefreshError. The entries without a deadline are never reloaded
+	RefreshAhead  float64
+	RefreshLoader Loader
+
+	// NegativeCacheTime is the lifetime of the NotFound entries, put by
+	// PutNotFound or by the loads failing with ErrNotFound, of at least a
+	// second. The default lifetime is used if it is not set
+	NegativeCacheTime time.Duration
+
+	// Clock tells the time of the deadlines, the system clock if nil. The
+	// sweeps of SweepInterval and the debounced puts still wait on the
+	// system clock, but the entries they find expired are judged by Clock
+	Clock Clock
 }
 
 // NewCache will create a default configured cache
@@ -62,94 +482,851 @@ func NewCache() Interface {
 
 // NewCacheWithConfig will create a cache with the configs
 func NewCacheWithConfig(config Config) Interface {
+	if config.MaxBytes > 0 {
+		config.MaxWeight, config.MaxBytes = config.MaxBytes, 0
+		if config.Weigher == nil {
+			config.Weigher = MemoryWeigher
+		}
+	}
+	// every shard holds at least one entry
+	if config.MaxLen > 0 && config.Shards > config.MaxLen {
+		config.Shards = config.MaxLen
+	}
+	if config.Shards > 1 {
+		return newShardedCache(config)
+	}
 	if config.CacheTime < time.Millisecond {
 		config.CacheTime = DefaultCacheTime
 	}
-	return &lruCache{
+	if config.InsertTTL > 0 {
+		config.CacheTime = config.InsertTTL
+	}
+	if config.Compressor == nil {
+		config.Compressor = FlateCompressor{}
+	}
+	if config.Weigher != nil && config.MaxWeight > 0 && config.MaxLen <= 0 {
+		config.MaxLen = unboundedLen
+	}
+	lru := &lruCache{
 		maxLen:    config.MaxLen,
-		onEvicted: config.Callback,
 		lst:       &list.List{},
-		hash:      map[Key]*list.Element{},
+		hash:      newKeyIndex(),
 		cacheTime: config.CacheTime,
+		hitTTL:    config.HitTTL,
+
+		onReplaced: config.CallbackWithReason != nil || config.CallbackWithError != nil,
+
+		compressThreshold: config.CompressThreshold,
+		compressor:        config.Compressor,
+
+		auditor:         config.Auditor,
+		onCorruption:    config.OnCorruption,
+		listeners:       config.Listeners,
+		onCallbackError: config.OnCallbackError,
+
+		evictions: newRateCounter(),
+
+		wal:        config.WAL,
+		onWALError: config.OnWALError,
+	}
+	lru.config = config
+	withReason := config.CallbackWithReason
+	if config.CallbackWithError != nil {
+		withReason = withErrors(withReason, config.CallbackWithError, lru.callbackFailed)
+	}
+	lru.onEvicted = safeCallback(evictionNotifier(config.Callback, withReason), lru.callbackFailed)
+	lru.evictionSamples = config.EvictionSamples
+	lru.equal = config.Equal
+	if lru.equal == nil {
+		lru.equal = DefaultEqual
+	}
+	if config.ReadBuffer > 0 {
+		lru.reads = make(chan *list.Element, config.ReadBuffer)
+	}
+	lru.slidingTTL = config.SlidingTTL
+	lru.staleWhileRevalidate = config.StaleWhileRevalidate
+	lru.onRefreshError = config.OnRefreshError
+	if config.NegativeCacheTime > 0 {
+		lru.negativeCacheTime = clampTimeout(config.NegativeCacheTime)
+	}
+	if config.RefreshAhead > 0 {
+		lru.refreshAhead = config.RefreshAhead
+		lru.refreshLoader = config.RefreshLoader
+	}
+	lru.ttlJitter = config.TTLJitter
+	lru.clock = config.Clock
+	if lru.clock == nil {
+		lru.clock = systemClock{}
+	}
+	lru.policy = newPolicy(config.Policy, lru)
+	lru.insertionOrder = config.Policy == PolicyFIFO || config.Policy == PolicyCLOCK
+	if config.TinyLFU {
+		size := config.MaxLen
+		if size == unboundedLen {
+			size = DefaultMaxLen
+		}
+		lru.admission = newFrequencySketch(size)
+	}
+	if config.Weigher != nil {
+		lru.weigher = config.Weigher
+		lru.maxWeight = config.MaxWeight
+	}
+	if config.TrackCardinality {
+		lru.cardinality = newHyperLogLog()
+		lru.cardinalityThreshold = config.CardinalityThreshold
+		lru.onCardinalityExceeded = config.OnCardinalityExceeded
+	}
+	if lru.onEvicted != nil && (config.CallbackRateLimit > 0 || config.AsyncCallback) {
+		if config.CallbackBuffer <= 0 {
+			config.CallbackBuffer = DefaultCallbackBuffer
+		}
+		lru.throttled = newThrottledCallback(lru.onEvicted, config.CallbackRateLimit, config.CallbackBuffer)
+		lru.onEvicted = lru.throttled.push
+		lru.closeCallbackUnthrottled = config.CloseCallbackUnthrottled
+	}
+	if config.SweepInterval > 0 {
+		lru.sweeper = newSweeper(config.SweepInterval, lru.sweep)
+	}
+	if config.PutDebounce > 0 {
+		lru.debouncer = newDebouncer(config.PutDebounce, func(key Key, value Value, t time.Duration) {
+			if lru.saveStore(key, value) {
+				lru.put(key, value, t)
+			}
+		})
+	}
+	lru.backing = config.Store
+	lru.onStoreError = config.OnStoreError
+	if lru.backing != nil && config.WriteBehind {
+		if config.WriteBehindBuffer <= 0 {
+			config.WriteBehindBuffer = DefaultWriteBehindBuffer
+		}
+		lru.writeBehind = newWriteBehind(lru, config.WriteBehindBuffer)
 	}
+	lru.persistStats = config.PersistStats
+	if lru.snapshot = newSnapshotFile(config); lru.snapshot != nil {
+		lru.snapshot.load(lru)
+	}
+	lru.invalidator = config.Invalidator
+	lru.onInvalidatorError = config.OnInvalidatorError
+	if lru.invalidator != nil {
+		lru.stopInvalidations = subscribe(lru.invalidator, lru.invalidate, lru.onInvalidatorError)
+	}
+	return lru
 }
 
-func (lru *lruCache) removeElem(elem *list.Element) {
+// evictionNotifier merges the configured callbacks into one, nil if none
+func evictionNotifier(callback OnEvicted, withReason OnEvictedWithReason) OnEvictedWithReason {
+	if callback == nil {
+		return withReason
+	}
+	return func(key Key, value Value, reason EvictionReason) {
+		if reason != ReasonReplaced {
+			callback(key, value)
+		}
+		if withReason != nil {
+			withReason(key, value, reason)
+		}
+	}
+}
+
+func (lru *lruCache) removeElem(elem *list.Element, reason EvictionReason) {
 	if elem == nil {
 		return
 	}
 	lru.lst.Remove(elem)
+	lru.policy.remove(elem, reason)
 
 	entry := elem.Value.(*listEntry)
-	delete(lru.hash, entry.key)
-	if lru.onEvicted != nil {
-		lru.onEvicted(entry.key, entry.value)
+	lru.hash.remove(entry.key)
+	lru.untag(entry)
+	lru.weight -= entry.weight
+	if lru.onEvicted != nil || len(lru.listeners) > 0 {
+		value, _ := lru.valueOf(entry)
+		if lru.onEvicted != nil {
+			lru.onEvicted(entry.key, value, reason)
+		}
+		lru.emit(Event{Type: eventOf(reason), Key: entry.key, Value: value, Reason: reason})
+	}
+	releaseEntry(entry)
+}
+
+// makeRoom evicts the oldest entries until a new one fits in, so that
+// inserting never grows the cache beyond maxLen even transiently
+func (lru *lruCache) makeRoom() {
+	for lru.lst.Len() > 0 && lru.hash.len() >= lru.maxLen {
+		lru.evictOldest()
 	}
 }
 
 func (lru *lruCache) lazyRemoveOldest() {
-	if len(lru.hash) > lru.maxLen {
-		lru.removeElem(lru.lst.Back())
+	if lru.hash.len() > lru.maxLen {
+		lru.evictOldest()
+	}
+}
+
+// evictOldest evicts the victim of the policy, the least recently used
+// entry by default
+func (lru *lruCache) evictOldest() {
+	lru.removeElem(lru.policy.victim(), ReasonCapacity)
+	lru.evictions.add(lru.now(), 1)
+	lru.stats.Evictions++
+}
+
+// expire removes an element that has passed its deadline
+func (lru *lruCache) expire(elem *list.Element) {
+	lru.removeElem(elem, ReasonExpired)
+	lru.stats.Expirations++
+}
+
+func (lru *lruCache) audit(op string, key Key, hit bool) {
+	if lru.auditor != nil {
+		lru.auditor(op, key, hit)
 	}
 }
 
 func (lru *lruCache) Put(key Key, value Value) {
-	lru.PutWithTimeout(key, value, lru.cacheTime)
+	replaced := lru.debouncedPut(key, value, 0)
+	lru.audit("Put", key, replaced)
 }
 
+// PutWithTimeout puts the key with its own timeout, of at least a second,
+// or NoExpiration to keep it until it is evicted or deleted
 func (lru *lruCache) PutWithTimeout(key Key, value Value, t time.Duration) {
+	t = clampTimeout(t)
+	replaced := lru.debouncedPut(key, value, t)
+	lru.audit("PutWithTimeout", key, replaced)
+}
+
+// PutWithDeadline puts the key until the given time, a zero time means
+// NoExpiration. A deadline already passed removes the current value of
+// the key. With PutDebounce the deadline is turned into a timeout, which
+// starts when the put is applied
+func (lru *lruCache) PutWithDeadline(key Key, value Value, deadline time.Time) {
+	var replaced bool
+	if lru.debouncer != nil {
+		t := NoExpiration
+		if !deadline.IsZero() {
+			t = clampTimeout(deadline.Sub(lru.now()))
+		}
+		replaced = lru.debouncedPut(key, value, t)
+	} else {
+		replaced = lru.putWithDeadline(key, value, deadline)
+	}
+	lru.audit("PutWithDeadline", key, replaced)
+}
+
+func (lru *lruCache) putWithDeadline(key Key, value Value, deadline time.Time) bool {
+	if !lru.saveStore(key, value) || lru.bypassed() {
+		return false
+	}
+	rec := walRecord{Op: walPut, Key: key, Value: value, Deadline: deadline}
+	lru.Lock()
+	defer lru.Unlock()
+	_, replaced := lru.hash.get(key)
+	lru.logWAL(rec)
+	lru.replay(rec)
+	return replaced
+}
+
+// cancelPuts drops the debounced puts of the keys still pending, so that
+// the keys deleted do not come back once the window ends
+func (lru *lruCache) cancelPuts(keys ...Key) {
+	if lru.debouncer != nil {
+		lru.debouncer.cancel(keys...)
+	}
+}
+
+// debouncedPut writes the Store only when the put is applied, so the
+// puts coalesced by PutDebounce reach the Store once
+func (lru *lruCache) debouncedPut(key Key, value Value, t time.Duration) bool {
+	if lru.debouncer != nil {
+		return lru.debouncer.put(key, value, t)
+	}
+	if !lru.saveStore(key, value) {
+		return false
+	}
+	return lru.put(key, value, t)
+}
+
+// put inserts or replaces the key, a zero t means the default lifetime
+func (lru *lruCache) put(key Key, value Value, t time.Duration) bool {
+	if lru.bypassed() {
+		return false
+	}
+	entry := lru.newEntry(key, value, t)
+	lru.Lock()
+	defer lru.Unlock()
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: value, Deadline: entry.deadTime})
+	return lru.store(entry)
+}
+
+// clampTimeout raises the timeouts below a second to one, NoExpiration
+// is kept as is
+func clampTimeout(t time.Duration) time.Duration {
+	if t == NoExpiration {
+		return t
+	}
 	if t < time.Second {
-		t = time.Second
+		return time.Second
+	}
+	return t
+}
+
+// deadlineAfter returns the deadline t after now, the zero time for
+// NoExpiration
+func deadlineAfter(now time.Time, t time.Duration) time.Time {
+	if t == NoExpiration {
+		return time.Time{}
 	}
+	return now.Add(t)
+}
+
+// jitter randomizes the timeout by up to ttlJitter of it either way
+func (lru *lruCache) jitter(t time.Duration) time.Duration {
+	if lru.ttlJitter <= 0 || t == NoExpiration {
+		return t
+	}
+	return t + time.Duration((rand.Float64()*2-1)*lru.ttlJitter*float64(t))
+}
+
+// expired tells whether the entry has passed its deadline, the entries
+// without one never expire
+func (e *listEntry) expired(now time.Time) bool {
+	return !e.deadTime.IsZero() && e.deadTime.Before(now)
+}
+
+// newEntry prepares an entry outside of the lock, a zero t means the
+// default lifetime
+func (lru *lruCache) newEntry(key Key, value Value, t time.Duration) *listEntry {
+	probation := false
+	if t == 0 {
+		t = lru.cacheTime
+		probation = lru.hitTTL > 0
+	}
+	t = clampTimeout(lru.jitter(t))
+	value, compressed := lru.compress(value)
+	weight := lru.weigh(key, value)
+	now := lru.now()
+	entry := acquireEntry()
+	*entry = listEntry{key: key, value: value, deadTime: deadlineAfter(now, t), created: now, lifetime: t, compressed: compressed, weight: weight, probation: probation}
+	return entry
+}
+
+// store inserts the entry or updates the existing one of the same key,
+// it reports whether an entry was replaced, the lock must be held
+func (lru *lruCache) store(entry *listEntry) bool {
+	lru.trackKey(entry.key)
+	if lru.admission != nil {
+		lru.admission.increment(entry.key)
+	}
+	if elem, exists := lru.hash.get(entry.key); exists {
+		if lru.onEvicted != nil && lru.onReplaced {
+			old, _ := lru.valueOf(elem.Value.(*listEntry))
+			lru.onEvicted(entry.key, old, ReasonReplaced)
+		}
+		lru.promote(elem)
+		elem.Value.(*listEntry).value = entry.value
+		elem.Value.(*listEntry).compressed = entry.compressed
+		elem.Value.(*listEntry).deadTime = entry.deadTime
+		elem.Value.(*listEntry).created = entry.created
+		elem.Value.(*listEntry).lifetime = entry.lifetime
+		elem.Value.(*listEntry).probation = entry.probation
+		lru.untag(elem.Value.(*listEntry))
+		elem.Value.(*listEntry).tags = entry.tags
+		lru.tag(elem.Value.(*listEntry))
+		lru.weight += entry.weight - elem.Value.(*listEntry).weight
+		elem.Value.(*listEntry).weight = entry.weight
+		lru.emitStored(EventUpdate, entry)
+		releaseEntry(entry)
+		lru.evictOverweight()
+		return true
+	}
+	if !lru.admit(entry) {
+		lru.reject(entry)
+		return false
+	}
+	lru.policy.adding(entry.key)
+	lru.makeRoom()
+	elem := lru.lst.PushFront(entry)
+	lru.hash.set(entry.key, elem)
+	lru.tag(entry)
+	lru.policy.add(elem)
+	lru.weight += entry.weight
+	lru.emitStored(EventInsert, entry)
+	lru.lazyRemoveOldest()
+	lru.evictOverweight()
+	return false
+}
+
+// GetOrStore returns the live value of the key with loaded true, or
+// stores def with the timeout t and returns it with loaded false. The
+// check and the store happen atomically, like sync.Map.LoadOrStore
+func (lru *lruCache) GetOrStore(key Key, def Value, t time.Duration) (Value, bool) {
+	actual, loaded := lru.getOrStore(key, def, t)
+	lru.audit("GetOrStore", key, loaded)
+	return actual, loaded
+}
+
+func (lru *lruCache) getOrStore(key Key, def Value, t time.Duration) (Value, bool) {
+	if lru.bypassed() {
+		return def, false
+	}
+	t = clampTimeout(t)
+	entry := lru.newEntry(key, def, t)
 	lru.Lock()
 	defer lru.Unlock()
-	if elem, exists := lru.hash[key]; exists {
-		lru.lst.MoveToFront(elem)
-		elem.Value.(*listEntry).value = value
-		elem.Value.(*listEntry).deadTime = time.Now().Add(t)
-	} else {
-		lru.hash[key] = lru.lst.PushFront(&listEntry{key: key, value: value, deadTime: time.Now().Add(t)})
-		lru.lazyRemoveOldest()
+	elem, _ := lru.hash.get(key)
+	if value, ok := lru.access(elem); ok {
+		releaseEntry(entry)
+		return value, true
 	}
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: def, Deadline: entry.deadTime})
+	lru.store(entry)
+	return def, false
 }
 
 func (lru *lruCache) Get(key Key) (Value, bool) {
+	value, ok := lru.get(key)
+	if !ok && lru.backing != nil {
+		value, ok = lru.loadStore(key)
+	}
+	lru.audit("Get", key, ok)
+	return value, ok
+}
+
+func (lru *lruCache) get(key Key) (Value, bool) {
+	if lru.bypassed() {
+		return nil, false
+	}
+	if lru.reads != nil {
+		lru.RLock()
+		elem, _ := lru.hash.get(key)
+		value, ok, done := lru.sharedAccess(elem)
+		lru.RUnlock()
+		if done {
+			if ok {
+				lru.recordRead(elem)
+			}
+			return value, ok
+		}
+	}
 	lru.Lock()
 	defer lru.Unlock()
-	if elem, exists := lru.hash[key]; exists {
-		entry := elem.Value.(*listEntry)
-		// delete the cached value if it has already timeouted
-		if entry.deadTime.Before(time.Now()) {
-			lru.removeElem(elem)
-			return nil, false
+	elem, _ := lru.hash.get(key)
+	return lru.access(elem)
+}
+
+// access returns the value of a cached element and marks it as the most
+// recently used one, a nil element is a miss. The lock must be held
+func (lru *lruCache) access(elem *list.Element) (Value, bool) {
+	if elem == nil {
+		lru.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*listEntry)
+	// delete the cached value if it has already timeouted
+	if entry.expired(lru.now()) {
+		lru.expire(elem)
+		lru.stats.Misses++
+		return nil, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		lru.corrupted(elem, err)
+		lru.stats.Misses++
+		return nil, false
+	}
+	lru.stats.Hits++
+	entry.accessCount++
+	entry.lastAccess = lru.now()
+	if lru.admission != nil {
+		lru.admission.increment(entry.key)
+	}
+	if entry.probation {
+		entry.probation = false
+		entry.lifetime = lru.hitTTL
+		entry.deadTime = lru.now().Add(lru.hitTTL)
+	} else if lru.slidingTTL {
+		entry.deadTime = deadlineAfter(lru.now(), entry.lifetime)
+	}
+	if lru.dueForRefresh(entry, lru.now()) {
+		lru.reloadAhead(entry.key)
+	}
+	lru.promote(elem)
+	return value, true
+}
+
+// corrupted drops an element whose value can not be restored, never hand
+// out garbage but treat it as if it had expired
+func (lru *lruCache) corrupted(elem *list.Element, err error) {
+	key := elem.Value.(*listEntry).key
+	lru.removeElem(elem, ReasonCorrupted)
+	if lru.onCorruption != nil {
+		lru.onCorruption(key, err)
+	}
+}
+
+// Peek is the same as Get, but it neither changes the recency of the entry
+// nor removes it if it has expired, and it is not counted in the stats
+func (lru *lruCache) Peek(key Key) (Value, bool) {
+	var value Value
+	var ok bool
+	if !lru.bypassed() {
+		lru.Lock()
+		value, ok = lru.peek(key)
+		lru.Unlock()
+	}
+	lru.audit("Peek", key, ok)
+	return value, ok
+}
+
+// Contains reports whether the key has a live entry, like Peek it has no
+// side effect, and it does not even restore the value
+func (lru *lruCache) Contains(key Key) bool {
+	ok := false
+	if !lru.bypassed() {
+		lru.Lock()
+		elem, exists := lru.hash.get(key)
+		ok = exists && !elem.Value.(*listEntry).expired(lru.now())
+		lru.Unlock()
+	}
+	lru.audit("Contains", key, ok)
+	return ok
+}
+
+// TTL returns how long the key has left before it expires, NoExpiration
+// if it never does. It reports false if the key has no live entry
+func (lru *lruCache) TTL(key Key) (time.Duration, bool) {
+	lru.Lock()
+	defer lru.Unlock()
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		return 0, false
+	}
+	entry := elem.Value.(*listEntry)
+	if entry.deadTime.IsZero() {
+		return NoExpiration, true
+	}
+	left := entry.deadTime.Sub(lru.now())
+	if left < 0 {
+		return 0, false
+	}
+	return left, true
+}
+
+// Touch moves the deadline of a live entry to d from now, keeping its
+// value and recency. It reports false if the key has no live entry
+func (lru *lruCache) Touch(key Key, d time.Duration) bool {
+	ok := lru.touch(key, d)
+	lru.audit("Touch", key, ok)
+	return ok
+}
+
+func (lru *lruCache) touch(key Key, d time.Duration) bool {
+	d = clampTimeout(d)
+	lru.Lock()
+	defer lru.Unlock()
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		return false
+	}
+	entry := elem.Value.(*listEntry)
+	now := lru.now()
+	if entry.expired(now) {
+		return false
+	}
+	entry.deadTime = deadlineAfter(now, d)
+	entry.lifetime = d
+	entry.probation = false
+	if lru.wal != nil {
+		if value, err := lru.valueOf(entry); err == nil {
+			lru.logWAL(walRecord{Op: walPut, Key: key, Value: value, Deadline: entry.deadTime})
 		}
-		lru.lst.MoveToFront(elem)
-		return elem.Value.(*listEntry).value, true
 	}
-	return nil, false
+	return true
+}
 
+// peek returns the live value of the key without any side effect, the
+// lock must be held
+func (lru *lruCache) peek(key Key) (Value, bool) {
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		return nil, false
+	}
+	entry := elem.Value.(*listEntry)
+	if entry.expired(lru.now()) {
+		return nil, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
 }
+
+// GetAllowStale is the same as Get, but an entry that expired no more than
+// maxStale ago is still returned and reported as stale. Such entries are
+// kept in the cache, only the ones beyond the stale budget are removed
+func (lru *lruCache) GetAllowStale(key Key, maxStale time.Duration) (Value, bool, bool) {
+	var value Value
+	var stale, ok bool
+	if !lru.bypassed() {
+		lru.Lock()
+		value, stale, ok = lru.getAllowStale(key, maxStale)
+		lru.Unlock()
+	}
+	lru.audit("GetAllowStale", key, ok)
+	return value, stale, ok
+}
+
+func (lru *lruCache) getAllowStale(key Key, maxStale time.Duration) (Value, bool, bool) {
+	elem, exists := lru.hash.get(key)
+	if !exists {
+		lru.stats.Misses++
+		return nil, false, false
+	}
+	entry := elem.Value.(*listEntry)
+	now := lru.now()
+	if !entry.expired(now) {
+		value, ok := lru.access(elem)
+		return value, false, ok
+	}
+	if entry.deadTime.Add(maxStale).Before(now) {
+		lru.expire(elem)
+		lru.stats.Misses++
+		return nil, false, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		lru.corrupted(elem, err)
+		lru.stats.Misses++
+		return nil, false, false
+	}
+	lru.stats.Hits++
+	entry.accessCount++
+	entry.lastAccess = now
+	lru.promote(elem)
+	return value, true, true
+}
+
+// GetWithCount is the same as Get, but also returns how many times the
+// entry has been read since it was inserted, including this read.
+// Overwriting the value with Put keeps the count
+func (lru *lruCache) GetWithCount(key Key) (Value, uint64, bool) {
+	var value Value
+	var count uint64
+	var ok bool
+	if !lru.bypassed() {
+		lru.Lock()
+		elem, _ := lru.hash.get(key)
+		if value, ok = lru.access(elem); ok {
+			count = elem.Value.(*listEntry).accessCount
+		}
+		lru.Unlock()
+	}
+	lru.audit("GetWithCount", key, ok)
+	return value, count, ok
+}
+
 func (lru *lruCache) Del(key Key) Value {
+	value, ok := lru.del(key)
+	lru.publish(key)
+	lru.audit("Del", key, ok)
+	return value
+}
+
+// DelE removes the key and reports whether anything was deleted, so a
+// stored nil value can be told apart from an absent key
+func (lru *lruCache) DelE(key Key) (Value, bool) {
+	value, ok := lru.del(key)
+	lru.publish(key)
+	lru.audit("DelE", key, ok)
+	return value, ok
+}
+
+func (lru *lruCache) del(key Key) (Value, bool) {
+	lru.cancelPuts(key)
+	lru.deleteStore(key)
+	lru.Lock()
+	defer lru.Unlock()
+	if elem, exists := lru.hash.get(key); exists {
+		value, _ := lru.valueOf(elem.Value.(*listEntry))
+		lru.logWAL(walRecord{Op: walDel, Key: key})
+		lru.removeElem(elem, ReasonDeleted)
+		return value, true
+	}
+	return nil, false
+}
+
+// RemoveOldest evicts the entry the policy would evict next, the least
+// recently used one by default, and returns it. The eviction callbacks
+// are fired with ReasonCapacity, and the expired entries met on the way
+// are removed as such. The Store keeps the key
+func (lru *lruCache) RemoveOldest() (Key, Value, bool) {
+	key, value, ok := lru.removeOldest()
+	lru.audit("RemoveOldest", key, ok)
+	return key, value, ok
+}
+
+func (lru *lruCache) removeOldest() (Key, Value, bool) {
 	lru.Lock()
 	defer lru.Unlock()
-	if elem, exists := lru.hash[key]; exists {
-		value := elem.Value.(*listEntry).value
-		lru.removeElem(elem)
-		return value
+	now := lru.now()
+	for {
+		elem := lru.policy.victim()
+		if elem == nil {
+			return nil, nil, false
+		}
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			lru.expire(elem)
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			lru.corrupted(elem, err)
+			continue
+		}
+		key := entry.key
+		lru.logWAL(walRecord{Op: walDel, Key: key})
+		lru.evictOldest()
+		return key, value, true
 	}
-	return nil
 }
+
 func (lru *lruCache) Len() int {
 	lru.Lock()
 	defer lru.Unlock()
-	if lru.hash == nil {
-		return 0
+	return lru.hash.len()
+}
+
+// NextExpiry returns the earliest deadline among the live entries, it
+// scans the whole cache so the cost is O(n) in the number of entries
+func (lru *lruCache) NextExpiry() (time.Time, bool) {
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	var next time.Time
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		deadTime := elem.Value.(*listEntry).deadTime
+		if deadTime.IsZero() || deadTime.Before(now) {
+			continue
+		}
+		if next.IsZero() || deadTime.Before(next) {
+			next = deadTime
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Resize changes the maximum number of entries, evicting the least
+// recently used ones with ReasonCapacity until the cache fits
+func (lru *lruCache) Resize(maxLen int) {
+	lru.Lock()
+	defer lru.Unlock()
+	lru.maxLen = maxLen
+	for lru.lst.Len() > 0 && lru.hash.len() > lru.maxLen {
+		lru.evictOldest()
+	}
+}
+
+// EvictionRate returns the number of entries evicted per second to make
+// room for new ones, averaged over the last RateWindow with a precision of
+// RateResolution. Expired and deleted entries are not counted. A rate that
+// stays high compared to the insert rate means the cache is too small
+func (lru *lruCache) EvictionRate() float64 {
+	lru.Lock()
+	defer lru.Unlock()
+	return lru.evictions.rate(lru.now())
+}
+
+// SetBypass turns the cache into a pass through while enabled: every Get
+// misses and every Put is dropped, but the cached entries are kept and
+// served again once it is disabled. Del still removes entries, so that
+// invalidations are not lost. It is safe to toggle at any time
+func (lru *lruCache) SetBypass(bypass bool) {
+	var flag int32
+	if bypass {
+		flag = 1
+	}
+	for {
+		old := atomic.LoadInt32(&lru.bypass)
+		if old == bypassClosed || atomic.CompareAndSwapInt32(&lru.bypass, old, flag) {
+			return
+		}
+	}
+}
+
+// bypassClosed is the bypass state of a closed cache, which SetBypass can
+// not leave
+const bypassClosed = 2
+
+func (lru *lruCache) bypassed() bool {
+	return atomic.LoadInt32(&lru.bypass) != 0
+}
+
+func (lru *lruCache) closed() bool {
+	return atomic.LoadInt32(&lru.bypass) == bypassClosed
+}
+
+// Purge removes all the entries, firing the eviction callbacks and the
+// listeners for each of them, so that the resources held by the values
+// can be released. The live entries are removed with ReasonPurged and
+// the expired ones with ReasonExpired. The Store keeps the keys
+func (lru *lruCache) Purge() {
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	for elem := lru.lst.Back(); elem != nil; elem = lru.lst.Back() {
+		entry := elem.Value.(*listEntry)
+		lru.logWAL(walRecord{Op: walDel, Key: entry.key})
+		if entry.expired(now) {
+			lru.expire(elem)
+		} else {
+			lru.removeElem(elem, ReasonPurged)
+		}
 	}
-	return len(lru.hash)
+	lru.policy.reset()
 }
-func (lru *lruCache) Close() {
+
+// Close stops the background goroutines, runs the callbacks still queued,
+// applies the pending writes to the Store, saves the snapshot and drops
+// the entries. The closed cache stays in bypass mode for good, and it
+// does not touch the Store or the WAL anymore: every Get misses, every Put
+// is dropped, and ReplayWAL, ImportJSON and Warmup return ErrClosed. It
+// returns a *CloseError with the failures of the callbacks run meanwhile,
+// panics included, and of the snapshot, and nil when called again
+func (lru *lruCache) Close() error {
+	if !atomic.CompareAndSwapInt32(&lru.closing, 0, 1) {
+		return nil
+	}
+	if lru.debouncer != nil {
+		lru.debouncer.stop()
+	}
+	atomic.StoreInt32(&lru.bypass, bypassClosed)
+	if lru.stopInvalidations != nil {
+		lru.stopInvalidations()
+	}
+	if lru.sweeper != nil {
+		lru.sweeper.stop()
+	}
+	if lru.throttled != nil {
+		lru.throttled.close(lru.closeCallbackUnthrottled)
+	}
+	if lru.writeBehind != nil {
+		lru.writeBehind.close()
+	}
+	var err error
+	if lru.snapshot != nil {
+		err = lru.snapshot.save(lru)
+	}
 	lru.Lock()
 	defer lru.Unlock()
-	lru.hash = map[Key]*list.Element{}
+	lru.hash = newKeyIndex()
 	lru.lst.Init()
+	lru.policy.reset()
+	lru.weight = 0
+	lru.closeErrsLock.Lock()
+	defer lru.closeErrsLock.Unlock()
+	return joinErrors(append(lru.closeErrs, err)...)
 }
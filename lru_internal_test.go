@@ -0,0 +1,43 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "testing"
+
+func TestCacheMaxResident(t *testing.T) {
+	const maxLen = 16
+	var lru *lruCache
+	maxSeen := 0
+	cb := func(key Key, value Value) {
+		// the callback runs under the lock in the middle of a Put, so
+		// this observes the transient size of the cache
+		if n := lru.hash.len() + 1; n > maxSeen {
+			maxSeen = n
+		}
+	}
+	lru = NewCacheWithConfig(Config{MaxLen: maxLen, Callback: cb}).(*lruCache)
+
+	for i := 0; i < 100*maxLen; i++ {
+		lru.Put(i, i)
+		if n := lru.Len(); n > maxLen {
+			t.Fatalf("test len failed, expect at most %v, got %v", maxLen, n)
+		}
+	}
+	if maxSeen > maxLen {
+		t.Fatalf("test resident entries failed, expect at most %v, got %v", maxLen, maxSeen)
+	}
+}
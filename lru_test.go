@@ -17,12 +17,22 @@ limitations under the License.
 package cache_test
 
 import (
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	. "github.com/leopoldxx/cache"
 )
 
+func checkConsistent(t *testing.T, cache Interface) {
+	t.Helper()
+	if report := cache.(Inspector).Inspect(); !report.Consistent {
+		t.Fatalf("test consistency failed, got %+v", report)
+	}
+}
+
 func TestCache(t *testing.T) {
 	count := 0
 	cb := func(key Key, value Value) {
@@ -168,7 +178,7 @@ func TestCache(t *testing.T) {
 			}
 
 		}
-
+		checkConsistent(t, cache)
 	}
 
 }
@@ -252,3 +262,750 @@ func TestCacheTime(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheDelE(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.Put("nilkey", nil)
+
+	val, ok := cache.DelE("nilkey")
+	if !ok {
+		t.Fatalf("test key %s delete status failed, expect %v, got %v", "nilkey", true, ok)
+	}
+	if val != nil {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "nilkey", nil, val)
+	}
+
+	val, ok = cache.DelE("nilkey")
+	if ok {
+		t.Fatalf("test key %s delete status failed, expect %v, got %v", "nilkey", false, ok)
+	}
+	if val != nil {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "nilkey", nil, val)
+	}
+
+	if _, ok := cache.DelE("absentkey"); ok {
+		t.Fatalf("test key %s delete status failed, expect %v, got %v", "absentkey", false, ok)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("test len failed, expect %v, got %v", 0, cache.Len())
+	}
+}
+
+func TestCacheNextExpiry(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+	if _, ok := cache.(Inspector).NextExpiry(); ok {
+		t.Fatalf("test empty cache next expiry failed, expect %v, got %v", false, ok)
+	}
+
+	start := time.Now()
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Hour)
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Minute)
+	cache.PutWithTimeout("testkey3", "testvalue3", 2*time.Minute)
+	end := time.Now()
+
+	next, ok := cache.(Inspector).NextExpiry()
+	if !ok {
+		t.Fatalf("test next expiry status failed, expect %v, got %v", true, ok)
+	}
+	if next.Before(start.Add(time.Minute)) || next.After(end.Add(time.Minute)) {
+		t.Fatalf("test next expiry failed, expect about %v, got %v", start.Add(time.Minute), next)
+	}
+
+	cache.Del("testkey2")
+	next, _ = cache.(Inspector).NextExpiry()
+	if next.Before(start.Add(2*time.Minute)) || next.After(end.Add(2*time.Minute)) {
+		t.Fatalf("test next expiry failed, expect about %v, got %v", start.Add(2*time.Minute), next)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.Put("testkey1", "testvalue1")
+
+	cache.SetBypass(true)
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if _, ok := cache.GetString("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	cache.Put("testkey2", "testvalue2")
+	if cache.Len() != 1 {
+		t.Fatalf("test len failed, expect %v, got %v", 1, cache.Len())
+	}
+
+	cache.SetBypass(false)
+	if val, ok := cache.Get("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey2", false, ok)
+	}
+}
+
+func TestCacheGetWithCount(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	if _, count, ok := cache.(Inspector).GetWithCount("testkey1"); ok || count != 0 {
+		t.Fatalf("test key %s count failed, expect %v, got %v", "testkey1", 0, count)
+	}
+
+	cache.Put("testkey1", "testvalue1")
+	cache.Get("testkey1")
+	for i := uint64(2); i < 5; i++ {
+		val, count, ok := cache.(Inspector).GetWithCount("testkey1")
+		if !ok || val != "testvalue1" {
+			t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+		}
+		if count != i {
+			t.Fatalf("test key %s count failed, expect %v, got %v", "testkey1", i, count)
+		}
+	}
+}
+
+func TestCacheGetAllowStale(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+
+	val, stale, ok := cache.(StaleReader).GetAllowStale("testkey1", time.Second)
+	if !ok || stale || val != "testvalue1" {
+		t.Fatalf("test key %s fresh read failed, expect %v/%v/%v, got %v/%v/%v", "testkey1", "testvalue1", false, true, val, stale, ok)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	val, stale, ok = cache.(StaleReader).GetAllowStale("testkey1", time.Second)
+	if !ok || !stale || val != "testvalue1" {
+		t.Fatalf("test key %s stale read failed, expect %v/%v/%v, got %v/%v/%v", "testkey1", "testvalue1", true, true, val, stale, ok)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("test len failed, expect %v, got %v", 1, cache.Len())
+	}
+
+	// just beyond the stale budget it is a miss, and the entry is gone
+	_, _, ok = cache.(StaleReader).GetAllowStale("testkey1", 100*time.Millisecond)
+	if ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("test len failed, expect %v, got %v", 0, cache.Len())
+	}
+}
+
+func TestCacheHitTTL(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3, InsertTTL: time.Second, HitTTL: time.Minute})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.PutWithTimeout("testkey3", "testvalue3", time.Second)
+
+	// hits on testkey2 and testkey3, only the one added by Put is promoted
+	cache.Get("testkey2")
+	cache.Get("testkey3")
+
+	time.Sleep(1200 * time.Millisecond)
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if val, ok := cache.Get("testkey2"); !ok || val != "testvalue2" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey2", "testvalue2", val)
+	}
+	if _, ok := cache.Get("testkey3"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey3", false, ok)
+	}
+}
+
+func TestCacheEvictionRate(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	if rate := cache.(Estimator).EvictionRate(); rate != 0 {
+		t.Fatalf("test eviction rate failed, expect %v, got %v", 0, rate)
+	}
+	for i := 0; i < 62; i++ {
+		cache.Put(i, i)
+	}
+	if rate := cache.(Estimator).EvictionRate(); rate != 1 {
+		t.Fatalf("test eviction rate failed, expect %v, got %v", 1, rate)
+	}
+	cache.Del(61)
+	if rate := cache.(Estimator).EvictionRate(); rate != 1 {
+		t.Fatalf("test eviction rate failed, expect %v, got %v", 1, rate)
+	}
+}
+
+func TestCacheGetOrStore(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+
+	const workers = 32
+	var wg sync.WaitGroup
+	actuals := make([]Value, workers)
+	stored := int32(0)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual, loaded := cache.GetOrStore("testkey1", i, time.Minute)
+			if !loaded {
+				atomic.AddInt32(&stored, 1)
+			}
+			actuals[i] = actual
+		}(i)
+	}
+	wg.Wait()
+
+	if stored != 1 {
+		t.Fatalf("test stores failed, expect %v, got %v", 1, stored)
+	}
+	val, _ := cache.Get("testkey1")
+	for i, actual := range actuals {
+		if actual != val {
+			t.Fatalf("test worker %d value failed, expect %v, got %v", i, val, actual)
+		}
+	}
+}
+
+func TestCacheInspect(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+	cache.Put("testkey1", "testvalue1")
+	cache.PutInt(2, "testvalue2")
+	cache.Put(3, "testvalue3")
+	cache.Get("testkey1")
+
+	report := cache.(Inspector).Inspect()
+	if !report.Consistent || report.ListLen != 3 || report.IndexLen != 3 {
+		t.Fatalf("test inspect failed, got %+v", report)
+	}
+	if report.FrontKey != "testkey1" || report.BackKey != int64(2) {
+		t.Fatalf("test inspect front/back failed, expect %v/%v, got %v/%v", "testkey1", 2, report.FrontKey, report.BackKey)
+	}
+	if report.Expired != 0 {
+		t.Fatalf("test inspect expired failed, expect %v, got %v", 0, report.Expired)
+	}
+}
+
+func TestCacheEvictionReason(t *testing.T) {
+	type eviction struct {
+		key    Key
+		value  Value
+		reason EvictionReason
+	}
+	var evictions []eviction
+	count := 0
+	cache := NewCacheWithConfig(Config{
+		MaxLen:   2,
+		Callback: func(key Key, value Value) { count++ },
+		CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+			evictions = append(evictions, eviction{key, value, reason})
+		},
+	})
+
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.Put("testkey1", "testvalue2")
+	cache.Put("testkey2", "testvalue3")
+	cache.Put("testkey3", "testvalue4")
+	cache.Del("testkey2")
+	cache.PutWithTimeout("testkey4", "testvalue5", time.Second)
+	time.Sleep(1200 * time.Millisecond)
+	cache.Get("testkey4")
+
+	expect := []eviction{
+		{"testkey1", "testvalue1", ReasonReplaced},
+		{"testkey1", "testvalue2", ReasonCapacity},
+		{"testkey2", "testvalue3", ReasonDeleted},
+		{"testkey4", "testvalue5", ReasonExpired},
+	}
+	if len(evictions) != len(expect) {
+		t.Fatalf("test evictions failed, expect %v, got %v", expect, evictions)
+	}
+	for i := range expect {
+		if evictions[i] != expect[i] {
+			t.Fatalf("test eviction %d failed, expect %v, got %v", i, expect[i], evictions[i])
+		}
+	}
+	// the plain callback never hears about replaced values
+	if count != 3 {
+		t.Fatalf("test callbacks failed, expect %v, got %v", 3, count)
+	}
+}
+
+func TestCachePeek(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+
+	if val, ok := cache.Peek("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+	if _, ok := cache.Peek("testkey3"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey3", false, ok)
+	}
+	// peeking did not make testkey1 recently used, so it is evicted
+	cache.Put("testkey3", "testvalue3")
+	if _, ok := cache.Peek("testkey1"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("test stats failed, expect no reads, got %+v", stats)
+	}
+}
+
+func TestCacheContains(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Second)
+	cache.Put("testkey3", nil)
+
+	if !cache.Contains("testkey3") {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey3", true, false)
+	}
+	if cache.Contains("testkey1") {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey1", false, true)
+	}
+	time.Sleep(1200 * time.Millisecond)
+	if cache.Contains("testkey2") {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey2", false, true)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Minute)
+
+	ttl, ok := cache.TTL("testkey1")
+	if !ok || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("test key %s ttl failed, expect about %v, got %v", "testkey1", time.Minute, ttl)
+	}
+	if _, ok := cache.TTL("testkey2"); ok {
+		t.Fatalf("test key %s exist status failed, expect %v, got %v", "testkey2", false, ok)
+	}
+}
+
+func TestCacheTouch(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Second)
+
+	if !cache.Touch("testkey1", time.Minute) {
+		t.Fatalf("test key %s touch failed, expect %v, got %v", "testkey1", true, false)
+	}
+	if cache.Touch("testkey3", time.Minute) {
+		t.Fatalf("test key %s touch failed, expect %v, got %v", "testkey3", false, true)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	if val, ok := cache.Get("testkey1"); !ok || val != "testvalue1" {
+		t.Fatalf("test key %s value failed, expect %v, got %v", "testkey1", "testvalue1", val)
+	}
+	if cache.Touch("testkey2", time.Minute) {
+		t.Fatalf("test expired key %s touch failed, expect %v, got %v", "testkey2", false, true)
+	}
+}
+
+func TestCacheResize(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 4, Callback: cb})
+	for i := 0; i < 4; i++ {
+		cache.Put(i, i)
+	}
+	cache.Get(0)
+
+	cache.(Resizer).Resize(2)
+	if cache.Len() != 2 {
+		t.Fatalf("test len failed, expect %v, got %v", 2, cache.Len())
+	}
+	expect := []Key{1, 2}
+	if len(evicted) != len(expect) || evicted[0] != expect[0] || evicted[1] != expect[1] {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+
+	cache.(Resizer).Resize(3)
+	cache.Put(4, 4)
+	if cache.Len() != 3 || !cache.Contains(0) || !cache.Contains(3) {
+		t.Fatalf("test grown cache failed, got keys %v", cache.Keys())
+	}
+}
+
+func TestCacheSlidingTTL(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3, SlidingTTL: true})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Second)
+
+	// reading testkey1 keeps it alive past its original deadline
+	for i := 0; i < 3; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if _, ok := cache.Get("testkey1"); !ok {
+			t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", true, ok)
+		}
+	}
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", false, ok)
+	}
+
+	// once idle for its timeout, it expires
+	time.Sleep(1100 * time.Millisecond)
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", false, ok)
+	}
+}
+
+func TestCacheNoExpiration(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2, CacheTime: time.Second, SlidingTTL: true})
+	cache.PutWithTimeout("testkey1", "testvalue1", NoExpiration)
+	cache.Put("testkey2", "testvalue2")
+	cache.Get("testkey1")
+
+	if d, ok := cache.TTL("testkey1"); !ok || d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey1", NoExpiration, d)
+	}
+	if next, ok := cache.(Inspector).NextExpiry(); !ok || time.Until(next) > time.Second {
+		t.Fatalf("test next expiry failed, got %v", next)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if v, ok := cache.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", false, ok)
+	}
+
+	// it is still evicted by capacity
+	cache.Put("testkey3", "testvalue3")
+	cache.Put("testkey4", "testvalue4")
+	if cache.Contains("testkey1") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", false, true)
+	}
+
+	// and a deadline can be dropped by touching the key
+	if !cache.Touch("testkey3", NoExpiration) {
+		t.Fatalf("test touch key %s failed, expect %v, got %v", "testkey3", true, false)
+	}
+	if _, ok := cache.(Inspector).NextExpiry(); !ok {
+		t.Fatalf("test next expiry failed, expect %v, got %v", true, ok)
+	}
+	cache.Del("testkey4")
+	if _, ok := cache.(Inspector).NextExpiry(); ok {
+		t.Fatalf("test next expiry failed, expect %v, got %v", false, ok)
+	}
+}
+
+func TestCachePutWithDeadline(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	cache.PutWithDeadline("testkey1", "testvalue1", deadline)
+	cache.PutWithDeadline("testkey2", "testvalue2", time.Time{})
+	cache.Put("testkey3", "testvalue3")
+
+	if next, ok := cache.(Inspector).NextExpiry(); !ok || !next.Equal(deadline) {
+		t.Fatalf("test next expiry failed, expect %v, got %v", deadline, next)
+	}
+	if d, ok := cache.TTL("testkey2"); !ok || d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey2", NoExpiration, d)
+	}
+
+	// a deadline in the past drops the value
+	cache.PutWithDeadline("testkey3", "testvalue3-2", time.Now().Add(-time.Second))
+	if cache.Contains("testkey3") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey3", false, true)
+	}
+
+	time.Sleep(1600 * time.Millisecond)
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", false, ok)
+	}
+	if _, ok := cache.Get("testkey2"); !ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", true, ok)
+	}
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100, CacheTime: 10 * time.Second, TTLJitter: 0.2})
+	for i := 0; i < 100; i++ {
+		cache.Put(i, i)
+	}
+	min, max := time.Hour, time.Duration(0)
+	for i := 0; i < 100; i++ {
+		d, ok := cache.TTL(i)
+		if !ok {
+			t.Fatalf("test key %d failed, expect %v, got %v", i, true, ok)
+		}
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	if min < 8*time.Second || max > 12*time.Second {
+		t.Fatalf("test ttl range failed, expect within %v and %v, got %v and %v", 8*time.Second, 12*time.Second, min, max)
+	}
+	if max-min < time.Second {
+		t.Fatalf("test ttl spread failed, got %v to %v", min, max)
+	}
+}
+
+func TestCacheRemoveOldest(t *testing.T) {
+	var evicted []EvictionReason
+	cache := NewCacheWithConfig(Config{MaxLen: 100, CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+		evicted = append(evicted, reason)
+	}})
+	defer cache.Close()
+	cache.PutWithDeadline("testkey1", "testvalue1", time.Now().Add(10*time.Millisecond))
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	cache.Get("testkey2")
+	time.Sleep(20 * time.Millisecond)
+
+	tests := []struct {
+		key   Key
+		value Value
+		ok    bool
+	}{
+		{"testkey3", "testvalue3", true},
+		{"testkey2", "testvalue2", true},
+		{nil, nil, false},
+	}
+	for _, test := range tests {
+		key, value, ok := cache.(Evicter).RemoveOldest()
+		if key != test.key || value != test.value || ok != test.ok {
+			t.Fatalf("test key %v failed, expect %v, got %v %v", test.key, test.value, key, value)
+		}
+	}
+	expected := []EvictionReason{ReasonExpired, ReasonCapacity, ReasonCapacity}
+	if len(evicted) != len(expected) || evicted[0] != expected[0] || evicted[1] != expected[1] || evicted[2] != expected[2] {
+		t.Fatalf("test eviction reasons failed, expect %v, got %v", expected, evicted)
+	}
+	if stats := cache.Stats(); stats.Evictions != 2 || stats.Expirations != 1 {
+		t.Fatalf("test stats failed, got %+v", stats)
+	}
+}
+
+func TestShardedRemoveOldest(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: 4})
+	defer cache.Close()
+	for i := 0; i < 20; i++ {
+		cache.Put(i, i)
+	}
+	for i := 0; i < 20; i++ {
+		if _, _, ok := cache.(Evicter).RemoveOldest(); !ok {
+			t.Fatalf("test remove %d failed, expect %v, got %v", i, true, ok)
+		}
+	}
+	if _, _, ok := cache.(Evicter).RemoveOldest(); ok || cache.Len() != 0 {
+		t.Fatalf("test empty cache failed, expect %v, got %v", 0, cache.Len())
+	}
+}
+
+func TestCacheGetOldestNewest(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	if _, ok := cache.(Evicter).GetOldest(); ok {
+		t.Fatalf("test empty cache failed, expect %v, got %v", false, ok)
+	}
+	deadline := time.Now().Add(time.Minute)
+	cache.PutWithDeadline("testkey1", "testvalue1", deadline)
+	cache.PutWithTimeout("testkey2", "testvalue2", NoExpiration)
+	cache.PutWithDeadline("testkey3", "testvalue3", time.Now().Add(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	tests := []struct {
+		get   func() (Entry, bool)
+		entry Entry
+	}{
+		{cache.(Evicter).GetOldest, Entry{Key: "testkey1", Value: "testvalue1", Deadline: deadline}},
+		{cache.(Evicter).GetNewest, Entry{Key: "testkey2", Value: "testvalue2"}},
+		// inspecting does not move the entries
+		{cache.(Evicter).GetOldest, Entry{Key: "testkey1", Value: "testvalue1", Deadline: deadline}},
+	}
+	for _, test := range tests {
+		entry, ok := test.get()
+		if !ok || entry.Key != test.entry.Key || entry.Value != test.entry.Value {
+			t.Fatalf("test key %s failed, expect %+v, got %+v", test.entry.Key, test.entry, entry)
+		}
+		if !entry.Deadline.Equal(test.entry.Deadline) {
+			t.Fatalf("test key %s deadline failed, expect %v, got %v", test.entry.Key, test.entry.Deadline, entry.Deadline)
+		}
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("test len failed, expect %v, got %v", 3, cache.Len())
+	}
+}
+
+func TestCacheGetEntry(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		clock := NewManualClock(time.Unix(1000, 0))
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, CacheTime: time.Minute, Clock: clock})
+		created := clock.Now()
+		cache.Put("testkey1", "testvalue1")
+		entry, ok := cache.GetEntry("testkey1")
+		if !ok || entry.Value != "testvalue1" || entry.Created != created || entry.Hits != 0 || !entry.LastAccess.IsZero() {
+			t.Fatalf("test shards %d key %s failed, expect %v unread, got %+v", shards, "testkey1", "testvalue1", entry)
+		}
+
+		clock.Advance(time.Second)
+		cache.Get("testkey1")
+		clock.Advance(time.Second)
+		read := clock.Now()
+		cache.Get("testkey1")
+		clock.Advance(time.Second)
+		// overwriting resets the creation time and keeps the hits
+		cache.Put("testkey1", "testvalue2")
+		updated := clock.Now()
+		clock.Advance(time.Second)
+
+		entry, ok = cache.GetEntry("testkey1")
+		expect := Entry{Key: "testkey1", Value: "testvalue2", Deadline: updated.Add(time.Minute), Age: time.Second, Created: updated, LastAccess: read, Hits: 2}
+		if !ok || entry != expect {
+			t.Fatalf("test shards %d key %s failed, expect %+v, got %+v", shards, "testkey1", expect, entry)
+		}
+		// inspecting is not a read
+		if entry, _ = cache.GetEntry("testkey1"); entry.Hits != 2 || cache.Stats().Hits != 2 {
+			t.Fatalf("test shards %d key %s hits failed, expect %v, got %v/%v", shards, "testkey1", 2, entry.Hits, cache.Stats().Hits)
+		}
+
+		if _, ok := cache.GetEntry("testkey2"); ok {
+			t.Fatalf("test shards %d missing key %s failed, expect %v, got %v", shards, "testkey2", false, ok)
+		}
+		clock.Advance(time.Minute + time.Second)
+		if _, ok := cache.GetEntry("testkey1"); ok {
+			t.Fatalf("test shards %d expired key %s failed, expect %v, got %v", shards, "testkey1", false, ok)
+		}
+	}
+}
+
+func TestCacheGetOldestPolicy(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		oldest  Key
+		removed Key
+	}{
+		// LFU evicts the key read less often, not the least recently used
+		{PolicyLFU, "testkey1", "testkey2"},
+		// FIFO evicts and reports the key inserted first
+		{PolicyFIFO, "testkey1", "testkey1"},
+	}
+	for _, test := range tests {
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Policy: test.policy})
+		cache.Put("testkey1", "testvalue1")
+		cache.Put("testkey2", "testvalue2")
+		cache.Get("testkey1")
+		cache.Get("testkey1")
+		cache.Get("testkey2")
+		if entry, _ := cache.(Evicter).GetOldest(); entry.Key != test.oldest {
+			t.Fatalf("test policy %d oldest failed, expect %v, got %v", test.policy, test.oldest, entry.Key)
+		}
+		if key, _, _ := cache.(Evicter).RemoveOldest(); key != test.removed {
+			t.Fatalf("test policy %d removed failed, expect %v, got %v", test.policy, test.removed, key)
+		}
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		purged := map[Key]EvictionReason{}
+		var events []EventType
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards,
+			CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+				purged[key] = reason
+			},
+			Listeners: []Listener{func(e Event) {
+				if e.Type != EventInsert {
+					events = append(events, e.Type)
+				}
+			}},
+		})
+		cache.Put("testkey1", "testvalue1")
+		cache.Put("testkey2", "testvalue2")
+		cache.PutWithDeadline("testkey3", "testvalue3", time.Now().Add(10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		cache.Purge()
+
+		tests := []struct {
+			key    Key
+			reason EvictionReason
+		}{
+			{"testkey1", ReasonPurged},
+			{"testkey2", ReasonPurged},
+			{"testkey3", ReasonExpired},
+		}
+		for _, test := range tests {
+			if reason := purged[test.key]; reason != test.reason {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, test.key, test.reason, reason)
+			}
+		}
+		if len(events) != 3 || cache.Len() != 0 {
+			t.Fatalf("test %d shards purge failed, expect %v events, got %v, len %v", shards, 3, events, cache.Len())
+		}
+		cache.Put("testkey4", "testvalue4")
+		if v, _ := cache.Get("testkey4"); v != "testvalue4" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey4", "testvalue4", v)
+		}
+		cache.Close()
+	}
+}
+
+func TestCacheCloseCallbackErrors(t *testing.T) {
+	errClose := errors.New("testerror")
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, CallbackRateLimit: 10, CloseCallbackUnthrottled: true,
+			CallbackWithError: func(key Key, value Value, reason EvictionReason) error {
+				if key == "testkey2" {
+					panic("testpanic")
+				}
+				return errClose
+			}})
+		cache.Put("testkey1", "testvalue1")
+		cache.Put("testkey2", "testvalue2")
+		cache.Del("testkey1")
+		cache.Del("testkey2")
+
+		err := cache.Close()
+		var ce *CloseError
+		if !errors.As(err, &ce) || len(ce.Errs) != 2 || !errors.Is(err, errClose) {
+			t.Fatalf("test %d shards close failed, expect %v and a panic, got %v", shards, errClose, err)
+		}
+		var p *CallbackPanic
+		for _, err := range ce.Errs {
+			if errors.As(err, &p) && p.Value != "testpanic" {
+				t.Fatalf("test %d shards close panic failed, expect %v, got %v", shards, "testpanic", p.Value)
+			}
+		}
+		if p == nil {
+			t.Fatalf("test %d shards close panic failed, expect %v, got %v", shards, "testpanic", err)
+		}
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		store := newMapStore()
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, Store: store})
+		cache.Put("testkey1", "testvalue1")
+		if err := cache.Close(); err != nil {
+			t.Fatalf("test %d shards close failed, expect %v, got %v", shards, nil, err)
+		}
+		if err := cache.Close(); err != nil {
+			t.Fatalf("test %d shards second close failed, expect %v, got %v", shards, nil, err)
+		}
+
+		cache.SetBypass(false)
+		cache.Put("testkey2", "testvalue2")
+		cache.Del("testkey1")
+		tests := []struct {
+			key Key
+		}{
+			{"testkey1"},
+			{"testkey2"},
+		}
+		for _, test := range tests {
+			if v, ok := cache.Get(test.key); ok {
+				t.Fatalf("test %d shards closed key %s failed, expect %v, got %v", shards, test.key, nil, v)
+			}
+		}
+		if cache.Len() != 0 || len(store.data) != 1 || store.data["testkey1"] != "testvalue1" {
+			t.Fatalf("test %d shards closed cache failed, expect the store untouched, got %v", shards, store.data)
+		}
+		if err := cache.(Warmer).Warmup([]Key{"testkey3"}, nil, 1); err != ErrClosed {
+			t.Fatalf("test %d shards warmup failed, expect %v, got %v", shards, ErrClosed, err)
+		}
+	}
+}
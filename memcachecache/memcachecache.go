@@ -0,0 +1,755 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memcachecache implements cache.Interface over memcached, with
+// the TTL semantics of the in-process cache.
+package memcachecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/leopoldxx/cache"
+)
+
+// Client is the part of *memcache.Client the cache uses
+type Client interface {
+	Get(key string) (*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	CompareAndSwap(item *memcache.Item) error
+	Delete(key string) error
+	Close() error
+}
+
+// Codec converts the values to and from the bytes stored in memcached
+type Codec interface {
+	Marshal(v cache.Value) ([]byte, error)
+	Unmarshal(data []byte) (cache.Value, error)
+}
+
+// GobCodec is the default Codec, values of custom types must be
+// registered with gob.Register
+type GobCodec struct{}
+
+// Marshal will gob encode the value as an interface
+func (GobCodec) Marshal(v cache.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+// Unmarshal will decode a value encoded by Marshal
+func (GobCodec) Unmarshal(data []byte) (cache.Value, error) {
+	var v cache.Value
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// Config of the memcached cache
+type Config struct {
+	Client Client
+	// Prefix is prepended to the keys, so that several caches can share
+	// the servers
+	Prefix string
+	// CacheTime is the lifetime of the entries added by Put,
+	// cache.DefaultCacheTime if not set
+	CacheTime time.Duration
+	// NegativeCacheTime is the lifetime of the cache.NotFound entries,
+	// CacheTime if not set
+	NegativeCacheTime time.Duration
+	// Codec of the values, GobCodec if nil
+	Codec Codec
+	// OnError will be called with the errors of the memcached commands,
+	// which the methods without an error result turn into misses
+	OnError func(err error)
+	// Equal compares the values for CompareAndSwap, cache.DefaultEqual if
+	// nil
+	Equal func(a, b cache.Value) bool
+}
+
+type memcacheCache struct {
+	client            Client
+	prefix            string
+	cacheTime         time.Duration
+	negativeCacheTime time.Duration
+	codec             Codec
+	onError           func(err error)
+	equal             func(a, b cache.Value) bool
+	// view is set for the namespaces, which do not own the client
+	view bool
+
+	bypass int32
+	closed int32
+	hits   uint64
+	misses uint64
+	loads  cache.LoadGroup
+	locks  *cache.StripedLocks
+}
+
+// New will create a cache over the memcached client of the config. The
+// keys are stored as their fmt.Sprint string, and must follow the rules
+// of memcached once prefixed: at most 250 bytes, without spaces or
+// control characters. Every item starts with the deadline of the entry,
+// so that TTL reports it and the deadlines are kept to the nanosecond
+// even though memcached expires the items on the second after. memcached
+// can not list its keys, so Len, Keys and Range see no entry, and the
+// cache does not implement cache.Persister. Closing the cache closes the
+// client
+func New(config Config) cache.Interface {
+	if config.CacheTime < time.Millisecond {
+		config.CacheTime = cache.DefaultCacheTime
+	}
+	if config.NegativeCacheTime <= 0 {
+		config.NegativeCacheTime = config.CacheTime
+	}
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	if config.Equal == nil {
+		config.Equal = cache.DefaultEqual
+	}
+	return &memcacheCache{
+		client:            config.Client,
+		prefix:            config.Prefix,
+		cacheTime:         config.CacheTime,
+		negativeCacheTime: config.NegativeCacheTime,
+		codec:             config.Codec,
+		onError:           config.OnError,
+		equal:             config.Equal,
+		locks:             new(cache.StripedLocks),
+	}
+}
+
+func (mc *memcacheCache) name(key cache.Key) string {
+	return mc.prefix + fmt.Sprint(key)
+}
+
+func (mc *memcacheCache) fail(err error) {
+	if err != nil && err != memcache.ErrCacheMiss && mc.onError != nil {
+		mc.onError(err)
+	}
+}
+
+func (mc *memcacheCache) bypassed() bool {
+	return atomic.LoadInt32(&mc.bypass) == 1 || atomic.LoadInt32(&mc.closed) == 1
+}
+
+// deadlineAfter returns the deadline of a timeout, the zero time for
+// cache.NoExpiration
+func deadlineAfter(t time.Duration) time.Time {
+	if t == cache.NoExpiration {
+		return time.Time{}
+	}
+	if t < time.Second {
+		t = time.Second
+	}
+	return time.Now().Add(t)
+}
+
+// expiration turns a deadline into an absolute expiration, rounded up to
+// the second
+func expiration(deadline time.Time) int32 {
+	if deadline.IsZero() {
+		return 0
+	}
+	return int32(deadline.Add(time.Second - 1).Unix())
+}
+
+// item encodes the value after its deadline in unix nanoseconds, 0 for
+// none
+func (mc *memcacheCache) item(key cache.Key, value cache.Value, deadline time.Time) (*memcache.Item, error) {
+	data, err := mc.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8, 8+len(data))
+	if !deadline.IsZero() {
+		binary.BigEndian.PutUint64(buf, uint64(deadline.UnixNano()))
+	}
+	return &memcache.Item{Key: mc.name(key), Value: append(buf, data...), Expiration: expiration(deadline)}, nil
+}
+
+// decode returns the value and deadline of an item, reporting false if
+// it has already expired
+func (mc *memcacheCache) decode(item *memcache.Item) (cache.Value, time.Time, bool) {
+	if len(item.Value) < 8 {
+		mc.fail(fmt.Errorf("memcachecache: malformed item %q", item.Key))
+		return nil, time.Time{}, false
+	}
+	var deadline time.Time
+	if nanos := binary.BigEndian.Uint64(item.Value); nanos != 0 {
+		deadline = time.Unix(0, int64(nanos))
+		if deadline.Before(time.Now()) {
+			return nil, time.Time{}, false
+		}
+	}
+	value, err := mc.codec.Unmarshal(item.Value[8:])
+	if err != nil {
+		mc.fail(err)
+		return nil, time.Time{}, false
+	}
+	return value, deadline, true
+}
+
+// fetch reads the live value of the key, with its deadline
+func (mc *memcacheCache) fetch(key cache.Key) (*memcache.Item, cache.Value, time.Time, bool) {
+	if mc.bypassed() {
+		return nil, nil, time.Time{}, false
+	}
+	item, err := mc.client.Get(mc.name(key))
+	if err != nil {
+		mc.fail(err)
+		return nil, nil, time.Time{}, false
+	}
+	value, deadline, ok := mc.decode(item)
+	return item, value, deadline, ok
+}
+
+func (mc *memcacheCache) set(key cache.Key, value cache.Value, deadline time.Time) {
+	if mc.bypassed() {
+		return
+	}
+	if !deadline.IsZero() && deadline.Before(time.Now()) {
+		mc.fail(mc.client.Delete(mc.name(key)))
+		return
+	}
+	item, err := mc.item(key, value, deadline)
+	if err != nil {
+		mc.fail(err)
+		return
+	}
+	mc.fail(mc.client.Set(item))
+}
+
+func (mc *memcacheCache) Put(key cache.Key, value cache.Value) {
+	mc.set(key, value, deadlineAfter(mc.cacheTime))
+}
+
+// PutNotFound sets cache.NotFound for NegativeCacheTime
+func (mc *memcacheCache) PutNotFound(key cache.Key) {
+	mc.set(key, cache.NotFound, deadlineAfter(mc.negativeCacheTime))
+}
+
+func (mc *memcacheCache) PutWithTimeout(key cache.Key, value cache.Value, t time.Duration) {
+	mc.set(key, value, deadlineAfter(t))
+}
+
+func (mc *memcacheCache) PutWithDeadline(key cache.Key, value cache.Value, deadline time.Time) {
+	mc.set(key, value, deadline)
+}
+
+func (mc *memcacheCache) PutString(key string, value cache.Value) {
+	mc.Put(key, value)
+}
+
+func (mc *memcacheCache) PutInt(key int64, value cache.Value) {
+	mc.Put(key, value)
+}
+
+// PutMulti sets the keys one by one, memcached has no batch set
+func (mc *memcacheCache) PutMulti(entries map[cache.Key]cache.Value) {
+	mc.PutMultiWithTimeout(entries, mc.cacheTime)
+}
+
+func (mc *memcacheCache) PutMultiWithTimeout(entries map[cache.Key]cache.Value, t time.Duration) {
+	deadline := deadlineAfter(t)
+	for key, value := range entries {
+		mc.set(key, value, deadline)
+	}
+}
+
+func (mc *memcacheCache) Get(key cache.Key) (cache.Value, bool) {
+	_, value, _, ok := mc.fetch(key)
+	if ok {
+		atomic.AddUint64(&mc.hits, 1)
+	} else {
+		atomic.AddUint64(&mc.misses, 1)
+	}
+	return value, ok
+}
+
+func (mc *memcacheCache) Peek(key cache.Key) (cache.Value, bool) {
+	_, value, _, ok := mc.fetch(key)
+	return value, ok
+}
+
+// GetEntry returns the value and the deadline of the key, memcached keeps
+// no creation nor access time, and no hit count
+func (mc *memcacheCache) GetEntry(key cache.Key) (cache.Entry, bool) {
+	_, value, deadline, ok := mc.fetch(key)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	return cache.Entry{Key: key, Value: value, Deadline: deadline}, true
+}
+
+func (mc *memcacheCache) GetString(key string) (cache.Value, bool) {
+	return mc.Get(key)
+}
+
+func (mc *memcacheCache) GetInt(key int64) (cache.Value, bool) {
+	return mc.Get(key)
+}
+
+// GetMulti reads the keys one by one
+func (mc *memcacheCache) GetMulti(keys []cache.Key) map[cache.Key]cache.Value {
+	values := make(map[cache.Key]cache.Value, len(keys))
+	for _, key := range keys {
+		if value, ok := mc.Get(key); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func (mc *memcacheCache) Contains(key cache.Key) bool {
+	_, _, _, ok := mc.fetch(key)
+	return ok
+}
+
+func (mc *memcacheCache) TTL(key cache.Key) (time.Duration, bool) {
+	_, _, deadline, ok := mc.fetch(key)
+	if !ok {
+		return 0, false
+	}
+	if deadline.IsZero() {
+		return cache.NoExpiration, true
+	}
+	return time.Until(deadline), true
+}
+
+// Touch rewrites the item with the new deadline, with a compare and swap
+// so that a concurrent put is not overwritten with the old value
+func (mc *memcacheCache) Touch(key cache.Key, d time.Duration) bool {
+	for {
+		item, value, _, ok := mc.fetch(key)
+		if !ok {
+			return false
+		}
+		touched, err := mc.item(key, value, deadlineAfter(d))
+		if err != nil {
+			mc.fail(err)
+			return false
+		}
+		touched.CasID = item.CasID
+		err = mc.client.CompareAndSwap(touched)
+		if err == nil {
+			return true
+		}
+		if err != memcache.ErrCASConflict {
+			mc.fail(err)
+			return false
+		}
+	}
+}
+
+// LockKey serializes the callers of this process by key, the other
+// processes are not locked out
+func (mc *memcacheCache) LockKey(key cache.Key) func() {
+	return mc.locks.LockKey(mc.name(key))
+}
+
+// Add stores the value with ADD for the default lifetime. An item that
+// memcached still has after its deadline is replaced with a compare and
+// swap, so that only one of the concurrent adds wins
+func (mc *memcacheCache) Add(key cache.Key, value cache.Value) bool {
+	if mc.bypassed() {
+		return false
+	}
+	item, err := mc.item(key, value, deadlineAfter(mc.cacheTime))
+	if err != nil {
+		mc.fail(err)
+		return false
+	}
+	for {
+		err := mc.client.Add(item)
+		if err == nil {
+			return true
+		}
+		if err != memcache.ErrNotStored {
+			mc.fail(err)
+			return false
+		}
+		current, err := mc.client.Get(item.Key)
+		if err == memcache.ErrCacheMiss {
+			continue
+		}
+		if err != nil {
+			mc.fail(err)
+			return false
+		}
+		if _, _, ok := mc.decode(current); ok {
+			return false
+		}
+		item.CasID = current.CasID
+		err = mc.client.CompareAndSwap(item)
+		if err == nil {
+			return true
+		}
+		if err != memcache.ErrCASConflict && err != memcache.ErrNotStored {
+			mc.fail(err)
+			return false
+		}
+	}
+}
+
+// Replace rewrites a live item for the default lifetime, with a compare
+// and swap so that an item past its deadline is not replaced
+func (mc *memcacheCache) Replace(key cache.Key, value cache.Value) bool {
+	if mc.bypassed() {
+		return false
+	}
+	replaced, err := mc.item(key, value, deadlineAfter(mc.cacheTime))
+	if err != nil {
+		mc.fail(err)
+		return false
+	}
+	for {
+		item, _, _, ok := mc.fetch(key)
+		if !ok {
+			return false
+		}
+		replaced.CasID = item.CasID
+		err = mc.client.CompareAndSwap(replaced)
+		if err == nil {
+			return true
+		}
+		if err != memcache.ErrCASConflict {
+			mc.fail(err)
+			return false
+		}
+	}
+}
+
+// IncrementInt64 rewrites the item with a compare and swap, and tries
+// again if another client changes it meanwhile. The values are encoded
+// by the codec after their deadline, so INCR can not be used
+func (mc *memcacheCache) IncrementInt64(key cache.Key, delta int64) (int64, error) {
+	if mc.bypassed() {
+		return delta, nil
+	}
+	for {
+		n := delta
+		deadline := deadlineAfter(mc.cacheTime)
+		current, value, currentDeadline, ok := mc.fetch(key)
+		if ok {
+			i, isInt := cache.ToInt64(value)
+			if !isInt {
+				return 0, cache.ErrNotInteger
+			}
+			n += i
+			deadline = currentDeadline
+		}
+		item, err := mc.item(key, n, deadline)
+		if err != nil {
+			mc.fail(err)
+			return 0, err
+		}
+		if ok {
+			item.CasID = current.CasID
+			err = mc.client.CompareAndSwap(item)
+		} else {
+			err = mc.client.Add(item)
+		}
+		switch err {
+		case nil:
+			return n, nil
+		case memcache.ErrCASConflict, memcache.ErrNotStored:
+			continue
+		}
+		mc.fail(err)
+		return 0, err
+	}
+}
+
+func (mc *memcacheCache) DecrementInt64(key cache.Key, delta int64) (int64, error) {
+	return mc.IncrementInt64(key, -delta)
+}
+
+// CompareAndSwap relies on the CAS of memcached, the swap fails if the
+// item changed since it was compared. The item keeps its deadline
+func (mc *memcacheCache) CompareAndSwap(key cache.Key, old, new cache.Value) bool {
+	item, value, deadline, ok := mc.fetch(key)
+	if !ok || !mc.equal(value, old) {
+		return false
+	}
+	swapped, err := mc.item(key, new, deadline)
+	if err != nil {
+		mc.fail(err)
+		return false
+	}
+	swapped.CasID = item.CasID
+	err = mc.client.CompareAndSwap(swapped)
+	if err != memcache.ErrCASConflict && err != memcache.ErrNotStored {
+		mc.fail(err)
+	}
+	return err == nil
+}
+
+// GetOrStore stores def with ADD, and reads the value the key already
+// had if it was not stored
+func (mc *memcacheCache) GetOrStore(key cache.Key, def cache.Value, t time.Duration) (cache.Value, bool) {
+	if mc.bypassed() {
+		return def, false
+	}
+	item, err := mc.item(key, def, deadlineAfter(t))
+	if err != nil {
+		mc.fail(err)
+		return def, false
+	}
+	for {
+		err := mc.client.Add(item)
+		if err == nil {
+			return def, false
+		}
+		if err != memcache.ErrNotStored {
+			mc.fail(err)
+			return def, false
+		}
+		// the key may expire between ADD and GET, then try again
+		if value, ok := mc.Get(key); ok {
+			return value, true
+		}
+	}
+}
+
+// GetCtx only checks ctx before the read, the memcache client has no
+// context support
+func (mc *memcacheCache) GetCtx(ctx context.Context, key cache.Key) (cache.Value, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, ok := mc.Get(key)
+	return value, ok, nil
+}
+
+// PutCtx only checks ctx before the write, the memcache client has no
+// context support
+func (mc *memcacheCache) PutCtx(ctx context.Context, key cache.Key, value cache.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mc.Put(key, value)
+	return nil
+}
+
+// GetOrLoad shares the loads of the same key within this process only
+func (mc *memcacheCache) GetOrLoad(key cache.Key, load cache.LoadFunc) (cache.Value, error) {
+	return mc.GetOrLoadCtx(context.Background(), key, func(ctx context.Context, key cache.Key) (cache.Value, error) {
+		return load(key)
+	})
+}
+
+// GetOrLoadCtx is GetOrLoad with a context, a caller waiting for the load
+// of another one stops waiting when its ctx is done. A panic of load is
+// returned as a *cache.LoaderPanic
+func (mc *memcacheCache) GetOrLoadCtx(ctx context.Context, key cache.Key, load cache.LoadCtxFunc) (cache.Value, error) {
+	value, ok, err := mc.GetCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if value == cache.NotFound {
+			return nil, cache.ErrNotFound
+		}
+		return value, nil
+	}
+	return mc.loads.Do(ctx, mc.name(key), func() (cache.Value, error) {
+		value, err := load(ctx, key)
+		if err == nil {
+			mc.Put(key, value)
+		} else if errors.Is(err, cache.ErrNotFound) {
+			mc.PutNotFound(key)
+		}
+		return value, err
+	})
+}
+
+func (mc *memcacheCache) Del(key cache.Key) cache.Value {
+	value, _ := mc.DelE(key)
+	return value
+}
+
+// DelE reads the value before deleting it, a put in between is deleted
+// without its value being returned
+func (mc *memcacheCache) DelE(key cache.Key) (cache.Value, bool) {
+	_, value, _, ok := mc.fetch(key)
+	if err := mc.client.Delete(mc.name(key)); err != nil {
+		mc.fail(err)
+	}
+	return value, ok
+}
+
+// DelMulti deletes the keys one by one, it is not atomic
+func (mc *memcacheCache) DelMulti(keys []cache.Key) int {
+	n := 0
+	for _, key := range keys {
+		err := mc.client.Delete(mc.name(key))
+		if err == nil {
+			n++
+		}
+		mc.fail(err)
+	}
+	return n
+}
+
+// PutTagged puts the value like Put and drops the tags, memcached can
+// not list the keys put with a tag
+func (mc *memcacheCache) PutTagged(key cache.Key, value cache.Value, tags ...string) {
+	mc.Put(key, value)
+}
+
+// InvalidateTag deletes nothing for the same reason
+func (mc *memcacheCache) InvalidateTag(tag string) int { return 0 }
+
+// DelPrefix deletes nothing, memcached can not list its keys
+func (mc *memcacheCache) DelPrefix(prefix string) int { return 0 }
+
+// DelFunc deletes nothing for the same reason
+func (mc *memcacheCache) DelFunc(fn func(key cache.Key, value cache.Value) bool) int { return 0 }
+
+// Len is always 0, memcached can not count the keys of a prefix
+func (mc *memcacheCache) Len() int { return 0 }
+
+// Keys is always empty, memcached can not list the keys
+func (mc *memcacheCache) Keys() []cache.Key { return nil }
+
+// Stats counts the hits and misses of this process only
+func (mc *memcacheCache) Stats() cache.Stats {
+	return cache.Stats{
+		Hits:   atomic.LoadUint64(&mc.hits),
+		Misses: atomic.LoadUint64(&mc.misses),
+	}
+}
+
+// Range visits no entry, memcached can not list the keys
+func (mc *memcacheCache) Range(fn func(key cache.Key, value cache.Value) bool) {}
+
+func (mc *memcacheCache) SetBypass(bypass bool) {
+	var v int32
+	if bypass {
+		v = 1
+	}
+	atomic.StoreInt32(&mc.bypass, v)
+}
+
+// localCache is the in-process cache the entries are copied through, the
+// caches of NewCacheWithConfig implement the optional interfaces
+type localCache interface {
+	cache.Interface
+	cache.Warmer
+}
+
+// local returns an in-process cache without bounds, used to convert the
+// entries from the formats of the cache package
+func (mc *memcacheCache) local() localCache {
+	return cache.NewCacheWithConfig(cache.Config{MaxLen: math.MaxInt, CacheTime: mc.cacheTime}).(localCache)
+}
+
+// restore puts the live entries of an in-process cache
+func (mc *memcacheCache) restore(local cache.Interface) {
+	now := time.Now()
+	local.Range(func(key cache.Key, value cache.Value) bool {
+		left, ok := local.TTL(key)
+		switch {
+		case !ok:
+		case left == cache.NoExpiration:
+			mc.set(key, value, time.Time{})
+		default:
+			mc.set(key, value, now.Add(left))
+		}
+		return true
+	})
+	local.Close()
+}
+
+func (mc *memcacheCache) Warmup(keys []cache.Key, loader cache.Loader, parallelism int) error {
+	local := mc.local()
+	err := local.Warmup(keys, loader, parallelism)
+	mc.restore(local)
+	return err
+}
+
+// Flush has nothing to write, the puts reach memcached before they return
+func (mc *memcacheCache) Flush() {}
+
+// Purge does nothing, memcached can not list the keys of a prefix and
+// flush_all would clear the other caches sharing the servers
+func (mc *memcacheCache) Purge() {}
+
+// Close closes the client, and returns nil when called again. The
+// methods skipped in bypass mode are skipped once it is closed as well,
+// the others fail with the errors of the client
+func (mc *memcacheCache) Close() error {
+	if !atomic.CompareAndSwapInt32(&mc.closed, 0, 1) || mc.view {
+		return nil
+	}
+	return mc.client.Close()
+}
+
+// Clone returns an empty in-process cache, memcached can not list the
+// keys
+func (mc *memcacheCache) Clone() cache.Interface {
+	return mc.local()
+}
+
+// Merge puts the live entries of other, like the Merge of the cache
+// package, a key is read and written in separate commands
+func (mc *memcacheCache) Merge(other cache.Interface, conflict func(key cache.Key, a, b cache.Value) cache.Value) {
+	other.Range(func(key cache.Key, theirs cache.Value) bool {
+		left, ok := other.TTL(key)
+		if !ok {
+			return true
+		}
+		value := theirs
+		if mine, ok := mc.Peek(key); ok {
+			if conflict != nil {
+				value = conflict(key, mine, theirs)
+			}
+			if kept, ok := mc.TTL(key); ok {
+				left = cache.Fresher(left, kept)
+			}
+		}
+		mc.PutWithTimeout(key, value, left)
+		return true
+	})
+}
+
+// Namespace returns a view whose keys are prefixed with the name and a
+// colon after the prefix of the cache, so the keys of the cache itself
+// should not look like them. Closing the view leaves the client open
+func (mc *memcacheCache) Namespace(name string) cache.Interface {
+	return &memcacheCache{
+		client:            mc.client,
+		prefix:            mc.prefix + name + ":",
+		cacheTime:         mc.cacheTime,
+		negativeCacheTime: mc.negativeCacheTime,
+		codec:             mc.codec,
+		onError:           mc.onError,
+		equal:             mc.equal,
+		view:              true,
+		locks:             mc.locks,
+	}
+}
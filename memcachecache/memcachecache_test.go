@@ -0,0 +1,228 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memcachecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/memcachecache"
+)
+
+// fakeClient keeps the items in a map like a memcached server would
+type fakeClient struct {
+	items map[string]memcache.Item
+	cas   uint64
+	sync.Mutex
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{items: map[string]memcache.Item{}}
+}
+
+func (c *fakeClient) live(key string) (memcache.Item, bool) {
+	item, ok := c.items[key]
+	if ok && item.Expiration != 0 && int64(item.Expiration) <= time.Now().Unix() {
+		delete(c.items, key)
+		return item, false
+	}
+	return item, ok
+}
+
+func (c *fakeClient) Get(key string) (*memcache.Item, error) {
+	c.Lock()
+	defer c.Unlock()
+	item, ok := c.live(key)
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	return &item, nil
+}
+
+func (c *fakeClient) Set(item *memcache.Item) error {
+	c.Lock()
+	defer c.Unlock()
+	c.cas++
+	stored := *item
+	stored.CasID = c.cas
+	c.items[item.Key] = stored
+	return nil
+}
+
+func (c *fakeClient) Add(item *memcache.Item) error {
+	c.Lock()
+	if _, ok := c.live(item.Key); ok {
+		c.Unlock()
+		return memcache.ErrNotStored
+	}
+	c.Unlock()
+	return c.Set(item)
+}
+
+func (c *fakeClient) CompareAndSwap(item *memcache.Item) error {
+	c.Lock()
+	current, ok := c.live(item.Key)
+	c.Unlock()
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	if current.CasID != item.CasID {
+		return memcache.ErrCASConflict
+	}
+	return c.Set(item)
+}
+
+func (c *fakeClient) Delete(key string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.live(key); !ok {
+		return memcache.ErrCacheMiss
+	}
+	delete(c.items, key)
+	return nil
+}
+
+func (c *fakeClient) Close() error { return nil }
+
+func TestMemcacheCache(t *testing.T) {
+	client := newFakeClient()
+	onError := func(err error) { t.Fatalf("test memcached failed, got %v", err) }
+	cache := Wrap(memcachecache.New(memcachecache.Config{Client: client, Prefix: "test:", OnError: onError}))
+	defer cache.Close()
+
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", 2, time.Second)
+	cache.PutWithTimeout("testkey3", []byte("testvalue3"), NoExpiration)
+	cache.PutInt(4, "testvalue4")
+
+	tests := []struct {
+		key    Key
+		value  Value
+		exists bool
+	}{
+		{"testkey1", "testvalue1", true},
+		{"testkey2", 2, true},
+		{int64(4), "testvalue4", true},
+		{"testkey5", nil, false},
+	}
+	for _, test := range tests {
+		v, ok := cache.Get(test.key)
+		if ok != test.exists || v != test.value {
+			t.Fatalf("test key %v failed, expect %v, got %v", test.key, test.value, v)
+		}
+	}
+	if d, ok := cache.TTL("testkey3"); !ok || d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey3", NoExpiration, d)
+	}
+	if d, ok := cache.TTL("testkey1"); !ok || d > DefaultCacheTime || d < DefaultCacheTime-time.Second {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey1", DefaultCacheTime, d)
+	}
+
+	// the deadline is kept to the nanosecond, not to the second
+	if !cache.Touch("testkey2", 1500*time.Millisecond) {
+		t.Fatalf("test touch key %s failed, expect %v, got %v", "testkey2", true, false)
+	}
+	time.Sleep(1600 * time.Millisecond)
+	if cache.Contains("testkey2") || cache.Touch("testkey2", time.Second) {
+		t.Fatalf("test expired key %s failed, expect %v, got %v", "testkey2", false, true)
+	}
+
+	if v, loaded := cache.GetOrStore("testkey1", "testvalue6", time.Second); !loaded || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if v, loaded := cache.GetOrStore("testkey6", "testvalue6", time.Second); loaded || v != "testvalue6" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey6", "testvalue6", v)
+	}
+	if v, ok := cache.DelE("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test deleted key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if cache.Contains("testkey1") {
+		t.Fatalf("test deleted key %s failed, expect %v, got %v", "testkey1", false, true)
+	}
+	if stats := cache.Stats(); stats.Hits != 4 || stats.Misses != 1 {
+		t.Fatalf("test stats failed, got %+v", stats)
+	}
+	if _, ok := cache.(Persister); ok {
+		t.Fatalf("test persister failed, expect %v, got %v", false, true)
+	}
+
+	cache.PutWithTimeout("testkey11", 11, time.Minute)
+	if cache.CompareAndSwap("testkey11", 12, 13) || !cache.CompareAndSwap("testkey11", 11, 12) {
+		t.Fatalf("test swap key %s failed, expect %v, got %v", "testkey11", 12, 11)
+	}
+	if v, _ := cache.Get("testkey11"); v != 12 {
+		t.Fatalf("test swapped key %s failed, expect %v, got %v", "testkey11", 12, v)
+	}
+	if d, ok := cache.TTL("testkey11"); !ok || d <= 0 || d > time.Minute {
+		t.Fatalf("test swapped key %s ttl failed, expect %v, got %v", "testkey11", time.Minute, d)
+	}
+
+	if !cache.Add("testkey12", 12) || cache.Add("testkey12", 13) {
+		t.Fatalf("test add key %s failed, expect %v, got %v", "testkey12", 12, 13)
+	}
+	if v, _ := cache.Get("testkey12"); v != 12 {
+		t.Fatalf("test added key %s failed, expect %v, got %v", "testkey12", 12, v)
+	}
+
+	if cache.Replace("testkey13", 13) || !cache.Replace("testkey12", 13) {
+		t.Fatalf("test replace key %s failed, expect %v, got %v", "testkey12", 13, 12)
+	}
+	if v, _ := cache.Get("testkey12"); v != 13 {
+		t.Fatalf("test replaced key %s failed, expect %v, got %v", "testkey12", 13, v)
+	}
+	if cache.Contains("testkey13") {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey13", false, true)
+	}
+
+	if n, err := cache.IncrementInt64("testkey14", 14); n != 14 || err != nil {
+		t.Fatalf("test increment key %s failed, expect %v, got %v %v", "testkey14", 14, n, err)
+	}
+	if n, err := cache.DecrementInt64("testkey14", 4); n != 10 || err != nil {
+		t.Fatalf("test decrement key %s failed, expect %v, got %v %v", "testkey14", 10, n, err)
+	}
+	cache.Put("testkey15", "testvalue15")
+	if _, err := cache.IncrementInt64("testkey15", 1); err != ErrNotInteger {
+		t.Fatalf("test increment key %s failed, expect %v, got %v", "testkey15", ErrNotInteger, err)
+	}
+}
+
+func TestMemcacheCacheGetOrLoadPanic(t *testing.T) {
+	onError := func(err error) { t.Fatalf("test memcached failed, got %v", err) }
+	cache := memcachecache.New(memcachecache.Config{Client: newFakeClient(), Prefix: "test:", OnError: onError})
+	defer cache.Close()
+
+	var p *LoaderPanic
+	_, err := cache.GetOrLoad("testkey1", func(key Key) (Value, error) {
+		panic("testpanic1")
+	})
+	if !errors.As(err, &p) || p.Value != "testpanic1" {
+		t.Fatalf("test key %s panic failed, expect %v, got %v", "testkey1", "testpanic1", err)
+	}
+	// the key is not stuck once its loader panicked
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if v, err := cache.GetOrLoadCtx(ctx, "testkey1", func(ctx context.Context, key Key) (Value, error) {
+		return "testvalue1", nil
+	}); err != nil || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v/%v", "testkey1", "testvalue1", v, err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"reflect"
+)
+
+// entryOverhead is the memory the cache holds for an entry besides its
+// key and value: the list element, the listEntry and the index slot
+var entryOverhead = int64(reflect.TypeOf(list.Element{}).Size() +
+	reflect.TypeOf(listEntry{}).Size() +
+	reflect.TypeOf((*Key)(nil)).Elem().Size() + reflect.TypeOf((*list.Element)(nil)).Size())
+
+// MemoryWeigher weighs the entries by their approximate memory in bytes,
+// with SizeOf
+func MemoryWeigher(key Key, value Value) int64 {
+	return SizeOf(key) + SizeOf(value) + entryOverhead
+}
+
+// SizeOf estimates the memory held by v in bytes, following the pointers,
+// slices, maps, strings and interfaces it contains. The memory reachable
+// twice from v is counted once, the channels and funcs count as pointers
+func SizeOf(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	return int64(rv.Type().Size()) + sizeOfContent(rv, map[uintptr]bool{})
+}
+
+// sizeOfContent returns the memory v points to, its own size aside
+func sizeOfContent(v reflect.Value, seen map[uintptr]bool) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		return int64(v.Elem().Type().Size()) + sizeOfContent(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return int64(v.Elem().Type().Size()) + sizeOfContent(v.Elem(), seen)
+	case reflect.Slice:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if !hasPointers(v.Type().Elem()) {
+			return n
+		}
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOfContent(v.Index(i), seen)
+		}
+		return n
+	case reflect.Array:
+		if !hasPointers(v.Type().Elem()) {
+			return 0
+		}
+		var n int64
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOfContent(v.Index(i), seen)
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += sizeOfContent(v.Field(i), seen)
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		n := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size())
+		if !hasPointers(v.Type().Key()) && !hasPointers(v.Type().Elem()) {
+			return n
+		}
+		for iter := v.MapRange(); iter.Next(); {
+			n += sizeOfContent(iter.Key(), seen) + sizeOfContent(iter.Value(), seen)
+		}
+		return n
+	}
+	return 0
+}
+
+// hasPointers tells whether the values of t may point to more memory, the
+// slices and maps of the types without are sized without visiting them
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestSizeOf(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	loop := &node{Name: "abcd"}
+	loop.Next = loop
+	tests := []struct {
+		name   string
+		value  interface{}
+		expect int64
+	}{
+		{"nil", nil, 0},
+		{"int64", int64(1), 8},
+		{"string", "abcd", 16 + 4},
+		{"bytes", make([]byte, 4, 8), 24 + 8},
+		{"strings", []string{"ab", "cd"}, 24 + 2*16 + 4},
+		{"struct", node{Name: "abcd"}, 24 + 4},
+		{"cycle", loop, 8 + 24 + 4},
+		{"map", map[string]int64{"ab": 1}, 8 + 24 + 2},
+		{"large bytes", make([]byte, 1<<20), 24 + 1<<20},
+		{"arrays", [][2]int32{{1, 2}}, 24 + 8},
+	}
+	for _, test := range tests {
+		if got := SizeOf(test.value); got != test.expect {
+			t.Fatalf("test key %s sizeof failed, expect %v, got %v", test.name, test.expect, got)
+		}
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	value := strings.Repeat("a", 1000)
+	entry := MemoryWeigher("testkey1", value)
+	cache := NewCacheWithConfig(Config{MaxBytes: 3 * entry})
+
+	cache.Put("testkey1", value)
+	if cache.(Resizer).Weight() != entry {
+		t.Fatalf("test weight failed, expect %v, got %v", entry, cache.(Resizer).Weight())
+	}
+
+	cache.Put("testkey2", value)
+	cache.Put("testkey3", value)
+	cache.Put("testkey4", value)
+	if cache.Len() != 3 || cache.Contains("testkey1") {
+		t.Fatalf("test evicted failed, expect %v keys without testkey1, got %v", 3, cache.Keys())
+	}
+
+	// a larger value makes room by evicting more entries
+	cache.Put("testkey5", strings.Repeat("b", 2000))
+	if cache.Len() != 2 || cache.(Resizer).Weight() > 3*entry {
+		t.Fatalf("test large value failed, expect %v keys within %v, got %v weighing %v", 2, 3*entry, cache.Keys(), cache.(Resizer).Weight())
+	}
+
+	// the compressed values are charged for their compressed bytes
+	compressed := NewCacheWithConfig(Config{MaxBytes: 3 * entry, CompressThreshold: 100})
+	compressed.Put("testkey1", []byte(value))
+	if compressed.(Resizer).Weight() >= MemoryWeigher("testkey1", []byte(value)) {
+		t.Fatalf("test compressed weight failed, expect below %v, got %v", MemoryWeigher("testkey1", []byte(value)), compressed.(Resizer).Weight())
+	}
+
+	sharded := NewCacheWithConfig(Config{MaxBytes: 4 * entry, Shards: 2})
+	for _, key := range []string{"testkey1", "testkey2", "testkey3", "testkey4", "testkey5", "testkey6"} {
+		sharded.Put(key, value)
+	}
+	if sharded.(Resizer).Weight() > 4*entry {
+		t.Fatalf("test sharded weight failed, expect within %v, got %v", 4*entry, sharded.(Resizer).Weight())
+	}
+}
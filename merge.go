@@ -0,0 +1,72 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "time"
+
+// Merge puts the live entries of other into the cache. The value of a key
+// found in both is conflict(key, mine, theirs), theirs if conflict is nil,
+// and it keeps the later of the two deadlines. A key is read and written
+// in two steps, a concurrent write of the key in between is overwritten
+func (lru *lruCache) Merge(other Interface, conflict func(key Key, a, b Value) Value) {
+	merge(lru, other, conflict)
+}
+
+func (s *shardedCache) Merge(other Interface, conflict func(key Key, a, b Value) Value) {
+	merge(s, other, conflict)
+}
+
+func (n *namespacedCache) Merge(other Interface, conflict func(key Key, a, b Value) Value) {
+	merge(n, other, conflict)
+}
+
+// Merge merges other into both tiers
+func (t *tieredCache) Merge(other Interface, conflict func(key Key, a, b Value) Value) {
+	merge(t, other, conflict)
+}
+
+func merge(c, other Interface, conflict func(key Key, a, b Value) Value) {
+	other.Range(func(key Key, theirs Value) bool {
+		left, ok := other.TTL(key)
+		if !ok {
+			return true
+		}
+		value := theirs
+		if mine, ok := c.Peek(key); ok {
+			if conflict != nil {
+				value = conflict(key, mine, theirs)
+			}
+			if kept, ok := c.TTL(key); ok {
+				left = Fresher(left, kept)
+			}
+		}
+		c.PutWithTimeout(key, value, left)
+		return true
+	})
+}
+
+// Fresher returns the longer of two times left, NoExpiration being the
+// longest
+func Fresher(a, b time.Duration) time.Duration {
+	if a == NoExpiration || b == NoExpiration {
+		return NoExpiration
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
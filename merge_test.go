@@ -0,0 +1,68 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheMerge(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		clock := NewManualClock(time.Unix(0, 0))
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, Clock: clock})
+		other := NewCacheWithConfig(Config{MaxLen: 10, Clock: clock})
+		cache.PutWithTimeout("testkey1", "testvalue1", time.Minute)
+		cache.PutWithTimeout("testkey2", "testvalue2", time.Hour)
+		cache.PutWithTimeout("testkey3", "testvalue3", 3*time.Hour)
+		other.PutWithTimeout("testkey2", "othervalue2", 2*time.Hour)
+		other.PutWithTimeout("testkey3", "othervalue3", time.Hour)
+		other.PutWithTimeout("testkey4", "othervalue4", NoExpiration)
+
+		cache.Merge(other, func(key Key, a, b Value) Value { return a.(string) + "+" + b.(string) })
+		tests := []struct {
+			key   Key
+			value Value
+			ttl   time.Duration
+		}{
+			{"testkey1", "testvalue1", time.Minute},
+			{"testkey2", "testvalue2+othervalue2", 2 * time.Hour},
+			{"testkey3", "testvalue3+othervalue3", 3 * time.Hour},
+			{"testkey4", "othervalue4", NoExpiration},
+		}
+		for _, test := range tests {
+			if v, _ := cache.Get(test.key); v != test.value {
+				t.Fatalf("test shards %d merged key %s failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+			if left, _ := cache.TTL(test.key); left != test.ttl {
+				t.Fatalf("test shards %d merged key %s ttl failed, expect %v, got %v", shards, test.key, test.ttl, left)
+			}
+		}
+
+		cache.Merge(other, nil)
+		if v, _ := cache.Get("testkey2"); v != "othervalue2" {
+			t.Fatalf("test shards %d merged key %s failed, expect %v, got %v", shards, "testkey2", "othervalue2", v)
+		}
+		if v, _ := other.Get("testkey1"); v != nil {
+			t.Fatalf("test shards %d other key %s failed, expect %v, got %v", shards, "testkey1", nil, v)
+		}
+		cache.Close()
+		other.Close()
+	}
+}
@@ -0,0 +1,472 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	gob.Register(NamespacedKey{})
+}
+
+// NamespacedKey is the key the entries of a namespace are stored with in
+// the underlying cache, the callbacks and listeners of the cache see it.
+// Parents are the names of the namespaces Namespace is nested in, each
+// prefixed with its length and a colon, so that no two paths collide
+type NamespacedKey struct {
+	Namespace string
+	Key       Key
+	Parents   string
+}
+
+// namespacedCache is a view of the entries of one namespace of a cache,
+// Stats counts the keys of the namespace but the other counters are those
+// of the underlying cache. SetBypass and Close do nothing, the owner of
+// the underlying cache is in charge of it
+type namespacedCache struct {
+	c       fullCache
+	name    string
+	parents string
+}
+
+// Namespace returns a view of the cache whose keys are isolated from the
+// keys of the other namespaces and of the cache itself, while its entries
+// share the capacity of the cache. Len, Keys, Range and the methods built
+// on them scan the whole cache
+func (lru *lruCache) Namespace(name string) Interface {
+	return &namespacedCache{c: lru, name: name}
+}
+
+func (s *shardedCache) Namespace(name string) Interface {
+	return &namespacedCache{c: s, name: name}
+}
+
+// Namespace of a namespace is a namespace of the underlying cache with n
+// as its parent, so its keys are not listed by n
+func (n *namespacedCache) Namespace(name string) Interface {
+	return &namespacedCache{c: n.c, name: name, parents: n.path()}
+}
+
+// Clone returns the namespace of a clone of the whole underlying cache
+func (n *namespacedCache) Clone() Interface {
+	return &namespacedCache{c: n.c.Clone().(fullCache), name: n.name, parents: n.parents}
+}
+
+// path is the parents of the namespace followed by its own name, in the
+// encoding of NamespacedKey.Parents
+func (n *namespacedCache) path() string {
+	return n.parents + strconv.Itoa(len(n.name)) + ":" + n.name
+}
+
+func (n *namespacedCache) key(key Key) Key {
+	return NamespacedKey{Namespace: n.name, Key: key, Parents: n.parents}
+}
+
+// own returns the key within the namespace of a key of the underlying
+// cache, false if it belongs to another namespace
+func (n *namespacedCache) own(key Key) (Key, bool) {
+	nk, ok := key.(NamespacedKey)
+	if !ok || nk.Namespace != n.name || nk.Parents != n.parents {
+		return nil, false
+	}
+	return nk.Key, true
+}
+
+func (n *namespacedCache) keys(keys []Key) []Key {
+	mapped := make([]Key, len(keys))
+	for i, key := range keys {
+		mapped[i] = n.key(key)
+	}
+	return mapped
+}
+
+func (n *namespacedCache) entries(entries map[Key]Value) map[Key]Value {
+	mapped := make(map[Key]Value, len(entries))
+	for key, value := range entries {
+		mapped[n.key(key)] = value
+	}
+	return mapped
+}
+
+func (n *namespacedCache) Put(key Key, value Value) {
+	n.c.Put(n.key(key), value)
+}
+
+func (n *namespacedCache) PutWithTimeout(key Key, value Value, t time.Duration) {
+	n.c.PutWithTimeout(n.key(key), value, t)
+}
+
+func (n *namespacedCache) PutWithDeadline(key Key, value Value, deadline time.Time) {
+	n.c.PutWithDeadline(n.key(key), value, deadline)
+}
+
+func (n *namespacedCache) Get(key Key) (Value, bool) {
+	return n.c.Get(n.key(key))
+}
+
+func (n *namespacedCache) Peek(key Key) (Value, bool) {
+	return n.c.Peek(n.key(key))
+}
+
+func (n *namespacedCache) GetEntry(key Key) (Entry, bool) {
+	entry, ok := n.c.GetEntry(n.key(key))
+	if !ok {
+		return Entry{}, false
+	}
+	entry.Key = key
+	return entry, true
+}
+
+func (n *namespacedCache) Contains(key Key) bool {
+	return n.c.Contains(n.key(key))
+}
+
+func (n *namespacedCache) TTL(key Key) (time.Duration, bool) {
+	return n.c.TTL(n.key(key))
+}
+
+func (n *namespacedCache) Touch(key Key, d time.Duration) bool {
+	return n.c.Touch(n.key(key), d)
+}
+
+func (n *namespacedCache) GetOrStore(key Key, def Value, t time.Duration) (Value, bool) {
+	return n.c.GetOrStore(n.key(key), def, t)
+}
+
+func (n *namespacedCache) GetOrLoad(key Key, load LoadFunc) (Value, error) {
+	return n.c.GetOrLoad(n.key(key), func(Key) (Value, error) { return load(key) })
+}
+
+func (n *namespacedCache) LockKey(key Key) func() {
+	return n.c.LockKey(n.key(key))
+}
+
+func (n *namespacedCache) GetCtx(ctx context.Context, key Key) (Value, bool, error) {
+	return n.c.GetCtx(ctx, n.key(key))
+}
+
+func (n *namespacedCache) PutCtx(ctx context.Context, key Key, value Value) error {
+	return n.c.PutCtx(ctx, n.key(key), value)
+}
+
+func (n *namespacedCache) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	return n.c.GetOrLoadCtx(ctx, n.key(key), func(ctx context.Context, _ Key) (Value, error) { return load(ctx, key) })
+}
+
+func (n *namespacedCache) Add(key Key, value Value) bool {
+	return n.c.Add(n.key(key), value)
+}
+
+func (n *namespacedCache) Replace(key Key, value Value) bool {
+	return n.c.Replace(n.key(key), value)
+}
+
+func (n *namespacedCache) CompareAndSwap(key Key, old, new Value) bool {
+	return n.c.CompareAndSwap(n.key(key), old, new)
+}
+
+func (n *namespacedCache) IncrementInt64(key Key, delta int64) (int64, error) {
+	return n.c.IncrementInt64(n.key(key), delta)
+}
+
+func (n *namespacedCache) DecrementInt64(key Key, delta int64) (int64, error) {
+	return n.c.DecrementInt64(n.key(key), delta)
+}
+
+func (n *namespacedCache) GetAllowStale(key Key, maxStale time.Duration) (Value, bool, bool) {
+	return n.c.GetAllowStale(n.key(key), maxStale)
+}
+
+// PutString and the other typed methods store the key like Put does, the
+// typed index of the underlying cache is not used
+func (n *namespacedCache) PutString(key string, value Value) {
+	n.c.Put(n.key(key), value)
+}
+
+func (n *namespacedCache) GetString(key string) (Value, bool) {
+	return n.c.Get(n.key(key))
+}
+
+func (n *namespacedCache) PutInt(key int64, value Value) {
+	n.c.Put(n.key(key), value)
+}
+
+func (n *namespacedCache) GetInt(key int64) (Value, bool) {
+	return n.c.Get(n.key(key))
+}
+
+func (n *namespacedCache) PutMulti(entries map[Key]Value) {
+	n.c.PutMulti(n.entries(entries))
+}
+
+func (n *namespacedCache) PutMultiWithTimeout(entries map[Key]Value, t time.Duration) {
+	n.c.PutMultiWithTimeout(n.entries(entries), t)
+}
+
+func (n *namespacedCache) PutNotFound(key Key) {
+	n.c.PutNotFound(n.key(key))
+}
+
+func (n *namespacedCache) GetMulti(keys []Key) map[Key]Value {
+	values := make(map[Key]Value, len(keys))
+	for key, value := range n.c.GetMulti(n.keys(keys)) {
+		key, _ := n.own(key)
+		values[key] = value
+	}
+	return values
+}
+
+func (n *namespacedCache) Del(key Key) Value {
+	return n.c.Del(n.key(key))
+}
+
+func (n *namespacedCache) DelE(key Key) (Value, bool) {
+	return n.c.DelE(n.key(key))
+}
+
+func (n *namespacedCache) DelMulti(keys []Key) int {
+	return n.c.DelMulti(n.keys(keys))
+}
+
+// DelPrefix deletes the string keys of the namespace starting with
+// prefix, it scans the whole cache
+func (n *namespacedCache) DelPrefix(prefix string) int {
+	var keys []Key
+	for _, key := range n.Keys() {
+		if s, ok := key.(string); ok && strings.HasPrefix(s, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return n.DelMulti(keys)
+}
+
+func (n *namespacedCache) DelFunc(fn func(key Key, value Value) bool) int {
+	return n.c.DelFunc(func(key Key, value Value) bool {
+		key, ok := n.own(key)
+		return ok && fn(key, value)
+	})
+}
+
+// PutTagged tags the entry with the tags of the namespace, which are
+// distinct from the tags of the other namespaces
+func (n *namespacedCache) PutTagged(key Key, value Value, tags ...string) {
+	mapped := make([]string, len(tags))
+	for i, tag := range tags {
+		mapped[i] = n.tag(tag)
+	}
+	n.c.PutTagged(n.key(key), value, mapped...)
+}
+
+func (n *namespacedCache) InvalidateTag(tag string) int {
+	return n.c.InvalidateTag(n.tag(tag))
+}
+
+// tag prefixes the tag with the path of the namespace and a '#', which
+// can not start the length of a nested name, so the tags of two
+// namespaces never collide
+func (n *namespacedCache) tag(tag string) string {
+	return n.path() + "#" + tag
+}
+
+// RemoveOldest deletes the oldest entry of the namespace, the callbacks
+// are fired with ReasonDeleted
+func (n *namespacedCache) RemoveOldest() (Key, Value, bool) {
+	for {
+		entry, ok := n.GetOldest()
+		if !ok {
+			return nil, nil, false
+		}
+		if value, ok := n.DelE(entry.Key); ok {
+			return entry.Key, value, true
+		}
+	}
+}
+
+// GetOldest scans the whole cache
+func (n *namespacedCache) GetOldest() (Entry, bool) {
+	var oldest Key
+	found := false
+	n.Range(func(key Key, value Value) bool {
+		oldest, found = key, true
+		return true
+	})
+	if !found {
+		return Entry{}, false
+	}
+	return n.GetEntry(oldest)
+}
+
+func (n *namespacedCache) GetNewest() (Entry, bool) {
+	var newest Key
+	found := false
+	n.Range(func(key Key, value Value) bool {
+		newest, found = key, true
+		return false
+	})
+	if !found {
+		return Entry{}, false
+	}
+	return n.GetEntry(newest)
+}
+
+func (n *namespacedCache) Len() int {
+	return len(n.Keys())
+}
+
+func (n *namespacedCache) Keys() []Key {
+	var keys []Key
+	for _, key := range n.c.Keys() {
+		if key, ok := n.own(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Snapshot filters the snapshot of the whole cache
+func (n *namespacedCache) Snapshot() []Entry {
+	var entries []Entry
+	for _, entry := range n.c.Snapshot() {
+		if key, ok := n.own(entry.Key); ok {
+			entry.Key = key
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Stats returns the Len of the namespace, the other counters are those of
+// the whole cache, which the namespaces share
+func (n *namespacedCache) Stats() Stats {
+	stats := n.c.Stats()
+	stats.Len = n.Len()
+	return stats
+}
+
+func (n *namespacedCache) Range(fn func(key Key, value Value) bool) {
+	n.RangeWithOptions(fn, RangeOptions{Mode: RangeSnapshot})
+}
+
+func (n *namespacedCache) RangeWithOptions(fn func(key Key, value Value) bool, opts RangeOptions) {
+	n.c.RangeWithOptions(func(key Key, value Value) bool {
+		if key, ok := n.own(key); ok {
+			return fn(key, value)
+		}
+		return true
+	}, opts)
+}
+
+func (n *namespacedCache) SetBypass(bypass bool) {}
+
+// ReplayWAL replays the log into a cache of its own, then copies the
+// entries to the namespace
+func (n *namespacedCache) ReplayWAL(r io.Reader) error {
+	local := n.newLocalCache()
+	defer local.Close()
+	if err := local.ReplayWAL(r); err != nil {
+		return err
+	}
+	n.restore(local)
+	return nil
+}
+
+// SaveTo writes the entries of the namespace with their own keys
+func (n *namespacedCache) SaveTo(w io.Writer) error {
+	local := n.snapshot()
+	defer local.Close()
+	return local.SaveTo(w)
+}
+
+func (n *namespacedCache) ExportJSON(w io.Writer, codec JSONCodec) error {
+	local := n.snapshot()
+	defer local.Close()
+	return local.ExportJSON(w, codec)
+}
+
+func (n *namespacedCache) ImportJSON(r io.Reader, codec JSONCodec) error {
+	local := n.newLocalCache()
+	defer local.Close()
+	if err := local.ImportJSON(r, codec); err != nil {
+		return err
+	}
+	n.restore(local)
+	return nil
+}
+
+// snapshot copies the live entries of the namespace to a cache of its own
+func (n *namespacedCache) snapshot() *lruCache {
+	local := n.newLocalCache()
+	putEntries(local, n.Snapshot())
+	return local
+}
+
+// newLocalCache is an unbounded cache on the clock of the underlying
+// cache, to read or write the entries of the namespace
+func (n *namespacedCache) newLocalCache() *lruCache {
+	return NewCacheWithConfig(Config{MaxLen: unboundedLen, Clock: n.c.cacheClock()}).(*lruCache)
+}
+
+// restore puts the live entries of a cache in the namespace
+func (n *namespacedCache) restore(local *lruCache) {
+	putEntries(n, local.Snapshot())
+}
+
+// putEntries puts the entries with their deadlines, from the last one so
+// that the first one ends up the most recently used
+func putEntries(c Interface, entries []Entry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Deadline.IsZero() {
+			c.PutWithTimeout(entries[i].Key, entries[i].Value, NoExpiration)
+		} else {
+			c.PutWithDeadline(entries[i].Key, entries[i].Value, entries[i].Deadline)
+		}
+	}
+}
+
+func (n *namespacedCache) Warmup(keys []Key, loader Loader, parallelism int) error {
+	err := n.c.Warmup(n.keys(keys), func(key Key) (Value, time.Duration, error) {
+		key, _ = n.own(key)
+		return loader(key)
+	}, parallelism)
+	if werr, ok := err.(*WarmupError); ok {
+		errs := make(map[Key]error, len(werr.Errors))
+		for key, err := range werr.Errors {
+			key, _ := n.own(key)
+			errs[key] = err
+		}
+		return &WarmupError{Errors: errs}
+	}
+	return err
+}
+
+func (n *namespacedCache) Flush() {
+	n.c.Flush()
+}
+
+// Purge deletes the entries of the namespace, the callbacks are fired
+// with ReasonDeleted
+func (n *namespacedCache) Purge() {
+	n.c.DelMulti(n.keys(n.Keys()))
+}
+
+func (n *namespacedCache) Close() error { return nil }
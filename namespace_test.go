@@ -0,0 +1,183 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestNamespace(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards})
+		users := cache.Namespace("users")
+		posts := cache.Namespace("posts")
+		cache.Put("testkey1", "testvalue1")
+		users.Put("testkey1", "user1")
+		users.Put("testkey2", "user2")
+		posts.Put("testkey1", "post1")
+
+		tests := []struct {
+			cache Interface
+			key   Key
+			value Value
+		}{
+			{cache, "testkey1", "testvalue1"},
+			{cache, "testkey2", nil},
+			{users, "testkey1", "user1"},
+			{users, "testkey2", "user2"},
+			{posts, "testkey1", "post1"},
+			{posts, "testkey2", nil},
+		}
+		for _, test := range tests {
+			if v, _ := test.cache.Get(test.key); v != test.value {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+		}
+		if n := cache.Len(); n != 4 {
+			t.Fatalf("test %d shards len failed, expect %v, got %v", shards, 4, n)
+		}
+		if n := users.Len(); n != 2 {
+			t.Fatalf("test %d shards namespace %s len failed, expect %v, got %v", shards, "users", 2, n)
+		}
+		keys := users.Keys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].(string) < keys[j].(string) })
+		if len(keys) != 2 || keys[0] != "testkey1" || keys[1] != "testkey2" {
+			t.Fatalf("test %d shards namespace %s keys failed, got %v", shards, "users", keys)
+		}
+
+		users.Purge()
+		if n := users.Len(); n != 0 {
+			t.Fatalf("test %d shards namespace %s len failed, expect %v, got %v", shards, "users", 0, n)
+		}
+		if v, _ := posts.Get("testkey1"); v != "post1" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", "post1", v)
+		}
+		if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", "testvalue1", v)
+		}
+
+		nested := posts.Namespace("drafts")
+		nested.Put("testkey1", "draft1")
+		if v, _ := posts.Get("testkey1"); v != "post1" {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", "post1", v)
+		}
+		if n := posts.Len(); n != 1 {
+			t.Fatalf("test %d shards namespace %s len failed, expect %v, got %v", shards, "posts", 1, n)
+		}
+		cache.Close()
+	}
+}
+
+func TestNamespaceCapacity(t *testing.T) {
+	var evicted []Key
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}})
+	defer cache.Close()
+	users := cache.Namespace("users")
+	posts := cache.Namespace("posts")
+	users.Put("testkey1", "user1")
+	posts.Put("testkey1", "post1")
+	users.Put("testkey2", "user2")
+	posts.Put("testkey2", "post2")
+
+	if len(evicted) != 1 || evicted[0] != (NamespacedKey{Namespace: "users", Key: "testkey1"}) {
+		t.Fatalf("test evictions failed, got %v", evicted)
+	}
+	if e, ok := posts.(Evicter).GetOldest(); !ok || e.Key != "testkey1" || e.Value != "post1" {
+		t.Fatalf("test namespace %s oldest failed, got %v %v", "posts", e, ok)
+	}
+	if e, ok := posts.(Evicter).GetNewest(); !ok || e.Key != "testkey2" || e.Value != "post2" {
+		t.Fatalf("test namespace %s newest failed, got %v %v", "posts", e, ok)
+	}
+	if key, value, ok := users.(Evicter).RemoveOldest(); !ok || key != "testkey2" || value != "user2" {
+		t.Fatalf("test namespace %s remove oldest failed, got %v %v %v", "users", key, value, ok)
+	}
+	if _, _, ok := users.(Evicter).RemoveOldest(); ok {
+		t.Fatalf("test namespace %s remove oldest failed, expect %v, got %v", "users", false, ok)
+	}
+}
+
+func TestNamespaceSaveTo(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	users := cache.Namespace("users")
+	users.PutWithTimeout("testkey1", "user1", NoExpiration)
+	cache.PutWithTimeout("testkey2", "testvalue2", NoExpiration)
+
+	var buf bytes.Buffer
+	if err := users.(Persister).SaveTo(&buf); err != nil {
+		t.Fatalf("test save failed, got %v", err)
+	}
+	posts := cache.Namespace("posts")
+	if err := posts.(Persister).ReplayWAL(&buf); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	if v, _ := posts.Get("testkey1"); v != "user1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "user1", v)
+	}
+	if _, ok := posts.Get("testkey2"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", false, ok)
+	}
+}
+
+func TestNamespaceCollisions(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewCacheWithConfig(Config{MaxLen: 100, Clock: clock})
+	defer cache.Close()
+	nested := cache.Namespace("a").Namespace("b")
+	joined := cache.Namespace("a:b")
+
+	nested.Put("testkey1", "nested1")
+	joined.Put("testkey1", "joined1")
+	if v, _ := nested.Get("testkey1"); v != "nested1" {
+		t.Fatalf("test nested key %s failed, expect %v, got %v", "testkey1", "nested1", v)
+	}
+	if v, _ := joined.Get("testkey1"); v != "joined1" {
+		t.Fatalf("test joined key %s failed, expect %v, got %v", "testkey1", "joined1", v)
+	}
+
+	cache.Namespace("a").PutTagged("testkey2", "testvalue2", "b:c")
+	cache.Namespace("a:b").PutTagged("testkey3", "testvalue3", "c")
+	if n := cache.Namespace("a:b").InvalidateTag("c"); n != 1 || !cache.Namespace("a").Contains("testkey2") {
+		t.Fatalf("test tag %s failed, expect %v, got %v", "c", 1, n)
+	}
+
+	// the copies keep the deadlines of the clock of the cache, even below
+	// a second
+	users := cache.Namespace("users")
+	users.PutWithDeadline("testkey4", "user4", clock.Now().Add(500*time.Millisecond))
+	var buf bytes.Buffer
+	if err := users.(Persister).SaveTo(&buf); err != nil {
+		t.Fatalf("test save failed, got %v", err)
+	}
+	posts := cache.Namespace("posts")
+	if err := posts.(Persister).ReplayWAL(&buf); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	if d, ok := posts.TTL("testkey4"); !ok || d != 500*time.Millisecond {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey4", 500*time.Millisecond, d)
+	}
+	if e, ok := users.(Evicter).GetOldest(); !ok || !e.Deadline.Equal(clock.Now().Add(500*time.Millisecond)) {
+		t.Fatalf("test oldest deadline failed, expect %v, got %v", clock.Now().Add(500*time.Millisecond), e.Deadline)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"time"
+)
+
+func init() {
+	gob.Register(NotFound)
+}
+
+// notFound is the type of NotFound, a named integer so that gob can encode
+// it like the other values
+type notFound uint8
+
+// NotFound is the value of the negative entries, which record that a key
+// does not exist in the backing database. Get returns it like any value,
+// GetOrLoad turns it into ErrNotFound
+const NotFound notFound = 0
+
+// ErrNotFound is returned by a LoadFunc or Loader when the key does not
+// exist, GetOrLoad and the reloads then cache NotFound for
+// NegativeCacheTime instead of failing
+var ErrNotFound = errors.New("cache: key not found")
+
+// PutNotFound puts a negative entry for the key, it expires after
+// NegativeCacheTime
+func (lru *lruCache) PutNotFound(key Key) {
+	replaced := lru.debouncedPut(key, NotFound, lru.negativeCacheTime)
+	lru.audit("PutNotFound", key, replaced)
+}
+
+func (s *shardedCache) PutNotFound(key Key) {
+	s.shard(key).PutNotFound(key)
+}
+
+// putLoaded caches the result of a load, the value with the timeout t or
+// NotFound for ErrNotFound, and reports whether it was cached
+func (lru *lruCache) putLoaded(key Key, value Value, t time.Duration, err error) bool {
+	switch {
+	case err == nil:
+		lru.put(key, value, t)
+	case errors.Is(err, ErrNotFound):
+		lru.put(key, NotFound, lru.negativeCacheTime)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestNegativeCache(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, CacheTime: time.Minute, NegativeCacheTime: 5 * time.Second})
+		loads := 0
+		load := func(key Key) (Value, error) {
+			loads++
+			return nil, fmt.Errorf("loading %v: %w", key, ErrNotFound)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := cache.GetOrLoad("testkey1", load); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v", shards, "testkey1", ErrNotFound, err)
+			}
+		}
+		if loads != 1 {
+			t.Fatalf("test %d shards key %s loads failed, expect %v, got %v", shards, "testkey1", 1, loads)
+		}
+		if v, ok := cache.Get("testkey1"); !ok || v != NotFound {
+			t.Fatalf("test %d shards key %s failed, expect %v, got %v %v", shards, "testkey1", NotFound, v, ok)
+		}
+		if left, _ := cache.TTL("testkey1"); left > 5*time.Second || left < 4*time.Second {
+			t.Fatalf("test %d shards key %s ttl failed, expect %v, got %v", shards, "testkey1", 5*time.Second, left)
+		}
+
+		cache.PutNotFound("testkey2")
+		cache.Put("testkey3", nil)
+		tests := []struct {
+			key   Key
+			value Value
+		}{
+			{"testkey2", NotFound},
+			{"testkey3", nil},
+		}
+		for _, test := range tests {
+			if v, ok := cache.Get(test.key); !ok || v != test.value {
+				t.Fatalf("test %d shards key %s failed, expect %v, got %v %v", shards, test.key, test.value, v, ok)
+			}
+		}
+		cache.Close()
+	}
+}
+
+func TestNegativeCacheSaveTo(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 10})
+	defer cache.Close()
+	cache.PutNotFound("testkey1")
+	var buf bytes.Buffer
+	if err := cache.(Persister).SaveTo(&buf); err != nil {
+		t.Fatalf("test save failed, got %v", err)
+	}
+	restored := NewCacheWithConfig(Config{MaxLen: 10})
+	defer restored.Close()
+	if err := restored.(Persister).ReplayWAL(&buf); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	if v, ok := restored.Get("testkey1"); !ok || v != NotFound {
+		t.Fatalf("test key %s failed, expect %v, got %v %v", "testkey1", NotFound, v, ok)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the cache the time, the deadlines of the entries are
+// set and checked against it
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// ManualClock is a Clock which only moves when told to, so that the tests
+// can expire the entries without sleeping
+type ManualClock struct {
+	now time.Time
+	sync.Mutex
+}
+
+// NewManualClock returns a ManualClock set to now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.Lock()
+	c.now = c.now.Add(d)
+	c.Unlock()
+}
+
+// Set moves the clock to now
+func (c *ManualClock) Set(now time.Time) {
+	c.Lock()
+	c.now = now
+	c.Unlock()
+}
+
+func (lru *lruCache) now() time.Time {
+	return lru.clock.Now()
+}
+
+func (lru *lruCache) cacheClock() Clock {
+	return lru.clock
+}
+
+// cacheClock is the clock of the shards, they all share the one of the
+// config
+func (s *shardedCache) cacheClock() Clock {
+	return s.shards[0].clock
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheClock(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		clock := NewManualClock(time.Unix(0, 0))
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, CacheTime: time.Minute, Clock: clock})
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithDeadline("testkey2", "testvalue2", clock.Now().Add(time.Hour))
+
+		clock.Advance(59 * time.Second)
+		if left, ok := cache.TTL("testkey1"); !ok || left != time.Second {
+			t.Fatalf("test shards %d key %s ttl failed, expect %v, got %v/%v", shards, "testkey1", time.Second, left, ok)
+		}
+		clock.Advance(2 * time.Second)
+		if v, ok := cache.Get("testkey1"); ok {
+			t.Fatalf("test shards %d expired key %s failed, expect %v, got %v", shards, "testkey1", nil, v)
+		}
+		if v, _ := cache.Get("testkey2"); v != "testvalue2" {
+			t.Fatalf("test shards %d key %s failed, expect %v, got %v", shards, "testkey2", "testvalue2", v)
+		}
+		clock.Set(time.Unix(0, 0).Add(time.Hour + time.Second))
+		if cache.Contains("testkey2") {
+			t.Fatalf("test shards %d expired key %s failed, expect %v, got %v", shards, "testkey2", false, true)
+		}
+		cache.Close()
+	}
+}
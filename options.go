@@ -0,0 +1,100 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option sets a field of the Config of New, it fails on the values which
+// the Config would silently replace or misread
+type Option func(config *Config) error
+
+// New will create a cache of DefaultMaxLen entries living DefaultCacheTime,
+// changed by opts. It returns the error of the first option failing, or
+// of Validate
+func New(opts ...Option) (Interface, error) {
+	config := Config{MaxLen: DefaultMaxLen, CacheTime: DefaultCacheTime}
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+	return NewCacheWithConfigChecked(config)
+}
+
+// WithMaxLen sets MaxLen, which must be positive
+func WithMaxLen(maxLen int) Option {
+	return func(config *Config) error {
+		if maxLen <= 0 {
+			return fmt.Errorf("%w: max len %d is not positive", ErrInvalidConfig, maxLen)
+		}
+		config.MaxLen = maxLen
+		return nil
+	}
+}
+
+// WithTTL sets CacheTime, the default lifetime of the entries, which must
+// be at least a millisecond
+func WithTTL(ttl time.Duration) Option {
+	return func(config *Config) error {
+		if ttl < time.Millisecond {
+			return fmt.Errorf("%w: ttl %v is below a millisecond", ErrInvalidConfig, ttl)
+		}
+		config.CacheTime = ttl
+		return nil
+	}
+}
+
+// WithMaxBytes sets MaxBytes, which must be positive
+func WithMaxBytes(maxBytes int64) Option {
+	return func(config *Config) error {
+		if maxBytes <= 0 {
+			return fmt.Errorf("%w: max bytes %d is not positive", ErrInvalidConfig, maxBytes)
+		}
+		config.MaxBytes = maxBytes
+		return nil
+	}
+}
+
+// WithOnEvicted sets Callback
+func WithOnEvicted(fn OnEvicted) Option {
+	return func(config *Config) error {
+		config.Callback = fn
+		return nil
+	}
+}
+
+// WithShards sets Shards, which must be positive, 1 is not sharded
+func WithShards(shards int) Option {
+	return func(config *Config) error {
+		if shards <= 0 {
+			return fmt.Errorf("%w: shards %d is not positive", ErrInvalidConfig, shards)
+		}
+		config.Shards = shards
+		return nil
+	}
+}
+
+// WithClock sets Clock
+func WithClock(clock Clock) Option {
+	return func(config *Config) error {
+		config.Clock = clock
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestNew(t *testing.T) {
+	evicted := []Key{}
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := New(WithMaxLen(2), WithTTL(time.Minute), WithShards(1), WithClock(clock), WithOnEvicted(func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}))
+	if err != nil {
+		t.Fatalf("test new failed, expect %v, got %v", nil, err)
+	}
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	if len(evicted) != 1 || evicted[0] != "testkey1" {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", []Key{"testkey1"}, evicted)
+	}
+	if left, ok := cache.TTL("testkey3"); !ok || left != time.Minute {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey3", time.Minute, left)
+	}
+
+	cache, err = New()
+	if err != nil {
+		t.Fatalf("test new failed, expect %v, got %v", nil, err)
+	}
+	cache.Put("testkey1", "testvalue1")
+	if left, ok := cache.TTL("testkey1"); !ok || left > DefaultCacheTime || left < DefaultCacheTime-time.Second {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey1", DefaultCacheTime, left)
+	}
+
+	for _, opt := range []Option{WithMaxLen(0), WithMaxLen(-1), WithTTL(0), WithTTL(NoExpiration), WithShards(0), WithMaxBytes(0)} {
+		if cache, err := New(opt); !errors.Is(err, ErrInvalidConfig) || cache != nil {
+			t.Fatalf("test invalid option failed, expect %v, got %v/%v", ErrInvalidConfig, cache, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+)
+
+// CallbackPanic is the error passed to OnCallbackError when a callback
+// panics, with the value it panicked with and the stack of the panic
+type CallbackPanic struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *CallbackPanic) Error() string {
+	return fmt.Sprintf("cache callback panicked: %v", p.Value)
+}
+
+// LoaderPanic is the error returned to the callers waiting for a load
+// when the loader panics, and passed to OnRefreshError when a background
+// reload panics
+type LoaderPanic struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *LoaderPanic) Error() string {
+	return fmt.Sprintf("cache loader panicked: %v", p.Value)
+}
+
+// CloseError is returned by Close when callbacks failed while it waited
+// for them, or when the snapshot could not be saved. Errs holds the errors
+// in the order they happened, the one of the snapshot last
+type CloseError struct {
+	Errs []error
+}
+
+// Error joins the messages of Errs with "; "
+func (e *CloseError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "cache: close: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether one of Errs is target
+func (e *CloseError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *CloseError) Unwrap() []error {
+	return e.Errs
+}
+
+// joinErrors returns a *CloseError with the non nil errors, flattening
+// the CloseErrors among them, or nil if there is none
+func joinErrors(errs ...error) error {
+	var joined []error
+	for _, err := range errs {
+		if ce, ok := err.(*CloseError); ok {
+			joined = append(joined, ce.Errs...)
+		} else if err != nil {
+			joined = append(joined, err)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return &CloseError{Errs: joined}
+}
+
+// withErrors adds withError to withReason, its errors are passed to onError
+func withErrors(withReason OnEvictedWithReason, withError OnEvictedWithError, onError func(key Key, err error)) OnEvictedWithReason {
+	return func(key Key, value Value, reason EvictionReason) {
+		if withReason != nil {
+			withReason(key, value, reason)
+		}
+		if err := withError(key, value, reason); err != nil {
+			onError(key, err)
+		}
+	}
+}
+
+// callbackFailed passes the failure of a callback to OnCallbackError, and
+// keeps it for Close once closing
+func (lru *lruCache) callbackFailed(key Key, err error) {
+	if lru.onCallbackError != nil {
+		lru.onCallbackError(key, err)
+	}
+	if atomic.LoadInt32(&lru.closing) == 1 {
+		lru.closeErrsLock.Lock()
+		lru.closeErrs = append(lru.closeErrs, err)
+		lru.closeErrsLock.Unlock()
+	}
+}
+
+// safeCallback recovers the panics of callback and hands them to onError,
+// so that they do not unwind through the cache with its lock held
+func safeCallback(callback OnEvictedWithReason, onError func(key Key, err error)) OnEvictedWithReason {
+	if callback == nil {
+		return nil
+	}
+	return func(key Key, value Value, reason EvictionReason) {
+		defer recoverCallback(key, onError)
+		callback(key, value, reason)
+	}
+}
+
+// recoverCallback must be deferred by the caller of the callback
+func recoverCallback(key Key, onError func(key Key, err error)) {
+	if r := recover(); r != nil && onError != nil {
+		onError(key, &CallbackPanic{Value: r, Stack: debug.Stack()})
+	}
+}
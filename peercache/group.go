@@ -0,0 +1,73 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peercache
+
+import "github.com/leopoldxx/cache"
+
+// Fetcher gets the value of a key of a group from a peer
+type Fetcher interface {
+	Fetch(peer, group, key string) (cache.Value, error)
+}
+
+// Group is a cache whose keys are spread over the peers of a ring
+type Group struct {
+	name    string
+	self    string
+	local   cache.Interface
+	load    cache.LoadFunc
+	ring    *Ring
+	fetcher Fetcher
+}
+
+// NewGroup will create a group named name, for the peer self of the ring.
+// The keys it owns are loaded with load and cached in local, the others
+// are fetched from their owner with fetcher
+func NewGroup(name, self string, local cache.Interface, load cache.LoadFunc, ring *Ring, fetcher Fetcher) *Group {
+	return &Group{
+		name:    name,
+		self:    self,
+		local:   cache.Wrap(local),
+		load:    load,
+		ring:    ring,
+		fetcher: fetcher,
+	}
+}
+
+// Name returns the name of the group
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Get returns the value of the key from its owner. If the owner can not
+// be reached the key is loaded locally, without caching it, so that a
+// peer going down does not fail the reads
+func (g *Group) Get(key string) (cache.Value, error) {
+	owner, ok := g.ring.Owner(key)
+	if !ok || owner == g.self {
+		return g.getLocal(key)
+	}
+	value, err := g.fetcher.Fetch(owner, g.name, key)
+	if err == nil {
+		return value, nil
+	}
+	return g.load(key)
+}
+
+// getLocal returns the value of a key owned by this peer
+func (g *Group) getLocal(key string) (cache.Value, error) {
+	return g.local.GetOrLoad(key, g.load)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peercache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/leopoldxx/cache"
+)
+
+// DefaultBasePath is the path the peers are served under
+const DefaultBasePath = "/_peercache/"
+
+// HTTPPool serves the groups of this peer over HTTP, and fetches the keys
+// from the other peers, which are base URLs like "http://10.0.0.2:8000".
+// The values are gob encoded, so values of custom types must be
+// registered with gob.Register
+type HTTPPool struct {
+	basePath string
+	client   *http.Client
+	groups   map[string]*Group
+	sync.RWMutex
+}
+
+// NewHTTPPool will create a pool serving under basePath,
+// DefaultBasePath if it is empty, and fetching with http.DefaultClient
+func NewHTTPPool(basePath string) *HTTPPool {
+	if basePath == "" {
+		basePath = DefaultBasePath
+	}
+	return &HTTPPool{basePath: basePath, client: http.DefaultClient, groups: map[string]*Group{}}
+}
+
+// Register serves the group to the other peers
+func (p *HTTPPool) Register(g *Group) {
+	p.Lock()
+	defer p.Unlock()
+	p.groups[g.Name()] = g
+}
+
+// ServeHTTP answers GET basePath/group/key with the owned value of the key
+func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, p.basePath) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, p.basePath), "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	p.RLock()
+	g := p.groups[parts[0]]
+	p.RUnlock()
+	if g == nil {
+		http.NotFound(w, r)
+		return
+	}
+	// serve from the local cache whoever owns the key, a peer asks for
+	// the keys it believes this one owns
+	value, err := g.getLocal(parts[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(buf.Bytes())
+}
+
+// Fetch gets the value of the key from the peer
+func (p *HTTPPool) Fetch(peer, group, key string) (cache.Value, error) {
+	u := strings.TrimSuffix(peer, "/") + p.basePath + url.PathEscape(group) + "/" + url.PathEscape(key)
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("peercache: peer %s returned %s: %s", peer, resp.Status, bytes.TrimSpace(msg))
+	}
+	var value cache.Value
+	if err := gob.NewDecoder(resp.Body).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peercache_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/peercache"
+)
+
+func TestRing(t *testing.T) {
+	ring := peercache.NewRing(0, "a", "b", "c")
+	owners := map[string]string{}
+	count := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		owner, ok := ring.Owner(key)
+		if !ok {
+			t.Fatalf("test key %s owner failed, expect found, got none", key)
+		}
+		owners[key] = owner
+		count[owner]++
+	}
+	for _, peer := range []string{"a", "b", "c"} {
+		if count[peer] < 200 {
+			t.Fatalf("test peer %s share failed, expect more than 200, got %v", peer, count[peer])
+		}
+	}
+
+	// removing a peer only moves its own keys
+	ring.Set("a", "b")
+	for key, before := range owners {
+		owner, _ := ring.Owner(key)
+		if before != "c" && owner != before {
+			t.Fatalf("test key %s owner failed, expect %v, got %v", key, before, owner)
+		}
+	}
+
+	if _, ok := peercache.NewRing(0).Owner("key"); ok {
+		t.Fatalf("test empty ring failed, expect no owner, got one")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var lock sync.Mutex
+	loads := map[string]int{}
+	load := func() LoadFunc {
+		return func(key Key) (Value, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			loads[key.(string)]++
+			return "value of " + key.(string), nil
+		}
+	}
+
+	pools := []*peercache.HTTPPool{peercache.NewHTTPPool(""), peercache.NewHTTPPool("")}
+	servers := []*httptest.Server{httptest.NewServer(pools[0]), httptest.NewServer(pools[1])}
+	defer servers[0].Close()
+	defer servers[1].Close()
+	ring := peercache.NewRing(0, servers[0].URL, servers[1].URL)
+	groups := make([]*peercache.Group, 2)
+	for i := range groups {
+		c := NewCacheWithConfig(Config{MaxLen: 100})
+		defer c.Close()
+		groups[i] = peercache.NewGroup("test", servers[i].URL, c, load(), ring, pools[i])
+		pools[i].Register(groups[i])
+	}
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		for _, g := range groups {
+			value, err := g.Get(key)
+			if err != nil || value != "value of "+key {
+				t.Fatalf("test key %s get failed, expect %v, got %v %v", key, "value of "+key, value, err)
+			}
+		}
+	}
+	for key, n := range loads {
+		if n != 1 {
+			t.Fatalf("test key %s loads failed, expect %v, got %v", key, 1, n)
+		}
+	}
+
+	// an unreachable owner falls back to loading locally
+	servers[1].Close()
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		value, err := groups[0].Get(key)
+		if err != nil || value != "value of "+key {
+			t.Fatalf("test key %s fallback failed, expect %v, got %v %v", key, "value of "+key, value, err)
+		}
+	}
+}
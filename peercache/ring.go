@@ -0,0 +1,93 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package peercache spreads a cache over a group of peers, each key is
+// owned by one peer chosen by consistent hashing, which loads and caches
+// it, and the other peers fetch it from the owner instead of holding a
+// copy of their own.
+package peercache
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of points of each peer on the ring
+const DefaultReplicas = 50
+
+// Ring assigns the keys to the peers by consistent hashing, adding or
+// removing a peer only moves the keys of that peer
+type Ring struct {
+	replicas int
+	points   []uint64
+	owners   map[uint64]string
+	sync.RWMutex
+}
+
+// NewRing will create a ring with replicas points per peer,
+// DefaultReplicas if it is not positive
+func NewRing(replicas int, peers ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{replicas: replicas, owners: map[uint64]string{}}
+	r.Set(peers...)
+	return r
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	// fnv alone spreads similar strings poorly over the ring
+	v := h.Sum64()
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	return v
+}
+
+// Set replaces the peers on the ring
+func (r *Ring) Set(peers ...string) {
+	r.Lock()
+	defer r.Unlock()
+	r.points = r.points[:0]
+	r.owners = map[uint64]string{}
+	for _, peer := range peers {
+		for i := 0; i < r.replicas; i++ {
+			point := hash(strconv.Itoa(i) + peer)
+			r.points = append(r.points, point)
+			r.owners[point] = peer
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// Owner returns the peer owning the key, false if the ring is empty
+func (r *Ring) Owner(key string) (string, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.points) == 0 {
+		return "", false
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], true
+}
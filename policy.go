@@ -0,0 +1,125 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "container/list"
+
+// Policy selects which entry is evicted when the cache is full
+type Policy int
+
+const (
+	// PolicyLRU evicts the least recently used entry
+	PolicyLRU Policy = iota
+	// PolicyLFU evicts the least frequently used entry, the least recently
+	// used one among those used equally often
+	PolicyLFU
+	// PolicyARC balances between recency and frequency adaptively, by
+	// remembering the keys it evicted recently. Entries used once, e.g. by
+	// a scan, are evicted before the ones used repeatedly
+	PolicyARC
+	// Policy2Q puts new entries on probation, an entry is protected once it
+	// is used again. The entries on probation are evicted first, so keys
+	// used only once never push out the ones that proved useful
+	Policy2Q
+	// PolicyFIFO evicts the oldest entry, reads do not reorder the entries
+	// and replacing a value keeps its place. Keys and Range visit the
+	// entries from the newest to the oldest one
+	PolicyFIFO
+	// PolicyCLOCK approximates LRU with a second chance, a hit only flags
+	// the entry as referenced instead of moving it to the front, so reads
+	// hold the lock for a shorter time. Keys and Range visit the entries
+	// from the newest to the oldest one, an entry given a second chance
+	// counts as new
+	PolicyCLOCK
+	// PolicySample evicts the least recently used entry of a random sample
+	// of EvictionSamples entries, like Redis does. It is an approximation
+	// of LRU whose eviction cost does not depend on the size of the cache
+	PolicySample
+)
+
+// evictionPolicy tracks the resident elements to pick the eviction
+// victims. The list of the cache is kept in recency order whatever the
+// policy, except for PolicyFIFO and PolicyCLOCK which keep it in insertion
+// order, so Keys and Range are not affected by the policy state. adding is called with the
+// key of a new entry before room is made for it. The lock must be held
+type evictionPolicy interface {
+	adding(key Key)
+	add(elem *list.Element)
+	hit(elem *list.Element)
+	remove(elem *list.Element, reason EvictionReason)
+	victim() *list.Element
+	reset()
+}
+
+func newPolicy(policy Policy, lru *lruCache) evictionPolicy {
+	switch policy {
+	case PolicyLFU:
+		return newLFUPolicy()
+	case PolicyARC:
+		return newARCPolicy(lru)
+	case Policy2Q:
+		return newTwoQueuePolicy(lru)
+	case PolicyFIFO:
+		return fifoPolicy{lruPolicy{lst: lru.lst}}
+	case PolicyCLOCK:
+		return clockPolicy{lst: lru.lst}
+	case PolicySample:
+		return newSamplePolicy(lru.evictionSamples)
+	default:
+		return lruPolicy{lst: lru.lst}
+	}
+}
+
+// lruPolicy relies on the recency order of the list
+type lruPolicy struct {
+	lst *list.List
+}
+
+func (p lruPolicy) adding(key Key)                                   {}
+func (p lruPolicy) add(elem *list.Element)                           {}
+func (p lruPolicy) hit(elem *list.Element)                           {}
+func (p lruPolicy) remove(elem *list.Element, reason EvictionReason) {}
+func (p lruPolicy) reset()                                           {}
+
+func (p lruPolicy) victim() *list.Element {
+	return p.lst.Back()
+}
+
+// fifoPolicy relies on the list being kept in insertion order
+type fifoPolicy struct {
+	lruPolicy
+}
+
+// capacity is the number of entries the policies size their queues by,
+// the current length for the caches bounded by weight only
+func (lru *lruCache) capacity() int {
+	if lru.maxLen == unboundedLen {
+		if n := lru.hash.len(); n > 0 {
+			return n
+		}
+		return 1
+	}
+	return lru.maxLen
+}
+
+// promote marks an element as just used, the lock must be held
+func (lru *lruCache) promote(elem *list.Element) {
+	if !lru.insertionOrder {
+		lru.lst.MoveToFront(elem)
+	}
+	lru.policy.hit(elem)
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCachePolicyLFU(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: cb, Policy: PolicyLFU})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	for i := 0; i < 3; i++ {
+		cache.Get("testkey1")
+	}
+	cache.Get("testkey3")
+	cache.Get("testkey3")
+	// the most recently used key, but the least frequently used one
+	cache.Get("testkey2")
+
+	cache.Put("testkey4", "testvalue4")
+	cache.Put("testkey5", "testvalue5")
+
+	expect := []Key{"testkey2", "testkey4"}
+	if len(evicted) != len(expect) || evicted[0] != expect[0] || evicted[1] != expect[1] {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	tests := []struct {
+		key    Key
+		exists bool
+	}{
+		{"testkey1", true},
+		{"testkey3", true},
+		{"testkey5", true},
+	}
+	for _, test := range tests {
+		if cache.Contains(test.key) != test.exists {
+			t.Fatalf("test key %s failed, expect %v, got %v", test.key, test.exists, !test.exists)
+		}
+	}
+
+	// deleted entries leave the policy as well
+	cache.Del("testkey1")
+	cache.Put("testkey6", "testvalue6")
+	cache.Put("testkey7", "testvalue7")
+	if cache.Len() != 3 || !cache.Contains("testkey3") || !cache.Contains("testkey7") {
+		t.Fatalf("test keys after delete failed, got %v", cache.Keys())
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCachePolicyARC(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 4, Policy: PolicyARC})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Get("testkey1")
+	cache.Get("testkey2")
+
+	// a scan of keys used once does not flush the ones used repeatedly
+	for i := 0; i < 10; i++ {
+		cache.Put(i, i)
+	}
+	tests := []struct {
+		key    Key
+		exists bool
+	}{
+		{"testkey1", true},
+		{"testkey2", true},
+		{7, false},
+		{8, true},
+		{9, true},
+	}
+	for _, test := range tests {
+		if cache.Contains(test.key) != test.exists {
+			t.Fatalf("test key %v failed, expect %v, got %v", test.key, test.exists, !test.exists)
+		}
+	}
+
+	// a key evicted recently comes back as a frequent one, and shifts the
+	// balance toward the keys used once
+	cache.Put(7, 7)
+	cache.Put(10, 10)
+	cache.Put(11, 11)
+	if !cache.Contains(7) || cache.Len() != 4 {
+		t.Fatalf("test ghost key failed, got %v", cache.Keys())
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCachePolicy2Q(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 4, Callback: cb, Policy: Policy2Q})
+	for i := 0; i < 4; i++ {
+		cache.Put(i, i)
+	}
+	cache.Get(0)
+	cache.Get(1)
+	cache.Get(2)
+	cache.Get(3)
+
+	// only 3 entries are protected, 0 has been demoted on probation
+	cache.Put(4, 4)
+	cache.Put(5, 5)
+	expect := []Key{0, 4}
+	if len(evicted) != len(expect) || evicted[0] != expect[0] || evicted[1] != expect[1] {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	for _, key := range []Key{1, 2, 3, 5} {
+		if !cache.Contains(key) {
+			t.Fatalf("test key %v failed, expect %v, got %v", key, true, false)
+		}
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCacheTinyLFU(t *testing.T) {
+	reasons := map[Key]EvictionReason{}
+	cb := func(key Key, value Value, reason EvictionReason) {
+		reasons[key] = reason
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 2, CallbackWithReason: cb, TinyLFU: true})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Get("testkey1")
+	cache.Get("testkey2")
+
+	// a key seen once does not push out the popular ones
+	cache.Put("testkey3", "testvalue3")
+	if cache.Contains("testkey3") || cache.Len() != 2 {
+		t.Fatalf("test rejected key failed, got %v", cache.Keys())
+	}
+	if reasons["testkey3"] != ReasonRejected {
+		t.Fatalf("test key %s reason failed, expect %v, got %v", "testkey3", ReasonRejected, reasons["testkey3"])
+	}
+
+	// it is admitted once it is used more often than the victim
+	cache.Put("testkey3", "testvalue3")
+	cache.Put("testkey3", "testvalue3")
+	if !cache.Contains("testkey3") || cache.Contains("testkey1") {
+		t.Fatalf("test admitted key failed, got %v", cache.Keys())
+	}
+	if reasons["testkey1"] != ReasonCapacity {
+		t.Fatalf("test key %s reason failed, expect %v, got %v", "testkey1", ReasonCapacity, reasons["testkey1"])
+	}
+	if stats := cache.Stats(); stats.Rejections != 2 || stats.Evictions != 1 {
+		t.Fatalf("test stats failed, expect %v rejections and %v evictions, got %+v", 2, 1, stats)
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCachePolicyFIFO(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: cb, Policy: PolicyFIFO})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	cache.Get("testkey1")
+	cache.Put("testkey2", "testvalue2-2")
+
+	keys := cache.Keys()
+	expectKeys := []Key{"testkey3", "testkey2", "testkey1"}
+	for i := range expectKeys {
+		if keys[i] != expectKeys[i] {
+			t.Fatalf("test keys failed, expect %v, got %v", expectKeys, keys)
+		}
+	}
+
+	cache.Put("testkey4", "testvalue4")
+	cache.Put("testkey5", "testvalue5")
+	expect := []Key{"testkey1", "testkey2"}
+	if len(evicted) != len(expect) || evicted[0] != expect[0] || evicted[1] != expect[1] {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCachePolicyCLOCK(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: cb, Policy: PolicyCLOCK})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	cache.Get("testkey1")
+	cache.Get("testkey3")
+
+	// testkey1 gets a second chance
+	cache.Put("testkey4", "testvalue4")
+	// so does testkey3, while testkey1 has used its own
+	cache.Put("testkey5", "testvalue5")
+	cache.Put("testkey6", "testvalue6")
+
+	expect := []Key{"testkey2", "testkey1", "testkey4"}
+	if len(evicted) != len(expect) {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	for i := range expect {
+		if evicted[i] != expect[i] {
+			t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+		}
+	}
+	checkConsistent(t, cache)
+}
+
+func TestCachePolicySample(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	// with samples as large as the cache the victim is always the least
+	// recently used entry
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: cb, Policy: PolicySample, EvictionSamples: 100})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	cache.Get("testkey1")
+	cache.Put("testkey4", "testvalue4")
+	cache.Del("testkey3")
+	cache.Put("testkey5", "testvalue5")
+	cache.Put("testkey6", "testvalue6")
+
+	expect := []Key{"testkey2", "testkey3", "testkey1"}
+	if len(evicted) != len(expect) {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	for i := range expect {
+		if evicted[i] != expect[i] {
+			t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+		}
+	}
+
+	cache = NewCacheWithConfig(Config{MaxLen: 100, Policy: PolicySample})
+	for i := 0; i < 1000; i++ {
+		cache.Put(i, i)
+		cache.Get(i / 2)
+	}
+	if cache.Len() != 100 {
+		t.Fatalf("test len failed, expect %v, got %v", 100, cache.Len())
+	}
+	checkConsistent(t, cache)
+}
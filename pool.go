@@ -0,0 +1,40 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "sync"
+
+// entryPool recycles the entries that leave the caches, so that a high
+// put rate does not turn every eviction into garbage. The list elements
+// can not be recycled, container/list allocates one on every push
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return new(listEntry)
+	},
+}
+
+func acquireEntry() *listEntry {
+	return entryPool.Get().(*listEntry)
+}
+
+// releaseEntry clears the entry so that the pool does not keep its key and
+// value alive. Nothing may use the entry afterwards, the lock must be held
+// if it was in the cache
+func releaseEntry(entry *listEntry) {
+	*entry = listEntry{}
+	entryPool.Put(entry)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "time"
+
+// RangeMode selects how a range sees concurrent modifications
+type RangeMode int
+
+const (
+	// RangeLocked holds the cache lock for the whole iteration, so it sees
+	// a consistent view but blocks all other operations meanwhile. The
+	// callback must not call any method of the cache, or it deadlocks
+	RangeLocked RangeMode = iota
+	// RangeSnapshot copies the live entries under the lock and iterates
+	// the copy without it, so the callback may use the cache freely, but
+	// modifications made after the copy are not observed
+	RangeSnapshot
+)
+
+// RangeOptions of an iteration over the cache
+type RangeOptions struct {
+	Mode RangeMode
+}
+
+// Keys returns the live keys from the most to the least recently used one,
+// from the newest to the oldest one with PolicyFIFO
+func (lru *lruCache) Keys() []Key {
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	keys := make([]Key, 0, lru.lst.Len())
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		if entry := elem.Value.(*listEntry); !entry.expired(now) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+type rangeItem struct {
+	key   Key
+	value Value
+}
+
+// Range calls fn for every live entry from the most to the least recently
+// used one, until fn returns false. It iterates a snapshot taken under the
+// lock, so fn sees a consistent view and may use the cache, e.g. to delete
+// the entries it visits
+func (lru *lruCache) Range(fn func(key Key, value Value) bool) {
+	lru.RangeWithOptions(fn, RangeOptions{Mode: RangeSnapshot})
+}
+
+// RangeWithOptions calls fn for every live entry from the most to the
+// least recently used one, until fn returns false. Reading entries this
+// way does not change their recency
+func (lru *lruCache) RangeWithOptions(fn func(key Key, value Value) bool, opts RangeOptions) {
+	if opts.Mode == RangeSnapshot {
+		for _, item := range lru.rangeSnapshot() {
+			if !fn(item.key, item.value) {
+				return
+			}
+		}
+		return
+	}
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			continue
+		}
+		if !fn(entry.key, value) {
+			return
+		}
+	}
+}
+
+// Snapshot returns the live entries from the most to the least recently
+// used one, copied under the lock in one go, so that they can be walked
+// without holding it
+func (lru *lruCache) Snapshot() []Entry {
+	lru.Lock()
+	defer lru.Unlock()
+	return lru.entries(lru.now())
+}
+
+// Snapshot locks all the shards at once, the entries are grouped by shard
+func (s *shardedCache) Snapshot() []Entry {
+	for _, shard := range s.shards {
+		shard.Lock()
+	}
+	now := s.shards[0].now()
+	var entries []Entry
+	for _, shard := range s.shards {
+		entries = append(entries, shard.entries(now)...)
+	}
+	for _, shard := range s.shards {
+		shard.Unlock()
+	}
+	return entries
+}
+
+// entries copies the entries live at now, the lock must be held
+func (lru *lruCache) entries(now time.Time) []Entry {
+	entries := make([]Entry, 0, lru.lst.Len())
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry.export(value, now))
+	}
+	return entries
+}
+
+func (e *listEntry) export(value Value, now time.Time) Entry {
+	return Entry{Key: e.key, Value: value, Deadline: e.deadTime, Age: now.Sub(e.created), Created: e.created, LastAccess: e.lastAccess, Hits: e.accessCount}
+}
+
+func (lru *lruCache) rangeSnapshot() []rangeItem {
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	items := make([]rangeItem, 0, lru.lst.Len())
+	for elem := lru.lst.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			continue
+		}
+		items = append(items, rangeItem{entry.key, value})
+	}
+	return items
+}
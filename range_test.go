@@ -0,0 +1,162 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheRangeWithOptions(t *testing.T) {
+	for _, mode := range []RangeMode{RangeLocked, RangeSnapshot} {
+		cache := NewCacheWithConfig(Config{MaxLen: 3})
+		cache.Put("testkey1", "testvalue1")
+		cache.Put("testkey2", "testvalue2")
+		cache.Put("testkey3", "testvalue3")
+
+		var keys []Key
+		cache.(LockedRanger).RangeWithOptions(func(key Key, value Value) bool {
+			keys = append(keys, key)
+			return true
+		}, RangeOptions{Mode: mode})
+		expect := []Key{"testkey3", "testkey2", "testkey1"}
+		if len(keys) != len(expect) {
+			t.Fatalf("test mode %v keys failed, expect %v, got %v", mode, expect, keys)
+		}
+		for i := range expect {
+			if keys[i] != expect[i] {
+				t.Fatalf("test mode %v keys failed, expect %v, got %v", mode, expect, keys)
+			}
+		}
+
+		keys = keys[:0]
+		cache.(LockedRanger).RangeWithOptions(func(key Key, value Value) bool {
+			keys = append(keys, key)
+			return false
+		}, RangeOptions{Mode: mode})
+		if len(keys) != 1 {
+			t.Fatalf("test mode %v stop failed, expect %v keys, got %v", mode, 1, keys)
+		}
+	}
+}
+
+func TestCacheRangeSnapshotNotBlocking(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+	cache.Put("testkey1", "testvalue1")
+
+	cache.(LockedRanger).RangeWithOptions(func(key Key, value Value) bool {
+		done := make(chan struct{})
+		go func() {
+			cache.Put("testkey2", "testvalue2")
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("test snapshot range failed, concurrent put is blocked")
+		}
+		return true
+	}, RangeOptions{Mode: RangeSnapshot})
+
+	if cache.Len() != 2 {
+		t.Fatalf("test len failed, expect %v, got %v", 2, cache.Len())
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 3})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	cache.PutWithTimeout("testkey3", "testvalue3", time.Second)
+	cache.Get("testkey1")
+
+	keys := cache.Keys()
+	expect := []Key{"testkey1", "testkey3", "testkey2"}
+	if len(keys) != len(expect) {
+		t.Fatalf("test keys failed, expect %v, got %v", expect, keys)
+	}
+	for i := range expect {
+		if keys[i] != expect[i] {
+			t.Fatalf("test keys failed, expect %v, got %v", expect, keys)
+		}
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Fatalf("test live keys failed, expect %v keys, got %v", 2, keys)
+	}
+}
+
+func TestCacheRange(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 4})
+	for i := 0; i < 4; i++ {
+		cache.Put(i, i)
+	}
+
+	// bulk invalidation from inside the iteration
+	cache.Range(func(key Key, value Value) bool {
+		if value.(int)%2 == 0 {
+			cache.Del(key)
+		}
+		return true
+	})
+	if cache.Len() != 2 || cache.Contains(0) || cache.Contains(2) {
+		t.Fatalf("test range delete failed, got keys %v", cache.Keys())
+	}
+
+	visited := 0
+	cache.Range(func(key Key, value Value) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Fatalf("test range stop failed, expect %v visits, got %v", 1, visited)
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		start := time.Unix(0, 0)
+		clock := NewManualClock(start)
+		cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: shards, CacheTime: time.Minute, Clock: clock})
+		cache.Put("testkey1", "testvalue1")
+		clock.Advance(10 * time.Second)
+		cache.PutWithTimeout("testkey2", "testvalue2", NoExpiration)
+		cache.PutWithTimeout("testkey3", "testvalue3", time.Second)
+		clock.Advance(5 * time.Second)
+
+		expect := map[Key]Entry{
+			"testkey1": {Key: "testkey1", Value: "testvalue1", Deadline: start.Add(time.Minute), Age: 15 * time.Second, Created: start},
+			"testkey2": {Key: "testkey2", Value: "testvalue2", Age: 5 * time.Second, Created: start.Add(10 * time.Second)},
+		}
+		entries := cache.(Snapshotter).Snapshot()
+		if len(entries) != len(expect) {
+			t.Fatalf("test shards %d snapshot failed, expect %v, got %v", shards, expect, entries)
+		}
+		for _, entry := range entries {
+			if entry != expect[entry.Key] {
+				t.Fatalf("test shards %d key %v failed, expect %v, got %v", shards, entry.Key, expect[entry.Key], entry)
+			}
+		}
+		if shards == 0 && entries[0].Key != "testkey2" {
+			t.Fatalf("test snapshot order failed, expect %v, got %v", "testkey2", entries[0].Key)
+		}
+		cache.Close()
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "time"
+
+const (
+	// RateResolution is the width of a bucket of the windowed counters
+	RateResolution = time.Second
+	// RateWindow is the time span the windowed rates are computed over
+	RateWindow = time.Minute
+)
+
+// rateCounter counts events in time buckets over a sliding window,
+// it is not safe for concurrent use
+type rateCounter struct {
+	counts []uint64
+	slots  []int64
+}
+
+func newRateCounter() *rateCounter {
+	n := int(RateWindow / RateResolution)
+	return &rateCounter{
+		counts: make([]uint64, n),
+		slots:  make([]int64, n),
+	}
+}
+
+func (r *rateCounter) add(now time.Time, n uint64) {
+	slot := now.UnixNano() / int64(RateResolution)
+	i := int(slot % int64(len(r.slots)))
+	if r.slots[i] != slot {
+		r.slots[i] = slot
+		r.counts[i] = 0
+	}
+	r.counts[i] += n
+}
+
+// rate returns the events per second over the window ending at now
+func (r *rateCounter) rate(now time.Time) float64 {
+	slot := now.UnixNano() / int64(RateResolution)
+	var sum uint64
+	for i := range r.slots {
+		if slot-r.slots[i] < int64(len(r.slots)) {
+			sum += r.counts[i]
+		}
+	}
+	return float64(sum) / RateWindow.Seconds()
+}
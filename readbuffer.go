@@ -0,0 +1,96 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// With Config.ReadBuffer the hits are served under the read lock, and
+// the changes they make to the cache, the recency of the entry, its read
+// count and its frequency, are queued in a buffered channel instead. The
+// next exclusive lock applies them in a batch before anything else. The
+// buffer is lossy: when it is full and the lock is busy the read is not
+// recorded, which only makes the eviction order approximate.
+
+// sharedAccess serves a read under the read lock, it reports done false
+// if the read has to change the entry and must be retried exclusively
+func (lru *lruCache) sharedAccess(elem *list.Element) (value Value, ok bool, done bool) {
+	if elem == nil {
+		atomic.AddUint64(&lru.readMisses, 1)
+		return nil, false, true
+	}
+	entry := elem.Value.(*listEntry)
+	now := lru.now()
+	if entry.probation || lru.slidingTTL || entry.expired(now) || lru.dueForRefresh(entry, now) {
+		return nil, false, false
+	}
+	value, err := lru.valueOf(entry)
+	if err != nil {
+		return nil, false, false
+	}
+	atomic.AddUint64(&lru.readHits, 1)
+	return value, true, true
+}
+
+// recordRead queues the hit of elem, the lock must not be held
+func (lru *lruCache) recordRead(elem *list.Element) {
+	select {
+	case lru.reads <- elem:
+		return
+	default:
+	}
+	// the buffer is full, apply it now unless another goroutine holds the
+	// lock, then it will be applied when that one is done
+	if lru.RWMutex.TryLock() {
+		lru.drainReads()
+		lru.applyRead(elem)
+		lru.RWMutex.Unlock()
+	}
+}
+
+// drainReads applies the queued reads, the lock must be held
+func (lru *lruCache) drainReads() {
+	for {
+		select {
+		case elem := <-lru.reads:
+			lru.applyRead(elem)
+		default:
+			return
+		}
+	}
+}
+
+// applyRead updates the entry read, unless it has left the cache since
+func (lru *lruCache) applyRead(elem *list.Element) {
+	// the entry of a removed element may already be reused by a put
+	// outside of the lock, tell it from the links of the element first
+	if elem.Next() == nil && elem.Prev() == nil && lru.lst.Front() != elem {
+		return
+	}
+	entry := elem.Value.(*listEntry)
+	if current, exists := lru.hash.get(entry.key); !exists || current != elem {
+		return
+	}
+	entry.accessCount++
+	entry.lastAccess = lru.now()
+	if lru.admission != nil {
+		lru.admission.increment(entry.key)
+	}
+	lru.promote(elem)
+}
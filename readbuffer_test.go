@@ -0,0 +1,95 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestReadBuffer(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2, ReadBuffer: 16})
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	cache.PutInt(2, "testvalue2")
+	cache.PutWithTimeout("testkey3", "testvalue3", time.Second)
+
+	tests := []struct {
+		key    Key
+		value  Value
+		exists bool
+	}{
+		{"testkey1", nil, false},
+		{int64(2), "testvalue2", true},
+		{"testkey3", "testvalue3", true},
+	}
+	for _, test := range tests {
+		v, ok := cache.Get(test.key)
+		if ok != test.exists || v != test.value {
+			t.Fatalf("test key %v failed, expect %v, got %v", test.key, test.value, v)
+		}
+	}
+
+	// the buffered hit of 2 is applied before the put evicts testkey3
+	cache.GetInt(2)
+	cache.Put("testkey4", "testvalue4")
+	if _, ok := cache.GetString("testkey3"); ok {
+		t.Fatalf("test evicted key %s failed, expect %v, got %v", "testkey3", false, ok)
+	}
+	if v, count, ok := cache.(Inspector).GetWithCount(int64(2)); !ok || v != "testvalue2" || count != 3 {
+		t.Fatalf("test key %v count failed, expect %v, got %v", 2, 3, count)
+	}
+	if stats := cache.Stats(); stats.Hits != 4 || stats.Misses != 2 {
+		t.Fatalf("test stats failed, got %+v", stats)
+	}
+}
+
+func TestReadBufferConcurrent(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100, ReadBuffer: 4})
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Put(i, i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := (g*31 + i) % 150
+				if v, ok := cache.Get(key); ok && v != key {
+					t.Errorf("test key %v failed, expect %v, got %v", key, key, v)
+					return
+				}
+				if i%10 == 0 {
+					cache.Put(key, key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if report := cache.(Inspector).Inspect(); !report.Consistent || report.ListLen != 100 {
+		t.Fatalf("test consistency failed, got %+v", report)
+	}
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 8000 {
+		t.Fatalf("test stats failed, expect %v reads, got %+v", 8000, stats)
+	}
+}
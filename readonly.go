@@ -0,0 +1,104 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReadOnly is returned by the writes of a ReadOnly cache which return
+// an error
+var ErrReadOnly = errors.New("cache: read only")
+
+// readOnlyCache passes the reads through and drops the writes
+type readOnlyCache struct {
+	Interface
+}
+
+// ReadOnly returns a view of c for the code which should only read it.
+// The writes and deletes do nothing, those returning an error return
+// ErrReadOnly, and those returning whether they changed the cache report
+// false. GetOrLoad and GetOrStore return the value they would have stored
+// without storing it, Close, Purge and SetBypass are ignored
+func ReadOnly(c Interface) Interface {
+	return &readOnlyCache{Interface: Wrap(c)}
+}
+
+func (r *readOnlyCache) Put(key Key, value Value)                                 {}
+func (r *readOnlyCache) PutWithTimeout(key Key, value Value, t time.Duration)     {}
+func (r *readOnlyCache) PutWithDeadline(key Key, value Value, deadline time.Time) {}
+func (r *readOnlyCache) PutString(key string, value Value)                        {}
+func (r *readOnlyCache) PutInt(key int64, value Value)                            {}
+func (r *readOnlyCache) PutMulti(entries map[Key]Value)                           {}
+func (r *readOnlyCache) PutNotFound(key Key)                                      {}
+func (r *readOnlyCache) PutTagged(key Key, value Value, tags ...string)           {}
+func (r *readOnlyCache) Touch(key Key, d time.Duration) bool                      { return false }
+func (r *readOnlyCache) Add(key Key, value Value) bool                            { return false }
+func (r *readOnlyCache) Replace(key Key, value Value) bool                        { return false }
+func (r *readOnlyCache) CompareAndSwap(key Key, old, new Value) bool              { return false }
+func (r *readOnlyCache) Del(key Key) Value                                        { return nil }
+func (r *readOnlyCache) DelE(key Key) (Value, bool)                               { return nil, false }
+func (r *readOnlyCache) DelMulti(keys []Key) int                                  { return 0 }
+func (r *readOnlyCache) DelPrefix(prefix string) int                              { return 0 }
+func (r *readOnlyCache) DelFunc(fn func(key Key, value Value) bool) int           { return 0 }
+func (r *readOnlyCache) InvalidateTag(tag string) int                             { return 0 }
+func (r *readOnlyCache) SetBypass(bypass bool)                                    {}
+func (r *readOnlyCache) Purge()                                                   {}
+func (r *readOnlyCache) Close() error                                             { return nil }
+func (r *readOnlyCache) IncrementInt64(key Key, delta int64) (int64, error)       { return 0, ErrReadOnly }
+func (r *readOnlyCache) DecrementInt64(key Key, delta int64) (int64, error)       { return 0, ErrReadOnly }
+
+func (r *readOnlyCache) PutMultiWithTimeout(entries map[Key]Value, t time.Duration) {}
+
+func (r *readOnlyCache) Merge(other Interface, conflict func(key Key, a, b Value) Value) {}
+
+func (r *readOnlyCache) PutCtx(ctx context.Context, key Key, value Value) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyCache) GetOrStore(key Key, def Value, t time.Duration) (Value, bool) {
+	if value, ok := r.Interface.Get(key); ok {
+		return value, true
+	}
+	return def, false
+}
+
+func (r *readOnlyCache) GetOrLoad(key Key, load LoadFunc) (Value, error) {
+	return r.GetOrLoadCtx(context.Background(), key, func(ctx context.Context, key Key) (Value, error) {
+		return load(key)
+	})
+}
+
+func (r *readOnlyCache) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	value, ok, err := r.Interface.GetCtx(ctx, key)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return load(ctx, key)
+	case value == NotFound:
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Namespace returns a read only view of the namespace
+func (r *readOnlyCache) Namespace(name string) Interface {
+	return ReadOnly(r.Interface.Namespace(name))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestReadOnly(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 10})
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	view := ReadOnly(cache)
+
+	if v, ok := view.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	view.Put("testkey2", "testvalue2")
+	view.Del("testkey1")
+	view.Purge()
+	if cache.Len() != 1 || !cache.Contains("testkey1") {
+		t.Fatalf("test read only writes failed, expect %v, got %v", []Key{"testkey1"}, cache.Keys())
+	}
+	if view.Add("testkey2", "testvalue2") || view.DelMulti([]Key{"testkey1"}) != 0 {
+		t.Fatalf("test read only writes failed, expect %v, got %v", false, true)
+	}
+	if err := view.PutCtx(context.Background(), "testkey2", "testvalue2"); err != ErrReadOnly {
+		t.Fatalf("test read only put failed, expect %v, got %v", ErrReadOnly, err)
+	}
+	if _, err := view.IncrementInt64("testkey3", 1); err != ErrReadOnly {
+		t.Fatalf("test read only increment failed, expect %v, got %v", ErrReadOnly, err)
+	}
+
+	v, err := view.GetOrLoad("testkey2", func(key Key) (Value, error) { return "testvalue2", nil })
+	if err != nil || v != "testvalue2" || cache.Contains("testkey2") {
+		t.Fatalf("test read only load failed, expect %v not stored, got %v/%v", "testvalue2", v, err)
+	}
+	if v, loaded := view.GetOrStore("testkey1", "testvalue3", NoExpiration); !loaded || v != "testvalue1" {
+		t.Fatalf("test read only key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+
+	if _, ok := view.(Evicter); ok {
+		t.Fatalf("test read only evicter failed, expect %v, got %v", false, true)
+	}
+	if _, ok := cache.(Evicter); !ok {
+		t.Fatalf("test evicter failed, expect %v, got %v", true, false)
+	}
+
+	if err := view.Close(); err != nil || !cache.Contains("testkey1") {
+		t.Fatalf("test read only close failed, expect %v, got %v", nil, err)
+	}
+	ns := view.Namespace("testns")
+	ns.Put("testkey1", "testvalue1")
+	if cache.Len() != 1 {
+		t.Fatalf("test read only namespace failed, expect %v, got %v", 1, cache.Len())
+	}
+}
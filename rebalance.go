@@ -0,0 +1,69 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// rebalance lends the free capacity of the shards that evicted nothing
+// since the last call to the ones that did, see Config.ShardRebalance.
+// It is only called by the rebalancer goroutine
+func (s *shardedCache) rebalance() {
+	n := len(s.shards)
+	evicted := make([]uint64, n)
+	lens := make([]int, n)
+	maxLens := make([]int, n)
+	var pressure uint64
+	for i, shard := range s.shards {
+		shard.Lock()
+		evictions := shard.stats.Evictions
+		lens[i], maxLens[i] = shard.hash.len(), shard.maxLen
+		shard.Unlock()
+		evicted[i] = evictions - s.evictions[i]
+		s.evictions[i] = evictions
+		pressure += evicted[i]
+	}
+	if pressure == 0 {
+		return
+	}
+
+	current := append([]int(nil), maxLens...)
+	pool := 0
+	for i := range s.shards {
+		if evicted[i] == 0 && maxLens[i] > lens[i] {
+			lent := (maxLens[i] - lens[i]) / 2
+			maxLens[i] -= lent
+			pool += lent
+		}
+	}
+	if pool == 0 {
+		return
+	}
+	left, hottest := pool, 0
+	for i := range s.shards {
+		share := int(uint64(pool) * evicted[i] / pressure)
+		maxLens[i] += share
+		left -= share
+		if evicted[i] > evicted[hottest] {
+			hottest = i
+		}
+	}
+	maxLens[hottest] += left
+
+	for i, shard := range s.shards {
+		if maxLens[i] != current[i] {
+			shard.Resize(maxLens[i])
+		}
+	}
+}
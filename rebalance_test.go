@@ -0,0 +1,73 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+type shardStater interface {
+	ShardStats() []Stats
+}
+
+func TestCacheShardRebalance(t *testing.T) {
+	// the hot keys all fall into the same shard, twice as many as it holds
+	probe := NewCacheWithConfig(Config{MaxLen: 1000, Shards: 4})
+	var hot []Key
+	for i := 0; len(hot) < 20; i++ {
+		key := fmt.Sprintf("testkey%d", i)
+		probe.Put(key, i)
+		if probe.(shardStater).ShardStats()[0].Len > len(hot) {
+			hot = append(hot, key)
+		}
+	}
+
+	hitRatio := func(rebalance time.Duration) float64 {
+		cache := NewCacheWithConfig(Config{MaxLen: 40, Shards: 4, ShardRebalance: rebalance})
+		defer cache.Close()
+		for round := 0; round < 30; round++ {
+			for _, key := range hot {
+				if _, ok := cache.Get(key); !ok {
+					cache.Put(key, key)
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return cache.Stats().HitRatio()
+	}
+	fixed, rebalanced := hitRatio(0), hitRatio(10*time.Millisecond)
+	if fixed != 0 || rebalanced < 0.5 {
+		t.Fatalf("test rebalance hit ratio failed, expect %v then above %v, got %v then %v", 0, 0.5, fixed, rebalanced)
+	}
+
+	cache := NewCacheWithConfig(Config{MaxLen: 40, Shards: 4, ShardRebalance: 10 * time.Millisecond})
+	defer cache.Close()
+	for _, key := range hot {
+		cache.Put(key, key)
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, key := range hot {
+		cache.Put(key, key)
+	}
+	if stats := cache.(shardStater).ShardStats(); stats[0].Len != len(hot) {
+		t.Fatalf("test rebalanced shard len failed, expect %v, got %v", len(hot), stats[0].Len)
+	}
+}
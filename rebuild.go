@@ -0,0 +1,89 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned for the operations a cache can not do
+var ErrUnsupported = errors.New("cache: not supported")
+
+// rebuilder is implemented by the in-process caches
+type rebuilder interface {
+	rebuild(policy Policy) Interface
+}
+
+// Rebuild returns a new cache with the config of c but the given eviction
+// policy, holding the entries of c with their values and deadlines. The
+// state of the old policy is not carried over, the new one starts as if
+// the entries had just been put from the oldest to the newest. Like Clone
+// the new cache has no SnapshotPath nor WAL, and c is left as it is. It
+// copies the whole cache under its lock, so it is meant for retuning a
+// warm cache once in a while, not for the hot path. Only the caches
+// created by this package can be rebuilt, the others return
+// ErrUnsupported
+func Rebuild(c Interface, policy Policy) (Interface, error) {
+	if policy < PolicyLRU || policy > PolicySample {
+		return nil, fmt.Errorf("%w: Policy %d is unknown", ErrInvalidConfig, policy)
+	}
+	r, ok := c.(rebuilder)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return r.rebuild(policy), nil
+}
+
+func (lru *lruCache) rebuild(policy Policy) Interface {
+	config := cloneConfig(lru.config)
+	config.Policy = policy
+	rebuilt := NewCacheWithConfig(config).(*lruCache)
+	lru.Lock()
+	rebuilt.Lock()
+	lru.rebuildTo(rebuilt)
+	rebuilt.Unlock()
+	lru.Unlock()
+	return rebuilt
+}
+
+func (s *shardedCache) rebuild(policy Policy) Interface {
+	config := cloneConfig(s.config)
+	config.Policy = policy
+	rebuilt := newShardedCache(config)
+	for _, shard := range s.shards {
+		shard.Lock()
+	}
+	for i, shard := range s.shards {
+		rebuilt.shards[i].Lock()
+		shard.rebuildTo(rebuilt.shards[i])
+		rebuilt.shards[i].Unlock()
+	}
+	for _, shard := range s.shards {
+		shard.Unlock()
+	}
+	return rebuilt
+}
+
+// rebuildTo copies the entries like copyTo, without the reference bits of
+// PolicyCLOCK. Both locks must be held
+func (lru *lruCache) rebuildTo(rebuilt *lruCache) {
+	lru.copyTo(rebuilt)
+	for elem := rebuilt.lst.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*listEntry).referenced = false
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestRebuild(t *testing.T) {
+	for _, shards := range []int{0, 2} {
+		clock := NewManualClock(time.Unix(0, 0))
+		cache := NewCacheWithConfig(Config{MaxLen: 4, Shards: shards, CacheTime: time.Minute, Clock: clock})
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithTimeout("testkey2", "testvalue2", time.Hour)
+		clock.Advance(time.Second)
+
+		rebuilt, err := Rebuild(cache, PolicyLFU)
+		if err != nil {
+			t.Fatalf("test shards %d rebuild failed, expect %v, got %v", shards, nil, err)
+		}
+		tests := []struct {
+			key   string
+			value string
+			ttl   time.Duration
+		}{
+			{"testkey1", "testvalue1", time.Minute - time.Second},
+			{"testkey2", "testvalue2", time.Hour - time.Second},
+		}
+		for _, test := range tests {
+			if v, _ := rebuilt.Peek(test.key); v != test.value {
+				t.Fatalf("test shards %d key %s failed, expect %v, got %v", shards, test.key, test.value, v)
+			}
+			if left, _ := rebuilt.TTL(test.key); left != test.ttl {
+				t.Fatalf("test shards %d key %s ttl failed, expect %v, got %v", shards, test.key, test.ttl, left)
+			}
+		}
+
+		// the rebuilt cache is independent of the old one
+		rebuilt.Del("testkey1")
+		if !cache.Contains("testkey1") {
+			t.Fatalf("test shards %d old key %s failed, expect %v, got %v", shards, "testkey1", true, false)
+		}
+		cache.Close()
+		rebuilt.Close()
+	}
+
+	// the frequently used keys survive once rebuilt for LFU
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	rebuilt, _ := Rebuild(cache, PolicyLFU)
+	rebuilt.Get("testkey1")
+	rebuilt.Get("testkey1")
+	rebuilt.Get("testkey2")
+	rebuilt.Put("testkey3", "testvalue3")
+	if !rebuilt.Contains("testkey1") || rebuilt.Contains("testkey2") {
+		t.Fatalf("test lfu eviction failed, expect %v, got %v", []Key{"testkey3", "testkey1"}, rebuilt.Keys())
+	}
+
+	if _, err := Rebuild(cache, Policy(100)); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("test unknown policy failed, expect %v, got %v", ErrInvalidConfig, err)
+	}
+	if _, err := Rebuild(cache.Namespace("testns"), PolicyLFU); err != ErrUnsupported {
+		t.Fatalf("test namespace failed, expect %v, got %v", ErrUnsupported, err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rediscache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+
+	"github.com/leopoldxx/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidation is the message published for the deleted keys, origin
+// tells the subscriber of the publishing Invalidator to skip it
+type invalidation struct {
+	Origin string
+	Keys   []cache.Key
+}
+
+type invalidator struct {
+	client  redis.UniversalClient
+	channel string
+	origin  string
+}
+
+// NewInvalidator will create a cache.Invalidator publishing the keys on
+// the Redis channel, encoded with gob like the values of GobCodec, so the
+// keys of custom types must be registered with gob.Register. The messages
+// that can not be decoded are dropped
+func NewInvalidator(client redis.UniversalClient, channel string) cache.Invalidator {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	return &invalidator{client: client, channel: channel, origin: hex.EncodeToString(origin)}
+}
+
+func (i *invalidator) Publish(keys []cache.Key) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&invalidation{Origin: i.origin, Keys: keys}); err != nil {
+		return err
+	}
+	return i.client.Publish(context.Background(), i.channel, buf.String()).Err()
+}
+
+// Subscribe returns once Redis has confirmed the subscription, fn is
+// called in a goroutine of its own
+func (i *invalidator) Subscribe(fn func(keys []cache.Key)) (func(), error) {
+	ctx := context.Background()
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range pubsub.Channel() {
+			var inv invalidation
+			if err := gob.NewDecoder(bytes.NewReader([]byte(msg.Payload))).Decode(&inv); err != nil {
+				continue
+			}
+			if inv.Origin != i.origin {
+				fn(inv.Keys)
+			}
+		}
+	}()
+	return func() {
+		pubsub.Close()
+		<-done
+	}, nil
+}
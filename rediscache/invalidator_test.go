@@ -0,0 +1,61 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rediscache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/leopoldxx/cache"
+	"github.com/leopoldxx/cache/rediscache"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInvalidator(t *testing.T) {
+	server := miniredis.RunT(t)
+	onError := func(err error) { t.Fatalf("test invalidator failed, got %v", err) }
+	newCache := func(callback OnEvictedWithReason) Interface {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		return NewCacheWithConfig(Config{MaxLen: 100, CallbackWithReason: callback, OnInvalidatorError: onError,
+			Invalidator: rediscache.NewInvalidator(client, "test:invalidations")})
+	}
+	deleted := make(chan Key, 10)
+	cache1 := newCache(nil)
+	defer cache1.Close()
+	cache2 := newCache(func(key Key, value Value, reason EvictionReason) {
+		if reason == ReasonDeleted {
+			deleted <- key
+		}
+	})
+	defer cache2.Close()
+
+	cache1.Put("testkey1", "testvalue1")
+	cache2.Put("testkey1", "testvalue1")
+	cache2.Put(2, "testvalue2")
+	cache1.DelMulti([]Key{"testkey1", 2})
+	for _, key := range []Key{"testkey1", 2} {
+		select {
+		case got := <-deleted:
+			if got != key {
+				t.Fatalf("test key %v failed, expect %v, got %v", key, key, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("test key %v failed, expect it deleted, got it kept", key)
+		}
+	}
+}
@@ -0,0 +1,984 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rediscache implements cache.Interface over Redis, so that the
+// code written against the in-process cache can share its entries with
+// other processes by switching the constructor.
+package rediscache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/leopoldxx/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// Codec converts the values to and from the bytes stored in Redis
+type Codec interface {
+	Marshal(v cache.Value) ([]byte, error)
+	Unmarshal(data []byte) (cache.Value, error)
+}
+
+// GobCodec is the default Codec, values of custom types must be
+// registered with gob.Register
+type GobCodec struct{}
+
+// Marshal will gob encode the value as an interface
+func (GobCodec) Marshal(v cache.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+// Unmarshal will decode a value encoded by Marshal
+func (GobCodec) Unmarshal(data []byte) (cache.Value, error) {
+	var v cache.Value
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// Config of the Redis cache
+type Config struct {
+	Client redis.UniversalClient
+	// Prefix is prepended to the keys, so that several caches can share
+	// a database. The methods listing the keys only see the ones with the
+	// prefix
+	Prefix string
+	// CacheTime is the lifetime of the entries added by Put,
+	// cache.DefaultCacheTime if not set
+	CacheTime time.Duration
+	// NegativeCacheTime is the lifetime of the cache.NotFound entries,
+	// CacheTime if not set
+	NegativeCacheTime time.Duration
+	// Codec of the values, GobCodec if nil
+	Codec Codec
+	// OnError will be called with the errors of the Redis commands, which
+	// the methods without an error result turn into misses
+	OnError func(err error)
+	// Equal compares the values for CompareAndSwap, cache.DefaultEqual if
+	// nil
+	Equal func(a, b cache.Value) bool
+}
+
+type redisCache struct {
+	client            redis.UniversalClient
+	prefix            string
+	cacheTime         time.Duration
+	negativeCacheTime time.Duration
+	codec             Codec
+	onError           func(err error)
+	equal             func(a, b cache.Value) bool
+	// view is set for the namespaces, which do not own the client
+	view bool
+
+	bypass int32
+	closed int32
+	hits   uint64
+	misses uint64
+	loads  cache.LoadGroup
+	locks  *cache.StripedLocks
+}
+
+// New will create a cache over the Redis client of the config. The keys
+// are stored with their type, so 1 and "1" are different keys, but the
+// methods listing them, like Keys and Range, return the keys of types
+// other than the strings and the integers as their name. Closing the
+// cache closes the client
+func New(config Config) cache.Interface {
+	if config.CacheTime < time.Millisecond {
+		config.CacheTime = cache.DefaultCacheTime
+	}
+	if config.NegativeCacheTime <= 0 {
+		config.NegativeCacheTime = config.CacheTime
+	}
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	if config.Equal == nil {
+		config.Equal = cache.DefaultEqual
+	}
+	return &redisCache{
+		client:            config.Client,
+		prefix:            config.Prefix,
+		cacheTime:         config.CacheTime,
+		negativeCacheTime: config.NegativeCacheTime,
+		codec:             config.Codec,
+		onError:           config.OnError,
+		equal:             config.Equal,
+		locks:             new(cache.StripedLocks),
+	}
+}
+
+func (rc *redisCache) name(key cache.Key) string {
+	if s, ok := key.(string); ok {
+		return rc.prefix + "s:" + s
+	}
+	return rc.prefix + fmt.Sprintf("%T:%v", key, key)
+}
+
+// key reverses name for the strings and the integers, the other names are
+// returned without the prefix
+func (rc *redisCache) key(name string) cache.Key {
+	name = strings.TrimPrefix(name, rc.prefix)
+	typ, s, ok := strings.Cut(name, ":")
+	if !ok {
+		return name
+	}
+	switch typ {
+	case "s":
+		return s
+	case "int":
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+	case "int64":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "uint64":
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return n
+		}
+	}
+	return name
+}
+
+// fail reports err to OnError, the errors of the contexts given to the
+// Ctx methods are returned to their callers instead
+func (rc *redisCache) fail(err error) {
+	if err == nil || err == redis.Nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	if rc.onError != nil {
+		rc.onError(err)
+	}
+}
+
+func (rc *redisCache) bypassed() bool {
+	return atomic.LoadInt32(&rc.bypass) == 1 || atomic.LoadInt32(&rc.closed) == 1
+}
+
+// expiration turns a timeout into the expiration of a SET, which has no
+// NoExpiration but 0
+func expiration(t time.Duration) time.Duration {
+	if t == cache.NoExpiration {
+		return 0
+	}
+	if t < time.Second {
+		return time.Second
+	}
+	return t
+}
+
+func (rc *redisCache) set(ctx context.Context, key cache.Key, value cache.Value, t time.Duration) {
+	if rc.bypassed() {
+		return
+	}
+	data, err := rc.codec.Marshal(value)
+	if err != nil {
+		rc.fail(err)
+		return
+	}
+	rc.fail(rc.client.Set(ctx, rc.name(key), data, expiration(t)).Err())
+}
+
+// PutCtx returns the error of ctx if it is done before the SET completes
+func (rc *redisCache) PutCtx(ctx context.Context, key cache.Key, value cache.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rc.set(ctx, key, value, rc.cacheTime)
+	return ctx.Err()
+}
+
+func (rc *redisCache) Put(key cache.Key, value cache.Value) {
+	rc.set(context.Background(), key, value, rc.cacheTime)
+}
+
+// PutNotFound sets cache.NotFound for NegativeCacheTime
+func (rc *redisCache) PutNotFound(key cache.Key) {
+	rc.set(context.Background(), key, cache.NotFound, rc.negativeCacheTime)
+}
+
+func (rc *redisCache) PutWithTimeout(key cache.Key, value cache.Value, t time.Duration) {
+	rc.set(context.Background(), key, value, t)
+}
+
+// PutWithDeadline sets the value and its deadline in a transaction, a
+// deadline already passed removes the key
+func (rc *redisCache) PutWithDeadline(key cache.Key, value cache.Value, deadline time.Time) {
+	if deadline.IsZero() {
+		rc.set(context.Background(), key, value, cache.NoExpiration)
+		return
+	}
+	if rc.bypassed() {
+		return
+	}
+	data, err := rc.codec.Marshal(value)
+	if err != nil {
+		rc.fail(err)
+		return
+	}
+	ctx := context.Background()
+	name := rc.name(key)
+	_, err = rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, name, data, 0)
+		pipe.PExpireAt(ctx, name, deadline)
+		return nil
+	})
+	rc.fail(err)
+}
+
+func (rc *redisCache) PutString(key string, value cache.Value) {
+	rc.Put(key, value)
+}
+
+func (rc *redisCache) PutInt(key int64, value cache.Value) {
+	rc.Put(key, value)
+}
+
+// PutMulti sets the keys in a single pipeline
+func (rc *redisCache) PutMulti(entries map[cache.Key]cache.Value) {
+	rc.setMulti(entries, rc.cacheTime)
+}
+
+func (rc *redisCache) PutMultiWithTimeout(entries map[cache.Key]cache.Value, t time.Duration) {
+	rc.setMulti(entries, t)
+}
+
+func (rc *redisCache) setMulti(entries map[cache.Key]cache.Value, t time.Duration) {
+	if rc.bypassed() || len(entries) == 0 {
+		return
+	}
+	ctx := context.Background()
+	_, err := rc.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range entries {
+			data, err := rc.codec.Marshal(value)
+			if err != nil {
+				rc.fail(err)
+				continue
+			}
+			pipe.Set(ctx, rc.name(key), data, expiration(t))
+		}
+		return nil
+	})
+	rc.fail(err)
+}
+
+// lookup reads the value of the key, counting the hit or miss if count
+func (rc *redisCache) lookup(ctx context.Context, key cache.Key, count bool) (cache.Value, bool) {
+	if rc.bypassed() {
+		return nil, false
+	}
+	value, ok := rc.decode(rc.client.Get(ctx, rc.name(key)))
+	if count && ok {
+		atomic.AddUint64(&rc.hits, 1)
+	} else if count {
+		atomic.AddUint64(&rc.misses, 1)
+	}
+	return value, ok
+}
+
+func (rc *redisCache) decode(cmd *redis.StringCmd) (cache.Value, bool) {
+	data, err := cmd.Bytes()
+	if err != nil {
+		rc.fail(err)
+		return nil, false
+	}
+	value, err := rc.codec.Unmarshal(data)
+	if err != nil {
+		rc.fail(err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (rc *redisCache) Get(key cache.Key) (cache.Value, bool) {
+	return rc.lookup(context.Background(), key, true)
+}
+
+// GetCtx returns the error of ctx if it is done before the GET completes
+func (rc *redisCache) GetCtx(ctx context.Context, key cache.Key) (cache.Value, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, ok := rc.lookup(ctx, key, true)
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return value, ok, nil
+}
+
+// GetMulti reads the keys with a single MGET
+func (rc *redisCache) GetMulti(keys []cache.Key) map[cache.Key]cache.Value {
+	values := make(map[cache.Key]cache.Value, len(keys))
+	if rc.bypassed() || len(keys) == 0 {
+		return values
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = rc.name(key)
+	}
+	results, err := rc.client.MGet(context.Background(), names...).Result()
+	if err != nil {
+		rc.fail(err)
+		atomic.AddUint64(&rc.misses, uint64(len(keys)))
+		return values
+	}
+	for i, result := range results {
+		s, ok := result.(string)
+		if ok {
+			var value cache.Value
+			if value, err = rc.codec.Unmarshal([]byte(s)); err == nil {
+				values[keys[i]] = value
+			} else {
+				rc.fail(err)
+				ok = false
+			}
+		}
+		if ok {
+			atomic.AddUint64(&rc.hits, 1)
+		} else {
+			atomic.AddUint64(&rc.misses, 1)
+		}
+	}
+	return values
+}
+
+func (rc *redisCache) Peek(key cache.Key) (cache.Value, bool) {
+	return rc.lookup(context.Background(), key, false)
+}
+
+// GetEntry returns the value and the deadline of the key, Redis keeps no
+// creation nor access time, and no hit count
+func (rc *redisCache) GetEntry(key cache.Key) (cache.Entry, bool) {
+	value, ok := rc.Peek(key)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	left, ok := rc.TTL(key)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	entry := cache.Entry{Key: key, Value: value}
+	if left != cache.NoExpiration {
+		entry.Deadline = time.Now().Add(left)
+	}
+	return entry, true
+}
+
+func (rc *redisCache) GetString(key string) (cache.Value, bool) {
+	return rc.Get(key)
+}
+
+func (rc *redisCache) GetInt(key int64) (cache.Value, bool) {
+	return rc.Get(key)
+}
+
+func (rc *redisCache) Contains(key cache.Key) bool {
+	n, err := rc.client.Exists(context.Background(), rc.name(key)).Result()
+	rc.fail(err)
+	return n == 1
+}
+
+func (rc *redisCache) TTL(key cache.Key) (time.Duration, bool) {
+	left, err := rc.client.PTTL(context.Background(), rc.name(key)).Result()
+	if err != nil {
+		rc.fail(err)
+		return 0, false
+	}
+	switch left {
+	case -2:
+		return 0, false
+	case -1:
+		return cache.NoExpiration, true
+	}
+	return left, true
+}
+
+func (rc *redisCache) Touch(key cache.Key, d time.Duration) bool {
+	ctx := context.Background()
+	var ok bool
+	var err error
+	if d == cache.NoExpiration {
+		// PERSIST reports false for the keys without a deadline
+		if ok, err = rc.client.Persist(ctx, rc.name(key)).Result(); err == nil && !ok {
+			ok = rc.Contains(key)
+		}
+	} else {
+		ok, err = rc.client.PExpire(ctx, rc.name(key), expiration(d)).Result()
+	}
+	rc.fail(err)
+	return ok
+}
+
+// GetOrStore sets the key with SET NX, and reads the value it already
+// had if it was not set
+func (rc *redisCache) GetOrStore(key cache.Key, def cache.Value, t time.Duration) (cache.Value, bool) {
+	if rc.bypassed() {
+		return def, false
+	}
+	data, err := rc.codec.Marshal(def)
+	if err != nil {
+		rc.fail(err)
+		return def, false
+	}
+	ctx := context.Background()
+	for {
+		set, err := rc.client.SetNX(ctx, rc.name(key), data, expiration(t)).Result()
+		if err != nil {
+			rc.fail(err)
+			return def, false
+		}
+		if set {
+			return def, false
+		}
+		// the key may expire between SET NX and GET, then try again
+		if value, ok := rc.Get(key); ok {
+			return value, true
+		}
+	}
+}
+
+// LockKey serializes the callers of this process by key, the other
+// processes are not locked out
+func (rc *redisCache) LockKey(key cache.Key) func() {
+	return rc.locks.LockKey(rc.name(key))
+}
+
+// Add sets the key with SET NX for the default lifetime
+func (rc *redisCache) Add(key cache.Key, value cache.Value) bool {
+	if rc.bypassed() {
+		return false
+	}
+	data, err := rc.codec.Marshal(value)
+	if err != nil {
+		rc.fail(err)
+		return false
+	}
+	added, err := rc.client.SetNX(context.Background(), rc.name(key), data, expiration(rc.cacheTime)).Result()
+	rc.fail(err)
+	return added
+}
+
+// Replace sets the key with SET XX for the default lifetime
+func (rc *redisCache) Replace(key cache.Key, value cache.Value) bool {
+	if rc.bypassed() {
+		return false
+	}
+	data, err := rc.codec.Marshal(value)
+	if err != nil {
+		rc.fail(err)
+		return false
+	}
+	replaced, err := rc.client.SetXX(context.Background(), rc.name(key), data, expiration(rc.cacheTime)).Result()
+	rc.fail(err)
+	return replaced
+}
+
+// IncrementInt64 watches the key while it decodes and increments its
+// value, and tries again if another client changes the key meanwhile.
+// The values are encoded by the codec, so INCRBY can not be used
+func (rc *redisCache) IncrementInt64(key cache.Key, delta int64) (int64, error) {
+	if rc.bypassed() {
+		return delta, nil
+	}
+	ctx := context.Background()
+	name := rc.name(key)
+	for {
+		var n int64
+		err := rc.client.Watch(ctx, func(tx *redis.Tx) error {
+			n = delta
+			value, ok := rc.decode(tx.Get(ctx, name))
+			if ok {
+				i, isInt := cache.ToInt64(value)
+				if !isInt {
+					return cache.ErrNotInteger
+				}
+				n += i
+			}
+			data, err := rc.codec.Marshal(n)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if ok {
+					pipe.SetArgs(ctx, name, data, redis.SetArgs{KeepTTL: true})
+				} else {
+					pipe.Set(ctx, name, data, expiration(rc.cacheTime))
+				}
+				return nil
+			})
+			return err
+		}, name)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			if err != cache.ErrNotInteger {
+				rc.fail(err)
+			}
+			return 0, err
+		}
+		return n, nil
+	}
+}
+
+func (rc *redisCache) DecrementInt64(key cache.Key, delta int64) (int64, error) {
+	return rc.IncrementInt64(key, -delta)
+}
+
+// CompareAndSwap watches the key while it compares its value, the swap
+// fails if another client changes the key meanwhile. The key keeps its
+// TTL
+func (rc *redisCache) CompareAndSwap(key cache.Key, old, new cache.Value) bool {
+	if rc.bypassed() {
+		return false
+	}
+	data, err := rc.codec.Marshal(new)
+	if err != nil {
+		rc.fail(err)
+		return false
+	}
+	ctx := context.Background()
+	name := rc.name(key)
+	swapped := false
+	err = rc.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, ok := rc.decode(tx.Get(ctx, name))
+		if !ok || !rc.equal(current, old) {
+			return nil
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, name, data, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		swapped = err == nil
+		return err
+	}, name)
+	if err != redis.TxFailedErr {
+		rc.fail(err)
+	}
+	return swapped
+}
+
+// GetOrLoad shares the loads of the same key within this process only
+func (rc *redisCache) GetOrLoad(key cache.Key, load cache.LoadFunc) (cache.Value, error) {
+	return rc.GetOrLoadCtx(context.Background(), key, func(ctx context.Context, key cache.Key) (cache.Value, error) {
+		return load(key)
+	})
+}
+
+// GetOrLoadCtx is GetOrLoad with a context, a caller waiting for the load
+// of another one stops waiting when its ctx is done. A panic of load is
+// returned as a *cache.LoaderPanic
+func (rc *redisCache) GetOrLoadCtx(ctx context.Context, key cache.Key, load cache.LoadCtxFunc) (cache.Value, error) {
+	value, ok, err := rc.GetCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if value == cache.NotFound {
+			return nil, cache.ErrNotFound
+		}
+		return value, nil
+	}
+	return rc.loads.Do(ctx, rc.name(key), func() (cache.Value, error) {
+		value, err := load(ctx, key)
+		if err == nil {
+			rc.set(ctx, key, value, rc.cacheTime)
+		} else if errors.Is(err, cache.ErrNotFound) {
+			rc.set(ctx, key, cache.NotFound, rc.negativeCacheTime)
+		}
+		return value, err
+	})
+}
+
+func (rc *redisCache) Del(key cache.Key) cache.Value {
+	value, _ := rc.DelE(key)
+	return value
+}
+
+// DelMulti deletes the keys with a single DEL
+func (rc *redisCache) DelMulti(keys []cache.Key) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = rc.name(key)
+	}
+	n, err := rc.client.Del(context.Background(), names...).Result()
+	rc.fail(err)
+	return int(n)
+}
+
+// DelPrefix deletes the keys starting with prefix found by a SCAN of the
+// whole database, the keys put during the scan may or may not be deleted
+func (rc *redisCache) DelPrefix(prefix string) int {
+	ctx := context.Background()
+	n := 0
+	rc.scanMatch(globEscaper.Replace(rc.prefix+"s:"+prefix)+"*", "string", func(names []string) bool {
+		deleted, err := rc.client.Del(ctx, names...).Result()
+		rc.fail(err)
+		n += int(deleted)
+		return err == nil
+	})
+	return n
+}
+
+// DelFunc deletes the keys fn returns true for in a Range, the keys put
+// or deleted meanwhile may or may not be matched
+func (rc *redisCache) DelFunc(fn func(key cache.Key, value cache.Value) bool) int {
+	var keys []cache.Key
+	rc.Range(func(key cache.Key, value cache.Value) bool {
+		if fn(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return rc.DelMulti(keys)
+}
+
+// tagName is the name of the set of the keys tagged with tag
+func (rc *redisCache) tagName(tag string) string {
+	return rc.prefix + "tag:" + tag
+}
+
+// PutTagged sets the value and adds its key to a set per tag in a
+// transaction. The sets do not expire and are not updated when the key
+// is put again, so InvalidateTag deletes the keys put with the tag once,
+// whatever they were put with since. The keys of the cache should not
+// start with "tag:"
+func (rc *redisCache) PutTagged(key cache.Key, value cache.Value, tags ...string) {
+	if rc.bypassed() {
+		return
+	}
+	data, err := rc.codec.Marshal(value)
+	if err != nil {
+		rc.fail(err)
+		return
+	}
+	ctx := context.Background()
+	name := rc.name(key)
+	_, err = rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, name, data, expiration(rc.cacheTime))
+		for _, tag := range tags {
+			pipe.SAdd(ctx, rc.tagName(tag), name)
+		}
+		return nil
+	})
+	rc.fail(err)
+}
+
+// InvalidateTag pops the keys from the set of the tag until it is empty
+// and deletes them, the keys tagged meanwhile are deleted as well
+func (rc *redisCache) InvalidateTag(tag string) int {
+	ctx := context.Background()
+	n := 0
+	for {
+		names, err := rc.client.SPopN(ctx, rc.tagName(tag), 100).Result()
+		if err != nil {
+			rc.fail(err)
+			return n
+		}
+		if len(names) == 0 {
+			return n
+		}
+		deleted, err := rc.client.Del(ctx, names...).Result()
+		if err != nil {
+			rc.fail(err)
+			return n
+		}
+		n += int(deleted)
+	}
+}
+
+func (rc *redisCache) DelE(key cache.Key) (cache.Value, bool) {
+	ctx := context.Background()
+	name := rc.name(key)
+	var get *redis.StringCmd
+	_, err := rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, name)
+		pipe.Del(ctx, name)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		rc.fail(err)
+		return nil, false
+	}
+	return rc.decode(get)
+}
+
+// scan calls fn with the names of the keys of the cache of the type typ,
+// any type if empty, until it returns false
+func (rc *redisCache) scan(typ string, fn func(names []string) bool) {
+	rc.scanMatch(globEscaper.Replace(rc.prefix)+"*", typ, fn)
+}
+
+// globEscaper escapes the special characters of the MATCH patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// scanMatch is scan for the names matching the pattern match
+func (rc *redisCache) scanMatch(match, typ string, fn func(names []string) bool) {
+	ctx := context.Background()
+	var cursor uint64
+	for {
+		names, next, err := rc.client.ScanType(ctx, cursor, match, 100, typ).Result()
+		if err != nil {
+			rc.fail(err)
+			return
+		}
+		if len(names) > 0 && !fn(names) {
+			return
+		}
+		if cursor = next; cursor == 0 {
+			return
+		}
+	}
+}
+
+// Len counts the keys with a SCAN of the whole database
+func (rc *redisCache) Len() int {
+	n := 0
+	rc.scan("string", func(names []string) bool {
+		n += len(names)
+		return true
+	})
+	return n
+}
+
+func (rc *redisCache) Keys() []cache.Key {
+	var keys []cache.Key
+	rc.scan("string", func(names []string) bool {
+		for _, name := range names {
+			keys = append(keys, rc.key(name))
+		}
+		return true
+	})
+	return keys
+}
+
+// Stats counts the hits and misses of this process only
+func (rc *redisCache) Stats() cache.Stats {
+	return cache.Stats{
+		Hits:   atomic.LoadUint64(&rc.hits),
+		Misses: atomic.LoadUint64(&rc.misses),
+		Len:    rc.Len(),
+	}
+}
+
+// Range visits the entries in the order of a SCAN, the entries put or
+// deleted during the iteration may or may not be seen
+func (rc *redisCache) Range(fn func(key cache.Key, value cache.Value) bool) {
+	ctx := context.Background()
+	rc.scan("string", func(names []string) bool {
+		values, err := rc.client.MGet(ctx, names...).Result()
+		if err != nil {
+			rc.fail(err)
+			return false
+		}
+		for i, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			value, err := rc.codec.Unmarshal([]byte(s))
+			if err != nil {
+				rc.fail(err)
+				continue
+			}
+			if !fn(rc.key(names[i]), value) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (rc *redisCache) SetBypass(bypass bool) {
+	var v int32
+	if bypass {
+		v = 1
+	}
+	atomic.StoreInt32(&rc.bypass, v)
+}
+
+// localCache is the in-process cache the entries are copied through, the
+// caches of NewCacheWithConfig implement the optional interfaces
+type localCache interface {
+	cache.Interface
+	cache.Persister
+	cache.Warmer
+}
+
+// local returns an in-process cache without bounds, used to convert the
+// entries from and to the formats of the cache package
+func (rc *redisCache) local() localCache {
+	return cache.NewCacheWithConfig(cache.Config{MaxLen: math.MaxInt, CacheTime: rc.cacheTime}).(localCache)
+}
+
+// snapshot copies the live entries into an in-process cache
+func (rc *redisCache) snapshot() localCache {
+	local := rc.local()
+	now := time.Now()
+	rc.Range(func(key cache.Key, value cache.Value) bool {
+		left, ok := rc.TTL(key)
+		switch {
+		case !ok:
+		case left == cache.NoExpiration:
+			local.PutWithTimeout(key, value, cache.NoExpiration)
+		default:
+			local.PutWithDeadline(key, value, now.Add(left))
+		}
+		return true
+	})
+	return local
+}
+
+// restore puts the live entries of an in-process cache
+func (rc *redisCache) restore(local cache.Interface) {
+	now := time.Now()
+	local.Range(func(key cache.Key, value cache.Value) bool {
+		left, ok := local.TTL(key)
+		switch {
+		case !ok:
+		case left == cache.NoExpiration:
+			rc.PutWithTimeout(key, value, cache.NoExpiration)
+		default:
+			rc.PutWithDeadline(key, value, now.Add(left))
+		}
+		return true
+	})
+	local.Close()
+}
+
+// ReplayWAL puts the entries a write-ahead log ends up with, the deletes
+// in the log only cancel the puts before them in the log
+func (rc *redisCache) ReplayWAL(r io.Reader) error {
+	local := rc.local()
+	if err := local.ReplayWAL(r); err != nil {
+		return err
+	}
+	rc.restore(local)
+	return nil
+}
+
+func (rc *redisCache) SaveTo(w io.Writer) error {
+	local := rc.snapshot()
+	defer local.Close()
+	return local.SaveTo(w)
+}
+
+func (rc *redisCache) ExportJSON(w io.Writer, codec cache.JSONCodec) error {
+	local := rc.snapshot()
+	defer local.Close()
+	return local.ExportJSON(w, codec)
+}
+
+func (rc *redisCache) ImportJSON(r io.Reader, codec cache.JSONCodec) error {
+	local := rc.local()
+	if err := local.ImportJSON(r, codec); err != nil {
+		return err
+	}
+	rc.restore(local)
+	return nil
+}
+
+func (rc *redisCache) Warmup(keys []cache.Key, loader cache.Loader, parallelism int) error {
+	local := rc.local()
+	err := local.Warmup(keys, loader, parallelism)
+	rc.restore(local)
+	return err
+}
+
+// Flush has nothing to write, the puts reach Redis before they return
+func (rc *redisCache) Flush() {}
+
+// Purge deletes the keys of the cache batch by batch as they are scanned,
+// the keys put meanwhile may be kept
+func (rc *redisCache) Purge() {
+	ctx := context.Background()
+	rc.scan("", func(names []string) bool {
+		err := rc.client.Del(ctx, names...).Err()
+		rc.fail(err)
+		return err == nil
+	})
+}
+
+// Close closes the client, and returns nil when called again. The
+// methods skipped in bypass mode are skipped once it is closed as well,
+// the others fail with the errors of the client
+func (rc *redisCache) Close() error {
+	if !atomic.CompareAndSwapInt32(&rc.closed, 0, 1) || rc.view {
+		return nil
+	}
+	return rc.client.Close()
+}
+
+// Clone copies the live entries into an in-process cache without bounds,
+// Redis does not keep the recency of the keys
+func (rc *redisCache) Clone() cache.Interface {
+	return rc.snapshot()
+}
+
+// Merge puts the live entries of other, like the Merge of the cache
+// package, a key is read and written in separate commands
+func (rc *redisCache) Merge(other cache.Interface, conflict func(key cache.Key, a, b cache.Value) cache.Value) {
+	other.Range(func(key cache.Key, theirs cache.Value) bool {
+		left, ok := other.TTL(key)
+		if !ok {
+			return true
+		}
+		value := theirs
+		if mine, ok := rc.Peek(key); ok {
+			if conflict != nil {
+				value = conflict(key, mine, theirs)
+			}
+			if kept, ok := rc.TTL(key); ok {
+				left = cache.Fresher(left, kept)
+			}
+		}
+		rc.PutWithTimeout(key, value, left)
+		return true
+	})
+}
+
+// Namespace returns a view whose keys are prefixed with the name and a
+// colon after the prefix of the cache, so the keys of the cache itself
+// should not look like them. Closing the view leaves the client open
+func (rc *redisCache) Namespace(name string) cache.Interface {
+	return &redisCache{
+		client:            rc.client,
+		prefix:            rc.prefix + name + ":",
+		cacheTime:         rc.cacheTime,
+		negativeCacheTime: rc.negativeCacheTime,
+		codec:             rc.codec,
+		onError:           rc.onError,
+		equal:             rc.equal,
+		view:              true,
+		locks:             rc.locks,
+	}
+}
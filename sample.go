@@ -0,0 +1,101 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"math/rand"
+	"time"
+)
+
+// DefaultEvictionSamples is the sample size of PolicySample
+const DefaultEvictionSamples = 5
+
+// samplePolicy keeps the elements in a slice to pick random samples, the
+// victim is the least recently used element of the sample
+type samplePolicy struct {
+	items   []*sampleItem
+	index   map[*list.Element]*sampleItem
+	samples int
+	tick    uint64
+	rnd     *rand.Rand
+}
+
+type sampleItem struct {
+	elem *list.Element
+	tick uint64
+	pos  int
+}
+
+func newSamplePolicy(samples int) *samplePolicy {
+	if samples <= 0 {
+		samples = DefaultEvictionSamples
+	}
+	return &samplePolicy{
+		index:   map[*list.Element]*sampleItem{},
+		samples: samples,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (p *samplePolicy) adding(key Key) {}
+
+func (p *samplePolicy) add(elem *list.Element) {
+	p.tick++
+	item := &sampleItem{elem: elem, tick: p.tick, pos: len(p.items)}
+	p.index[elem] = item
+	p.items = append(p.items, item)
+}
+
+func (p *samplePolicy) hit(elem *list.Element) {
+	if item, ok := p.index[elem]; ok {
+		p.tick++
+		item.tick = p.tick
+	}
+}
+
+func (p *samplePolicy) remove(elem *list.Element, reason EvictionReason) {
+	item, ok := p.index[elem]
+	if !ok {
+		return
+	}
+	delete(p.index, elem)
+	last := p.items[len(p.items)-1]
+	p.items[item.pos] = last
+	last.pos = item.pos
+	p.items[len(p.items)-1] = nil
+	p.items = p.items[:len(p.items)-1]
+}
+
+func (p *samplePolicy) victim() *list.Element {
+	if len(p.items) == 0 {
+		return nil
+	}
+	var oldest *sampleItem
+	for i := 0; i < p.samples; i++ {
+		item := p.items[p.rnd.Intn(len(p.items))]
+		if oldest == nil || item.tick < oldest.tick {
+			oldest = item
+		}
+	}
+	return oldest.elem
+}
+
+func (p *samplePolicy) reset() {
+	p.items = nil
+	p.index = map[*list.Element]*sampleItem{}
+}
@@ -0,0 +1,448 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// shardedCache spreads the keys over several independent caches by their
+// hash, so that operations on different shards do not contend on a lock
+type shardedCache struct {
+	shards   []*lruCache
+	snapshot *snapshotFile
+	config   Config
+
+	stopInvalidations func()
+
+	rebalancer *sweeper
+	// evictions are the evictions of the shards at the last rebalance
+	evictions []uint64
+}
+
+// lockedWriter serializes the writes of the shards to a shared writer
+type lockedWriter struct {
+	w io.Writer
+	sync.Mutex
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.Lock()
+	defer lw.Unlock()
+	return lw.w.Write(p)
+}
+
+func newShardedCache(config Config) *shardedCache {
+	n := config.Shards
+	s := &shardedCache{shards: make([]*lruCache, n), snapshot: newSnapshotFile(config), config: config}
+	config.Shards = 0
+	config.CallbackRateLimit /= float64(n)
+	config.CardinalityThreshold /= uint64(n)
+	config.MaxWeight /= int64(n)
+	if config.WAL != nil {
+		config.WAL = &lockedWriter{w: config.WAL}
+	}
+	maxLen := config.MaxLen
+	config.SnapshotPath = ""
+	// the shards only publish, the keys received are split among them
+	invalidator := config.Invalidator
+	config.Invalidator = nil
+	for i := range s.shards {
+		config.MaxLen = shardMaxLen(maxLen, n, i)
+		s.shards[i] = NewCacheWithConfig(config).(*lruCache)
+		s.shards[i].invalidator = invalidator
+	}
+	if s.snapshot != nil {
+		s.snapshot.load(s)
+	}
+	if invalidator != nil {
+		s.stopInvalidations = subscribe(invalidator, s.invalidate, config.OnInvalidatorError)
+	}
+	if config.ShardRebalance > 0 && s.shards[0].maxLen != unboundedLen {
+		s.evictions = make([]uint64, n)
+		s.rebalancer = newSweeper(config.ShardRebalance, s.rebalance)
+	}
+	return s
+}
+
+// shardMaxLen splits maxLen among n shards as evenly as possible, each
+// shard gets at least one entry even if there are more shards than maxLen
+func shardMaxLen(maxLen, n, i int) int {
+	if maxLen < n && maxLen > 0 {
+		return 1
+	}
+	if i < maxLen%n {
+		return maxLen/n + 1
+	}
+	return maxLen / n
+}
+
+// closed tells whether the cache is closed, the shards are closed all
+// together
+func (s *shardedCache) closed() bool {
+	return s.shards[0].closed()
+}
+
+func (s *shardedCache) shard(key Key) *lruCache {
+	return s.shards[hashKey(key)%uint64(len(s.shards))]
+}
+
+func (s *shardedCache) Put(key Key, value Value) {
+	s.shard(key).Put(key, value)
+}
+
+func (s *shardedCache) PutWithTimeout(key Key, value Value, t time.Duration) {
+	s.shard(key).PutWithTimeout(key, value, t)
+}
+
+func (s *shardedCache) PutWithDeadline(key Key, value Value, deadline time.Time) {
+	s.shard(key).PutWithDeadline(key, value, deadline)
+}
+
+func (s *shardedCache) Get(key Key) (Value, bool) {
+	return s.shard(key).Get(key)
+}
+
+func (s *shardedCache) Peek(key Key) (Value, bool) {
+	return s.shard(key).Peek(key)
+}
+
+func (s *shardedCache) GetEntry(key Key) (Entry, bool) {
+	return s.shard(key).GetEntry(key)
+}
+
+func (s *shardedCache) Contains(key Key) bool {
+	return s.shard(key).Contains(key)
+}
+
+func (s *shardedCache) TTL(key Key) (time.Duration, bool) {
+	return s.shard(key).TTL(key)
+}
+
+func (s *shardedCache) Touch(key Key, d time.Duration) bool {
+	return s.shard(key).Touch(key, d)
+}
+
+func (s *shardedCache) GetOrStore(key Key, def Value, t time.Duration) (Value, bool) {
+	return s.shard(key).GetOrStore(key, def, t)
+}
+
+func (s *shardedCache) GetOrLoad(key Key, load LoadFunc) (Value, error) {
+	return s.shard(key).GetOrLoad(key, load)
+}
+
+func (s *shardedCache) GetWithCount(key Key) (Value, uint64, bool) {
+	return s.shard(key).GetWithCount(key)
+}
+
+func (s *shardedCache) GetAllowStale(key Key, maxStale time.Duration) (Value, bool, bool) {
+	return s.shard(key).GetAllowStale(key, maxStale)
+}
+
+func (s *shardedCache) PutString(key string, value Value) {
+	s.shards[hashString(key)%uint64(len(s.shards))].PutString(key, value)
+}
+
+func (s *shardedCache) GetString(key string) (Value, bool) {
+	return s.shards[hashString(key)%uint64(len(s.shards))].GetString(key)
+}
+
+func (s *shardedCache) PutInt(key int64, value Value) {
+	s.shards[hashUint64(uint64(key))%uint64(len(s.shards))].PutInt(key, value)
+}
+
+func (s *shardedCache) GetInt(key int64) (Value, bool) {
+	return s.shards[hashUint64(uint64(key))%uint64(len(s.shards))].GetInt(key)
+}
+
+func (s *shardedCache) Del(key Key) Value {
+	return s.shard(key).Del(key)
+}
+
+func (s *shardedCache) DelE(key Key) (Value, bool) {
+	return s.shard(key).DelE(key)
+}
+
+// fullest returns the shard with the most entries, nil if all are empty
+func (s *shardedCache) fullest() *lruCache {
+	var fullest *lruCache
+	n := 0
+	for _, shard := range s.shards {
+		if l := shard.Len(); l > n {
+			fullest, n = shard, l
+		}
+	}
+	return fullest
+}
+
+// RemoveOldest evicts the oldest entry of the fullest shard, the shards
+// do not share a recency order
+func (s *shardedCache) RemoveOldest() (Key, Value, bool) {
+	for {
+		fullest := s.fullest()
+		if fullest == nil {
+			return nil, nil, false
+		}
+		if key, value, ok := fullest.RemoveOldest(); ok {
+			return key, value, true
+		}
+	}
+}
+
+// GetOldest returns the oldest entry of the fullest shard, the shard
+// RemoveOldest removes from, see the GetOldest of the shards
+func (s *shardedCache) GetOldest() (Entry, bool) {
+	if fullest := s.fullest(); fullest != nil {
+		return fullest.GetOldest()
+	}
+	return Entry{}, false
+}
+
+// GetNewest returns the newest entry of the fullest shard as well
+func (s *shardedCache) GetNewest() (Entry, bool) {
+	if fullest := s.fullest(); fullest != nil {
+		return fullest.GetNewest()
+	}
+	return Entry{}, false
+}
+
+func (s *shardedCache) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Keys returns the keys shard by shard, the recency order only holds
+// within a shard
+func (s *shardedCache) Keys() []Key {
+	var keys []Key
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+func (s *shardedCache) Resize(maxLen int) {
+	for i, shard := range s.shards {
+		shard.Resize(shardMaxLen(maxLen, len(s.shards), i))
+	}
+}
+
+func (s *shardedCache) Weight() int64 {
+	var weight int64
+	for _, shard := range s.shards {
+		weight += shard.Weight()
+	}
+	return weight
+}
+
+func (s *shardedCache) Stats() Stats {
+	var stats Stats
+	for _, shard := range s.shards {
+		st := shard.Stats()
+		stats.Hits += st.Hits
+		stats.Misses += st.Misses
+		stats.Evictions += st.Evictions
+		stats.Expirations += st.Expirations
+		stats.Rejections += st.Rejections
+		stats.Contentions += st.Contentions
+		stats.Len += st.Len
+	}
+	return stats
+}
+
+// ShardStats returns the stats of every shard, to spot the hot ones
+func (s *shardedCache) ShardStats() []Stats {
+	stats := make([]Stats, len(s.shards))
+	for i, shard := range s.shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
+
+// Range visits a snapshot of each shard in turn
+func (s *shardedCache) Range(fn func(key Key, value Value) bool) {
+	s.RangeWithOptions(fn, RangeOptions{Mode: RangeSnapshot})
+}
+
+// RangeWithOptions visits the shards one after the other, the recency
+// order only holds within a shard. In RangeLocked mode the lock of one
+// shard at a time is held, so the view is consistent per shard only
+func (s *shardedCache) RangeWithOptions(fn func(key Key, value Value) bool, opts RangeOptions) {
+	stopped := false
+	wrapped := func(key Key, value Value) bool {
+		stopped = !fn(key, value)
+		return !stopped
+	}
+	for _, shard := range s.shards {
+		if shard.RangeWithOptions(wrapped, opts); stopped {
+			return
+		}
+	}
+}
+
+func (s *shardedCache) NextExpiry() (time.Time, bool) {
+	var next time.Time
+	for _, shard := range s.shards {
+		if t, ok := shard.NextExpiry(); ok && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (s *shardedCache) EvictionRate() float64 {
+	rate := 0.0
+	for _, shard := range s.shards {
+		rate += shard.EvictionRate()
+	}
+	return rate
+}
+
+// DistinctKeysEstimate merges the estimators of all the shards. Note that
+// each shard alerts on its share of CardinalityThreshold on its own
+func (s *shardedCache) DistinctKeysEstimate() uint64 {
+	merged := newHyperLogLog()
+	for _, shard := range s.shards {
+		shard.Lock()
+		if shard.cardinality == nil {
+			shard.Unlock()
+			return 0
+		}
+		for i, r := range shard.cardinality.registers {
+			if r > merged.registers[i] {
+				merged.registers[i] = r
+			}
+		}
+		shard.Unlock()
+	}
+	return merged.estimate()
+}
+
+func (s *shardedCache) SetBypass(bypass bool) {
+	for _, shard := range s.shards {
+		shard.SetBypass(bypass)
+	}
+}
+
+// Inspect sums up the reports of the shards, front and back keys are not
+// meaningful across shards and are left empty
+func (s *shardedCache) Inspect() InspectReport {
+	report := InspectReport{Consistent: true}
+	for i, shard := range s.shards {
+		r := shard.Inspect()
+		report.ListLen += r.ListLen
+		report.IndexLen += r.IndexLen
+		report.Expired += r.Expired
+		report.Consistent = report.Consistent && r.Consistent
+		for _, anomaly := range r.Anomalies {
+			report.Anomalies = append(report.Anomalies, fmt.Sprintf("shard %d: %s", i, anomaly))
+		}
+	}
+	return report
+}
+
+func (s *shardedCache) ReplayWAL(r io.Reader) error {
+	if s.closed() {
+		return ErrClosed
+	}
+	return readWAL(r, func(rec walRecord) {
+		shard := s.shard(rec.Key)
+		shard.Lock()
+		defer shard.Unlock()
+		shard.replay(rec)
+	})
+}
+
+// SaveTo writes the snapshots of the shards one after the other
+func (s *shardedCache) SaveTo(w io.Writer) error {
+	for _, shard := range s.shards {
+		if err := shard.SaveTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportJSON writes the entries of all the shards in a single array
+func (s *shardedCache) ExportJSON(w io.Writer, codec JSONCodec) error {
+	var recs []walRecord
+	for _, shard := range s.shards {
+		recs = append(recs, shard.snapshotRecords()...)
+	}
+	return exportJSON(w, codec, recs)
+}
+
+func (s *shardedCache) ImportJSON(r io.Reader, codec JSONCodec) error {
+	if s.closed() {
+		return ErrClosed
+	}
+	return importJSON(r, codec, func(rec walRecord) {
+		shard := s.shard(rec.Key)
+		shard.Lock()
+		defer shard.Unlock()
+		shard.replay(rec)
+	})
+}
+
+func (s *shardedCache) Warmup(keys []Key, loader Loader, parallelism int) error {
+	if s.closed() {
+		return ErrClosed
+	}
+	return warmup(keys, loader, parallelism, func(key Key, value Value, t time.Duration, err error) {
+		shard := s.shard(key)
+		if err == nil {
+			shard.put(key, value, t)
+		}
+		shard.audit("Warmup", key, err == nil)
+	})
+}
+
+func (s *shardedCache) Flush() {
+	for _, shard := range s.shards {
+		shard.Flush()
+	}
+}
+
+func (s *shardedCache) Purge() {
+	for _, shard := range s.shards {
+		shard.Purge()
+	}
+}
+
+func (s *shardedCache) Close() error {
+	if s.stopInvalidations != nil {
+		s.stopInvalidations()
+	}
+	if s.rebalancer != nil {
+		s.rebalancer.stop()
+	}
+	var err error
+	if s.snapshot != nil {
+		err = s.snapshot.save(s)
+	}
+	var errs []error
+	for _, shard := range s.shards {
+		errs = append(errs, shard.Close())
+	}
+	return joinErrors(append(errs, err)...)
+}
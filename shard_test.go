@@ -0,0 +1,113 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestShardedCache(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 1000, Shards: 8})
+
+	for i := 0; i < 50; i++ {
+		cache.PutString("testkey"+strconv.Itoa(i), i)
+		cache.PutInt(int64(i), i)
+	}
+	for i := 0; i < 50; i++ {
+		key := "testkey" + strconv.Itoa(i)
+		if val, ok := cache.Get(key); !ok || val != i {
+			t.Fatalf("test key %s value failed, expect %v, got %v", key, i, val)
+		}
+		if val, ok := cache.GetInt(int64(i)); !ok || val != i {
+			t.Fatalf("test key %v value failed, expect %v, got %v", i, i, val)
+		}
+	}
+	if cache.Len() != 100 {
+		t.Fatalf("test len failed, expect %v, got %v", 100, cache.Len())
+	}
+
+	count := 0
+	cache.(LockedRanger).RangeWithOptions(func(key Key, value Value) bool {
+		count++
+		return true
+	}, RangeOptions{})
+	if count != 100 {
+		t.Fatalf("test range failed, expect %v entries, got %v", 100, count)
+	}
+
+	for i := 0; i < 10000; i++ {
+		cache.Put(i, i)
+		if cache.Len() > 1000 {
+			t.Fatalf("test len failed, expect at most %v, got %v", 1000, cache.Len())
+		}
+	}
+	stats := cache.Stats()
+	if stats.Hits != 100 || stats.Evictions == 0 || stats.Len != cache.Len() {
+		t.Fatalf("test stats failed, got %+v", stats)
+	}
+	if report := cache.(Inspector).Inspect(); !report.Consistent || report.ListLen != cache.Len() {
+		t.Fatalf("test consistency failed, got %+v", report)
+	}
+}
+
+func TestShardedCacheMoreShardsThanMaxLen(t *testing.T) {
+	// there are as many shards as MaxLen, each holding one key
+	cache := NewCacheWithConfig(Config{MaxLen: 4, Shards: 8})
+	for i := 0; i < 100; i++ {
+		cache.Put(i, i)
+	}
+	if n := len(cache.(shardStater).ShardStats()); n != 4 || cache.Len() != 4 {
+		t.Fatalf("test len failed, expect %v in %v shards, got %v in %v", 4, 4, cache.Len(), n)
+	}
+
+	// resizing below the shard count still leaves one key per shard
+	cache.(Resizer).Resize(2)
+	for i := 0; i < 100; i++ {
+		cache.Put(i, i)
+	}
+	if cache.Len() != 4 {
+		t.Fatalf("test resized len failed, expect %v, got %v", 4, cache.Len())
+	}
+}
+
+func TestShardedCacheConcurrent(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 1000, Shards: 16})
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa(w*1000 + i)
+				cache.Put(key, i)
+				if val, ok := cache.GetString(key); !ok || val != i {
+					t.Errorf("test key %s value failed, expect %v, got %v", key, i, val)
+					return
+				}
+				cache.Del(key)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if cache.Len() != 0 {
+		t.Fatalf("test len failed, expect %v, got %v", 0, cache.Len())
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SaveTo writes the live entries to w, with their deadlines, so that a
+// cache can be warmed up from it with ReplayWAL, e.g. after a restart.
+// The snapshot has the format of the write-ahead log, its puts are
+// ordered from the least to the most recently used entry, and like the
+// log it needs the custom types of keys and values to be registered with
+// gob.Register. The entries are collected with the lock held, but written
+// after it is released. With PersistStats the counters of Stats are
+// written first
+func (lru *lruCache) SaveTo(w io.Writer) error {
+	if lru.persistStats {
+		stats := lru.Stats()
+		stats.Len, stats.Contentions = 0, 0
+		if err := writeWAL(w, walRecord{Op: walStats, Stats: &stats}); err != nil {
+			return err
+		}
+	}
+	for _, rec := range lru.snapshotRecords() {
+		if err := writeWAL(w, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotFile loads the cache from Config.SnapshotPath when it is
+// created and saves it there when it is closed
+type snapshotFile struct {
+	path    string
+	onError func(err error)
+	once    sync.Once
+}
+
+func newSnapshotFile(config Config) *snapshotFile {
+	if config.SnapshotPath == "" {
+		return nil
+	}
+	return &snapshotFile{path: config.SnapshotPath, onError: config.OnSnapshotError}
+}
+
+func (f *snapshotFile) fail(err error) {
+	if err != nil && f.onError != nil {
+		f.onError(err)
+	}
+}
+
+// load replays the snapshot into c, a missing file is not an error
+func (f *snapshotFile) load(c Persister) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		f.fail(err)
+		return
+	}
+	defer file.Close()
+	f.fail(c.ReplayWAL(file))
+}
+
+// save writes the snapshot of c once, to a temporary file renamed over
+// the previous snapshot, so that a crash never leaves a partial one
+func (f *snapshotFile) save(c Persister) error {
+	var err error
+	f.once.Do(func() {
+		err = f.write(c)
+		f.fail(err)
+	})
+	return err
+}
+
+func (f *snapshotFile) write(c Persister) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := c.SaveTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (lru *lruCache) snapshotRecords() []walRecord {
+	lru.Lock()
+	defer lru.Unlock()
+	now := lru.now()
+	recs := make([]walRecord, 0, lru.lst.Len())
+	for elem := lru.lst.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*listEntry)
+		if entry.expired(now) {
+			continue
+		}
+		value, err := lru.valueOf(entry)
+		if err != nil {
+			continue
+		}
+		recs = append(recs, walRecord{Op: walPut, Key: entry.key, Value: value, Deadline: entry.deadTime})
+	}
+	return recs
+}
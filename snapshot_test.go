@@ -0,0 +1,162 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheSaveTo(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 4, CompressThreshold: 4})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.Put("testkey2", []byte("testvalue2"))
+	cache.PutWithTimeout("testkey3", "testvalue3", NoExpiration)
+	cache.Put("testkey4", "testvalue4")
+	cache.Get("testkey2")
+	deadline, _ := cache.TTL("testkey4")
+
+	time.Sleep(1100 * time.Millisecond)
+	var buf bytes.Buffer
+	if err := cache.(Persister).SaveTo(&buf); err != nil {
+		t.Fatalf("test save failed, got %v", err)
+	}
+
+	restored := NewCacheWithConfig(Config{MaxLen: 4, Shards: 2})
+	if err := restored.(Persister).ReplayWAL(&buf); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	tests := []struct {
+		key    Key
+		value  Value
+		exists bool
+	}{
+		{"testkey1", nil, false},
+		{"testkey2", "testvalue2", true},
+		{"testkey3", "testvalue3", true},
+		{"testkey4", "testvalue4", true},
+	}
+	for _, test := range tests {
+		v, ok := restored.Peek(test.key)
+		if ok != test.exists {
+			t.Fatalf("test key %s failed, expect %v, got %v", test.key, test.exists, ok)
+		}
+		if b, isBytes := v.([]byte); isBytes {
+			v = string(b)
+		}
+		if ok && v != test.value {
+			t.Fatalf("test key %s failed, expect %v, got %v", test.key, test.value, v)
+		}
+	}
+	if d, _ := restored.TTL("testkey3"); d != NoExpiration {
+		t.Fatalf("test key %s ttl failed, expect %v, got %v", "testkey3", NoExpiration, d)
+	}
+	if d, _ := restored.TTL("testkey4"); d > deadline-time.Second {
+		t.Fatalf("test key %s ttl failed, expect at most %v, got %v", "testkey4", deadline-time.Second, d)
+	}
+
+	// the recency is kept as well
+	restored = NewCacheWithConfig(Config{MaxLen: 4})
+	var buf2 bytes.Buffer
+	cache.(Persister).SaveTo(&buf2)
+	restored.(Persister).ReplayWAL(&buf2)
+	keys := restored.Keys()
+	expect := []Key{"testkey2", "testkey4", "testkey3"}
+	if len(keys) != len(expect) {
+		t.Fatalf("test keys failed, expect %v, got %v", expect, keys)
+	}
+	for i := range expect {
+		if keys[i] != expect[i] {
+			t.Fatalf("test keys failed, expect %v, got %v", expect, keys)
+		}
+	}
+}
+
+func TestCacheSnapshotPath(t *testing.T) {
+	var failed error
+	onError := func(err error) { failed = err }
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	for _, shards := range []int{0, 4} {
+		config := Config{MaxLen: 10, Shards: shards, SnapshotPath: path, OnSnapshotError: onError}
+		cache := NewCacheWithConfig(config)
+		if cache.Len() != 0 {
+			t.Fatalf("test len failed, expect %v, got %v", 0, cache.Len())
+		}
+		cache.Put("testkey1", "testvalue1")
+		cache.PutWithTimeout("testkey2", "testvalue2", NoExpiration)
+		cache.Close()
+		cache.Close()
+
+		restored := NewCacheWithConfig(config)
+		for _, key := range []Key{"testkey1", "testkey2"} {
+			if !restored.Contains(key) {
+				t.Fatalf("test key %s failed, expect %v, got %v", key, true, false)
+			}
+		}
+		restored.Del("testkey1")
+		restored.Del("testkey2")
+		restored.Close()
+	}
+	if failed != nil {
+		t.Fatalf("test snapshot failed, got %v", failed)
+	}
+
+	os.WriteFile(path, []byte("\x00\x00\x00\x03bad"), 0o644)
+	NewCacheWithConfig(Config{MaxLen: 10, SnapshotPath: path, OnSnapshotError: onError})
+	if failed == nil {
+		t.Fatalf("test corrupted snapshot failed, expect an error, got %v", failed)
+	}
+
+	// the error of the snapshot is returned by Close as well
+	missing := filepath.Join(t.TempDir(), "missing", "cache.snapshot")
+	cache := NewCacheWithConfig(Config{MaxLen: 10, SnapshotPath: missing})
+	if err := cache.Close(); err == nil {
+		t.Fatalf("test snapshot to %s failed, expect an error, got %v", missing, err)
+	}
+}
+
+func TestCachePersistStats(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		path := filepath.Join(t.TempDir(), "cache.snapshot")
+		config := Config{MaxLen: 10, Shards: shards, SnapshotPath: path, PersistStats: true}
+		cache := NewCacheWithConfig(config)
+		cache.Put("testkey1", "testvalue1")
+		cache.Get("testkey1")
+		cache.Get("testkey1")
+		cache.Get("testkey2")
+		cache.Close()
+
+		restored := NewCacheWithConfig(config)
+		restored.Get("testkey1")
+		if stats := restored.Stats(); stats.Hits != 3 || stats.Misses != 1 || stats.Len != 1 {
+			t.Fatalf("test shards %d restored stats failed, expect %v hits %v misses, got %+v", shards, 3, 1, stats)
+		}
+		restored.Close()
+
+		// the stats are not restored unless asked for
+		config.PersistStats = false
+		if stats := NewCacheWithConfig(config).Stats(); stats.Hits != 0 || stats.Misses != 0 {
+			t.Fatalf("test shards %d stats failed, expect %v, got %+v", shards, 0, stats)
+		}
+	}
+}
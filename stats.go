@@ -0,0 +1,69 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "sync/atomic"
+
+// Stats of the cache since it was created
+type Stats struct {
+	// Hits and Misses count the reads, a read of an expired entry is a miss
+	Hits   uint64
+	Misses uint64
+	// Evictions counts the entries removed to make room for new ones
+	Evictions uint64
+	// Expirations counts the entries removed because they had expired
+	Expirations uint64
+	// Rejections counts the new entries not admitted by TinyLFU
+	Rejections uint64
+	// Contentions counts the times the lock was taken by another
+	// goroutine and had to be waited for
+	Contentions uint64
+	// Len is the current number of entries
+	Len int
+}
+
+// HitRatio returns the ratio of the reads that hit, 0 if there was none
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns a copy of the current statistics
+func (lru *lruCache) Stats() Stats {
+	lru.Lock()
+	defer lru.Unlock()
+	stats := lru.stats
+	stats.Len = lru.hash.len()
+	stats.Contentions = atomic.LoadUint64(&lru.contentions)
+	stats.Hits += atomic.LoadUint64(&lru.readHits)
+	stats.Misses += atomic.LoadUint64(&lru.readMisses)
+	return stats
+}
+
+// Lock counts the contentions before waiting for the lock, and applies
+// the reads buffered meanwhile once it has it
+func (lru *lruCache) Lock() {
+	if !lru.RWMutex.TryLock() {
+		atomic.AddUint64(&lru.contentions, 1)
+		lru.RWMutex.Lock()
+	}
+	if lru.reads != nil {
+		lru.drainReads()
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheStats(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 2})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.Put("testkey2", "testvalue2")
+	cache.Get("testkey1")
+	cache.Get("testkey3")
+	// evicts testkey2, the least recently used one
+	cache.Put("testkey3", "testvalue3")
+
+	time.Sleep(1200 * time.Millisecond)
+	cache.Get("testkey1")
+
+	expect := Stats{Hits: 1, Misses: 2, Evictions: 1, Expirations: 1, Len: 1}
+	if stats := cache.Stats(); stats != expect {
+		t.Fatalf("test stats failed, expect %+v, got %+v", expect, stats)
+	}
+
+	cache.GetString("testkey3")
+	cache.Del("testkey3")
+	expect = Stats{Hits: 2, Misses: 2, Evictions: 1, Expirations: 1, Len: 0}
+	if stats := cache.Stats(); stats != expect {
+		t.Fatalf("test stats failed, expect %+v, got %+v", expect, stats)
+	}
+	if ratio := cache.Stats().HitRatio(); ratio != 0.5 {
+		t.Fatalf("test hit ratio failed, expect %v, got %v", 0.5, ratio)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 10, Shards: 2})
+	defer cache.Close()
+	PublishExpvar("testcache", cache)
+	cache.Put("testkey1", "testvalue1")
+	cache.Get("testkey1")
+	cache.Get("testkey2")
+
+	v := expvar.Get("testcache")
+	if v == nil {
+		t.Fatalf("test published var failed, expect %v, got nil", "testcache")
+	}
+	var published struct {
+		Stats
+		HitRatio float64
+	}
+	if err := json.Unmarshal([]byte(v.String()), &published); err != nil {
+		t.Fatalf("test published var failed, got %v", err)
+	}
+	if published.Hits != 1 || published.Misses != 1 || published.Len != 1 || published.HitRatio != 0.5 {
+		t.Fatalf("test published stats failed, got %+v", published)
+	}
+
+	// a namespace counts its own keys only
+	users := cache.Namespace("users")
+	PublishExpvar("testcache.users", users)
+	users.Put("testkey1", "user1")
+	users.Put("testkey2", "user2")
+	if err := json.Unmarshal([]byte(expvar.Get("testcache.users").String()), &published); err != nil {
+		t.Fatalf("test published var failed, got %v", err)
+	}
+	if published.Len != 2 {
+		t.Fatalf("test published namespace len failed, expect %v, got %v", 2, published.Len)
+	}
+}
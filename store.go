@@ -0,0 +1,102 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// errNotStored tells the callers sharing a load that the store had no
+// value for the key
+var errNotStored = errors.New("cache: key not in store")
+
+// Store is a backing store the cache writes through to. Load reports
+// false if the store has no value for the key
+type Store interface {
+	Load(key Key) (Value, bool, error)
+	Save(key Key, value Value) error
+	Delete(key Key) error
+}
+
+// saveStore writes a put through to the store, it reports false if the
+// value must not be cached because it could not be saved, or because the
+// cache is closed
+func (lru *lruCache) saveStore(key Key, value Value) bool {
+	if lru.closed() {
+		return false
+	}
+	if lru.backing == nil {
+		return true
+	}
+	if lru.writeBehind != nil {
+		lru.writeBehind.push(storeOp{key: key, value: value})
+		return true
+	}
+	if err := lru.backing.Save(key, value); err != nil {
+		lru.storeError(key, err)
+		return false
+	}
+	return true
+}
+
+// deleteStore writes a delete through to the store
+func (lru *lruCache) deleteStore(key Key) {
+	if lru.backing == nil || lru.closed() {
+		return
+	}
+	if lru.writeBehind != nil {
+		lru.writeBehind.push(storeOp{key: key, del: true})
+		return
+	}
+	if err := lru.backing.Delete(key); err != nil {
+		lru.storeError(key, err)
+	}
+}
+
+// loadStore loads a missed key from the store and caches it for the
+// default lifetime, concurrent misses of the same key share one load
+func (lru *lruCache) loadStore(key Key) (Value, bool) {
+	if lru.closed() {
+		return nil, false
+	}
+	value, err := lru.loads.Do(context.Background(), key, func() (Value, error) {
+		value, ok, err := lru.backing.Load(key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errNotStored
+		}
+		lru.put(key, value, 0)
+		return value, nil
+	})
+	if err == errNotStored {
+		return nil, false
+	}
+	if err != nil {
+		lru.storeError(key, err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (lru *lruCache) storeError(key Key, err error) {
+	if lru.onStoreError != nil {
+		lru.onStoreError(key, err)
+	}
+}
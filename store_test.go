@@ -0,0 +1,150 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+// mapStore is a Store over a map, it fails to save the value "bad"
+type mapStore struct {
+	data  map[Key]Value
+	loads int
+	saves int
+	sync.Mutex
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{data: map[Key]Value{}}
+}
+
+func (s *mapStore) Load(key Key) (Value, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.loads++
+	value, ok := s.data[key]
+	return value, ok, nil
+}
+
+func (s *mapStore) Save(key Key, value Value) error {
+	if value == "bad" {
+		return errors.New("bad value")
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.saves++
+	s.data[key] = value
+	return nil
+}
+
+func (s *mapStore) Delete(key Key) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func TestCacheStore(t *testing.T) {
+	store := newMapStore()
+	var failed []Key
+	onError := func(key Key, err error) { failed = append(failed, key) }
+	cache := NewCacheWithConfig(Config{MaxLen: 2, Store: store, OnStoreError: onError})
+
+	cache.Put("testkey1", "testvalue1")
+	cache.PutString("testkey2", "testvalue2")
+	cache.Put("testkey3", "testvalue3")
+	if len(store.data) != 3 || cache.Contains("testkey1") {
+		t.Fatalf("test store failed, got %v in store and %v in cache", store.data, cache.Keys())
+	}
+
+	// the evicted key is loaded back from the store
+	if v, ok := cache.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if !cache.Contains("testkey1") || store.loads != 1 {
+		t.Fatalf("test loaded key failed, got %v in cache after %v loads", cache.Keys(), store.loads)
+	}
+	if _, ok := cache.GetString("testkey4"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey4", false, ok)
+	}
+
+	cache.Del("testkey2")
+	if _, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", false, ok)
+	}
+
+	// a value that can not be saved is not cached
+	cache.Put("testkey1", "bad")
+	if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if len(failed) != 1 || failed[0] != "testkey1" {
+		t.Fatalf("test store errors failed, expect %v, got %v", []Key{"testkey1"}, failed)
+	}
+}
+
+// slowStore delays the writes to a mapStore until it is released
+type slowStore struct {
+	*mapStore
+	release chan struct{}
+}
+
+func (s *slowStore) Save(key Key, value Value) error {
+	<-s.release
+	return s.mapStore.Save(key, value)
+}
+
+func TestCacheWriteBehind(t *testing.T) {
+	store := &slowStore{mapStore: newMapStore(), release: make(chan struct{})}
+	cache := NewCacheWithConfig(Config{MaxLen: 10, Store: store, WriteBehind: true, WriteBehindBuffer: 2})
+
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	if v, ok := cache.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	store.Lock()
+	stored := len(store.data)
+	store.Unlock()
+	if stored != 0 {
+		t.Fatalf("test pending writes failed, expect %v stored, got %v", 0, stored)
+	}
+
+	close(store.release)
+	cache.Del("testkey2")
+	cache.Flush()
+	store.Lock()
+	if len(store.data) != 1 || store.data["testkey1"] != "testvalue1" {
+		t.Fatalf("test flushed writes failed, got %v", store.data)
+	}
+	store.Unlock()
+
+	cache.Put("testkey3", "testvalue3")
+	cache.Close()
+	if _, ok := store.data["testkey3"]; !ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey3", true, ok)
+	}
+	// the writes after Close are dropped, the store is not touched
+	cache.Put("testkey4", "testvalue4")
+	if _, ok := store.data["testkey4"]; ok {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey4", false, ok)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// sweeper removes the expired entries in the background
+type sweeper struct {
+	stopCh chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newSweeper(interval time.Duration, sweep func()) *sweeper {
+	s := &sweeper{
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sweep()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// stop waits for the sweeper to exit, it is safe to call more than once
+func (s *sweeper) stop() {
+	s.once.Do(func() { close(s.stopCh) })
+	<-s.done
+}
+
+// sweep removes all the expired entries, firing the eviction callback for
+// each of them. It walks the whole cache with the lock held
+func (lru *lruCache) sweep() {
+	lru.Lock()
+	defer lru.Unlock()
+	// the entries within the StaleWhileRevalidate window are kept
+	now := lru.now().Add(-lru.staleWhileRevalidate)
+	for elem := lru.lst.Back(); elem != nil; {
+		prev := elem.Prev()
+		if elem.Value.(*listEntry).expired(now) {
+			lru.expire(elem)
+		}
+		elem = prev
+	}
+}
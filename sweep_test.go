@@ -0,0 +1,53 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheSweep(t *testing.T) {
+	var evicted int32
+	cb := func(key Key, value Value) {
+		atomic.AddInt32(&evicted, 1)
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 3, Callback: cb, SweepInterval: 100 * time.Millisecond})
+	cache.PutWithTimeout("testkey1", "testvalue1", time.Second)
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Second)
+	cache.Put("testkey3", "testvalue3")
+
+	time.Sleep(1300 * time.Millisecond)
+	if n := atomic.LoadInt32(&evicted); n != 2 {
+		t.Fatalf("test swept callbacks failed, expect %v, got %v", 2, n)
+	}
+	if stats := cache.Stats(); stats.Len != 1 || stats.Expirations != 2 {
+		t.Fatalf("test stats failed, expect len %v and %v expirations, got %+v", 1, 2, stats)
+	}
+
+	// the sweeper is gone after Close, and the closed cache drops the puts
+	cache.Close()
+	cache.Close()
+	cache.PutWithTimeout("testkey4", "testvalue4", time.Second)
+	time.Sleep(1300 * time.Millisecond)
+	if n := atomic.LoadInt32(&evicted); cache.Len() != 0 || n != 2 {
+		t.Fatalf("test closed cache failed, expect len %v and %v callbacks, got %v and %v", 0, 2, cache.Len(), n)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// tag indexes the entry under its tags, the lock must be held
+func (lru *lruCache) tag(entry *listEntry) {
+	for _, tag := range entry.tags {
+		if lru.tags == nil {
+			lru.tags = map[string]map[Key]struct{}{}
+		}
+		keys, ok := lru.tags[tag]
+		if !ok {
+			keys = map[Key]struct{}{}
+			lru.tags[tag] = keys
+		}
+		keys[entry.key] = struct{}{}
+	}
+}
+
+// untag removes the entry from the index of its tags, the lock must be
+// held
+func (lru *lruCache) untag(entry *listEntry) {
+	for _, tag := range entry.tags {
+		keys := lru.tags[tag]
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(lru.tags, tag)
+		}
+	}
+}
+
+// PutTagged puts the key with the default lifetime, like Put, and tags it
+// so that InvalidateTag deletes it. Putting the key again replaces its
+// tags, with none for the methods without tags. The put is not delayed by
+// PutDebounce, and it drops the debounced put of the key still pending
+func (lru *lruCache) PutTagged(key Key, value Value, tags ...string) {
+	replaced := lru.putTagged(key, value, tags)
+	lru.audit("PutTagged", key, replaced)
+}
+
+func (lru *lruCache) putTagged(key Key, value Value, tags []string) bool {
+	lru.cancelPuts(key)
+	if !lru.saveStore(key, value) || lru.bypassed() {
+		return false
+	}
+	entry := lru.newEntry(key, value, 0)
+	entry.tags = tags
+	lru.Lock()
+	defer lru.Unlock()
+	lru.logWAL(walRecord{Op: walPut, Key: key, Value: value, Deadline: entry.deadTime, Tags: tags})
+	return lru.store(entry)
+}
+
+// InvalidateTag deletes the entries tagged with tag under one lock and
+// returns how many there were. The callbacks are fired with ReasonDeleted
+// and the keys are deleted from the Store as well
+func (lru *lruCache) InvalidateTag(tag string) int {
+	return lru.delFound("InvalidateTag", func() []Key {
+		keys := make([]Key, 0, len(lru.tags[tag]))
+		for key := range lru.tags[tag] {
+			keys = append(keys, key)
+		}
+		return keys
+	})
+}
+
+func (s *shardedCache) PutTagged(key Key, value Value, tags ...string) {
+	s.shard(key).PutTagged(key, value, tags...)
+}
+
+func (s *shardedCache) InvalidateTag(tag string) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.InvalidateTag(tag)
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		var deleted []Key
+		cache := NewCacheWithConfig(Config{MaxLen: 100, Shards: shards, CallbackWithReason: func(key Key, value Value, reason EvictionReason) {
+			if reason == ReasonDeleted {
+				deleted = append(deleted, key)
+			}
+		}})
+		cache.PutTagged("testkey1", "testvalue1", "user:1")
+		cache.PutTagged("testkey2", "testvalue2", "user:1", "user:2")
+		cache.PutTagged("testkey3", "testvalue3", "user:2")
+		cache.PutTagged("testkey4", "testvalue4", "user:1")
+		cache.Put("testkey4", "testvalue4")
+		cache.PutTagged("testkey5", "testvalue5", "user:1")
+		cache.Del("testkey5")
+
+		tests := []struct {
+			tag   string
+			count int
+		}{
+			{"user:1", 2},
+			{"user:1", 0},
+			{"user:2", 1},
+			{"user:3", 0},
+		}
+		for _, test := range tests {
+			if n := cache.InvalidateTag(test.tag); n != test.count {
+				t.Fatalf("test %d shards tag %s failed, expect %v, got %v", shards, test.tag, test.count, n)
+			}
+		}
+		if len(deleted) != 4 {
+			t.Fatalf("test %d shards deletes failed, got %v", shards, deleted)
+		}
+		if keys := cache.Keys(); len(keys) != 1 || keys[0] != "testkey4" {
+			t.Fatalf("test %d shards keys failed, expect %v, got %v", shards, "[testkey4]", keys)
+		}
+		cache.Close()
+	}
+}
+
+func TestInvalidateTagReplay(t *testing.T) {
+	var wal bytes.Buffer
+	cache := NewCacheWithConfig(Config{MaxLen: 100, WAL: &wal})
+	cache.PutTagged("testkey1", "testvalue1", "user:1")
+	cache.PutTagged("testkey2", "testvalue2", "user:2")
+	cache.Close()
+
+	replayed := NewCacheWithConfig(Config{MaxLen: 100})
+	defer replayed.Close()
+	if err := replayed.(Persister).ReplayWAL(&wal); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+	if n := replayed.InvalidateTag("user:1"); n != 1 {
+		t.Fatalf("test tag %s failed, expect %v, got %v", "user:1", 1, n)
+	}
+	if v, _ := replayed.Get("testkey2"); v != "testvalue2" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", "testvalue2", v)
+	}
+}
+
+func TestNamespaceInvalidateTag(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	defer cache.Close()
+	users := cache.Namespace("users")
+	cache.PutTagged("testkey1", "testvalue1", "tag1")
+	users.PutTagged("testkey1", "user1", "tag1")
+	if n := users.InvalidateTag("tag1"); n != 1 {
+		t.Fatalf("test tag %s failed, expect %v, got %v", "tag1", 1, n)
+	}
+	if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCallbackBuffer is the number of eviction callbacks a throttled
+// cache holds back before evictions start waiting for room
+const DefaultCallbackBuffer = 1024
+
+type evictedItem struct {
+	key    Key
+	value  Value
+	reason EvictionReason
+}
+
+// throttledCallback runs the callbacks in a background goroutine, no more
+// than one per interval, or as fast as they come with a zero interval
+type throttledCallback struct {
+	callback OnEvictedWithReason
+	interval time.Duration
+	queue    chan evictedItem
+	done     chan struct{}
+
+	// unthrottled is set to drain the queue at full speed
+	unthrottled int32
+	closed      bool
+	sync.Mutex
+}
+
+// newThrottledCallback will create a queue of callbacks run at rate per
+// second, or without a limit if rate is not positive
+func newThrottledCallback(callback OnEvictedWithReason, rate float64, buffer int) *throttledCallback {
+	tc := &throttledCallback{
+		callback: callback,
+		queue:    make(chan evictedItem, buffer),
+		done:     make(chan struct{}),
+	}
+	if rate > 0 {
+		tc.interval = time.Duration(float64(time.Second) / rate)
+	}
+	go tc.run()
+	return tc
+}
+
+func (tc *throttledCallback) run() {
+	defer close(tc.done)
+	var tick <-chan time.Time
+	if tc.interval > 0 {
+		ticker := time.NewTicker(tc.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for item := range tc.queue {
+		if tick != nil && atomic.LoadInt32(&tc.unthrottled) == 0 {
+			<-tick
+		}
+		tc.callback(item.key, item.value, item.reason)
+	}
+}
+
+// push queues the callback, it waits for room while the queue is full.
+// Once closed the callbacks are run right away
+func (tc *throttledCallback) push(key Key, value Value, reason EvictionReason) {
+	tc.Lock()
+	defer tc.Unlock()
+	if tc.closed {
+		tc.callback(key, value, reason)
+		return
+	}
+	tc.queue <- evictedItem{key, value, reason}
+}
+
+// close waits until all the queued callbacks have been run
+func (tc *throttledCallback) close(unthrottled bool) {
+	tc.Lock()
+	if tc.closed {
+		tc.Unlock()
+		return
+	}
+	tc.closed = true
+	close(tc.queue)
+	tc.Unlock()
+	if unthrottled {
+		atomic.StoreInt32(&tc.unthrottled, 1)
+	}
+	<-tc.done
+}
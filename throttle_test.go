@@ -0,0 +1,106 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheCallbackRateLimit(t *testing.T) {
+	const rate = 20
+	var lock sync.Mutex
+	var calls []time.Time
+	cb := func(key Key, value Value) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, time.Now())
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 1, Callback: cb, CallbackRateLimit: rate})
+
+	// a burst of evictions must not block the cache
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		cache.Put(i, i)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("test burst eviction failed, took %v", elapsed)
+	}
+
+	cache.Close()
+	lock.Lock()
+	defer lock.Unlock()
+	if len(calls) != 10 {
+		t.Fatalf("test callbacks failed, expect %v, got %v", 10, len(calls))
+	}
+	minSpan := time.Duration(len(calls)-1) * time.Second / rate * 9 / 10
+	if span := calls[len(calls)-1].Sub(calls[0]); span < minSpan {
+		t.Fatalf("test callback rate failed, expect the calls to span at least %v, got %v", minSpan, span)
+	}
+}
+
+func TestCacheCallbackCloseUnthrottled(t *testing.T) {
+	count := 0
+	cb := func(key Key, value Value) {
+		count++
+	}
+	cache := NewCacheWithConfig(Config{MaxLen: 1, Callback: cb, CallbackRateLimit: 1, CloseCallbackUnthrottled: true})
+	for i := 0; i < 11; i++ {
+		cache.Put(i, i)
+	}
+
+	start := time.Now()
+	cache.Close()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("test unthrottled close failed, took %v", elapsed)
+	}
+	if count != 10 {
+		t.Fatalf("test callbacks failed, expect %v, got %v", 10, count)
+	}
+}
+
+func TestCacheAsyncCallback(t *testing.T) {
+	var cache Interface
+	var lock sync.Mutex
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		// the lock is not held, so the callback may use the cache
+		cache.Contains(key)
+		lock.Lock()
+		defer lock.Unlock()
+		evicted = append(evicted, key)
+	}
+	cache = NewCacheWithConfig(Config{MaxLen: 1, Callback: cb, AsyncCallback: true})
+	for i := 0; i < 11; i++ {
+		cache.Put(i, i)
+	}
+
+	cache.Close()
+	lock.Lock()
+	defer lock.Unlock()
+	if len(evicted) != 10 {
+		t.Fatalf("test callbacks failed, expect %v, got %v", 10, len(evicted))
+	}
+	for i, key := range evicted {
+		if key != i {
+			t.Fatalf("test callback order failed, expect %v, got %v", i, key)
+		}
+	}
+}
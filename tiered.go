@@ -0,0 +1,427 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// tieredCache looks the keys up in l1 first and falls back to l2
+type tieredCache struct {
+	l1 Interface
+	l2 Interface
+}
+
+// NewTiered will create a cache of two tiers, typically a small in-memory
+// l1 in front of a larger or shared l2. The puts, touches and deletes go
+// to both tiers, and the keys missing from l1 are looked up in l2, a hit
+// there is promoted to l1 with the time it has left in l2. Len, Keys and
+// Range see the keys of both tiers. The cache implements none of the
+// optional interfaces, even when both tiers do
+func NewTiered(l1, l2 Interface) Interface {
+	return &tieredCache{l1: Wrap(l1), l2: Wrap(l2)}
+}
+
+func (t *tieredCache) Put(key Key, value Value) {
+	t.l2.Put(key, value)
+	t.l1.Put(key, value)
+}
+
+func (t *tieredCache) PutWithTimeout(key Key, value Value, d time.Duration) {
+	t.l2.PutWithTimeout(key, value, d)
+	t.l1.PutWithTimeout(key, value, d)
+}
+
+func (t *tieredCache) PutWithDeadline(key Key, value Value, deadline time.Time) {
+	t.l2.PutWithDeadline(key, value, deadline)
+	t.l1.PutWithDeadline(key, value, deadline)
+}
+
+// PutCtx puts the key in l1 only once l2 has accepted it
+func (t *tieredCache) PutCtx(ctx context.Context, key Key, value Value) error {
+	if err := t.l2.PutCtx(ctx, key, value); err != nil {
+		return err
+	}
+	return t.l1.PutCtx(ctx, key, value)
+}
+
+func (t *tieredCache) PutString(key string, value Value) {
+	t.l2.PutString(key, value)
+	t.l1.PutString(key, value)
+}
+
+func (t *tieredCache) PutInt(key int64, value Value) {
+	t.l2.PutInt(key, value)
+	t.l1.PutInt(key, value)
+}
+
+func (t *tieredCache) PutMulti(entries map[Key]Value) {
+	t.l2.PutMulti(entries)
+	t.l1.PutMulti(entries)
+}
+
+func (t *tieredCache) PutMultiWithTimeout(entries map[Key]Value, d time.Duration) {
+	t.l2.PutMultiWithTimeout(entries, d)
+	t.l1.PutMultiWithTimeout(entries, d)
+}
+
+func (t *tieredCache) PutNotFound(key Key) {
+	t.l2.PutNotFound(key)
+	t.l1.PutNotFound(key)
+}
+
+func (t *tieredCache) Get(key Key) (Value, bool) {
+	if value, ok := t.l1.Get(key); ok {
+		return value, true
+	}
+	return t.promote(key, t.l2.Get)
+}
+
+// GetCtx looks the key up in l2 when l1 misses it, unless ctx is done
+func (t *tieredCache) GetCtx(ctx context.Context, key Key) (Value, bool, error) {
+	if value, ok, err := t.l1.GetCtx(ctx, key); ok || err != nil {
+		return value, ok, err
+	}
+	value, ok, err := t.l2.GetCtx(ctx, key)
+	if ok {
+		t.fill(key, value)
+	}
+	return value, ok, err
+}
+
+func (t *tieredCache) GetString(key string) (Value, bool) {
+	if value, ok := t.l1.GetString(key); ok {
+		return value, true
+	}
+	return t.promote(key, func(Key) (Value, bool) { return t.l2.GetString(key) })
+}
+
+func (t *tieredCache) GetInt(key int64) (Value, bool) {
+	if value, ok := t.l1.GetInt(key); ok {
+		return value, true
+	}
+	return t.promote(key, func(Key) (Value, bool) { return t.l2.GetInt(key) })
+}
+
+// promote reads the key from l2 with get and copies it to l1
+func (t *tieredCache) promote(key Key, get func(key Key) (Value, bool)) (Value, bool) {
+	value, ok := get(key)
+	if !ok {
+		return nil, false
+	}
+	t.fill(key, value)
+	return value, true
+}
+
+// fill copies a value read from l2 to l1, with the time it has left
+func (t *tieredCache) fill(key Key, value Value) {
+	if left, ok := t.l2.TTL(key); ok {
+		t.l1.PutWithTimeout(key, value, left)
+	} else {
+		t.l1.Put(key, value)
+	}
+}
+
+// GetMulti looks the keys missing from l1 up in l2 in one batch
+func (t *tieredCache) GetMulti(keys []Key) map[Key]Value {
+	values := t.l1.GetMulti(keys)
+	missing := make([]Key, 0, len(keys)-len(values))
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values
+	}
+	for key, value := range t.l2.GetMulti(missing) {
+		t.fill(key, value)
+		values[key] = value
+	}
+	return values
+}
+
+// Add adds the key to l2, which decides for all the processes sharing it,
+// and copies it to l1 if it was added
+func (t *tieredCache) Add(key Key, value Value) bool {
+	if !t.l2.Add(key, value) {
+		return false
+	}
+	t.fill(key, value)
+	return true
+}
+
+// Replace replaces the key in l2, and copies it to l1 if it was replaced
+func (t *tieredCache) Replace(key Key, value Value) bool {
+	if !t.l2.Replace(key, value) {
+		return false
+	}
+	t.fill(key, value)
+	return true
+}
+
+// IncrementInt64 increments the key in l2, and copies the result to l1
+func (t *tieredCache) IncrementInt64(key Key, delta int64) (int64, error) {
+	n, err := t.l2.IncrementInt64(key, delta)
+	if err == nil {
+		t.fill(key, n)
+	}
+	return n, err
+}
+
+func (t *tieredCache) DecrementInt64(key Key, delta int64) (int64, error) {
+	return t.IncrementInt64(key, -delta)
+}
+
+// CompareAndSwap swaps the value in l2, and copies it to l1 if it did
+func (t *tieredCache) CompareAndSwap(key Key, old, new Value) bool {
+	if !t.l2.CompareAndSwap(key, old, new) {
+		return false
+	}
+	t.fill(key, new)
+	return true
+}
+
+func (t *tieredCache) Peek(key Key) (Value, bool) {
+	if value, ok := t.l1.Peek(key); ok {
+		return value, true
+	}
+	return t.l2.Peek(key)
+}
+
+// GetEntry returns the entry of l1, or the one of l2 if l1 misses it,
+// without promoting it
+func (t *tieredCache) GetEntry(key Key) (Entry, bool) {
+	if entry, ok := t.l1.GetEntry(key); ok {
+		return entry, true
+	}
+	return t.l2.GetEntry(key)
+}
+
+func (t *tieredCache) Contains(key Key) bool {
+	return t.l1.Contains(key) || t.l2.Contains(key)
+}
+
+func (t *tieredCache) TTL(key Key) (time.Duration, bool) {
+	if left, ok := t.l1.TTL(key); ok {
+		return left, true
+	}
+	return t.l2.TTL(key)
+}
+
+func (t *tieredCache) Touch(key Key, d time.Duration) bool {
+	touched := t.l2.Touch(key, d)
+	return t.l1.Touch(key, d) || touched
+}
+
+// GetOrStore stores def in l2 unless it has the key, and copies the
+// value l2 ends up with to l1
+func (t *tieredCache) GetOrStore(key Key, def Value, d time.Duration) (Value, bool) {
+	if value, ok := t.Get(key); ok {
+		return value, true
+	}
+	value, loaded := t.l2.GetOrStore(key, def, d)
+	t.promote(key, func(Key) (Value, bool) { return value, true })
+	return value, loaded
+}
+
+// GetOrLoad looks the key up in l2 before calling load, and puts the
+// loaded value, or NotFound, in both tiers
+func (t *tieredCache) GetOrLoad(key Key, load LoadFunc) (Value, error) {
+	return t.l1.GetOrLoad(key, func(key Key) (Value, error) {
+		if value, ok := t.l2.Get(key); ok {
+			if value == NotFound {
+				return nil, ErrNotFound
+			}
+			return value, nil
+		}
+		value, err := load(key)
+		if err == nil {
+			t.l2.Put(key, value)
+		} else if errors.Is(err, ErrNotFound) {
+			t.l2.PutNotFound(key)
+		}
+		return value, err
+	})
+}
+
+// GetOrLoadCtx is GetOrLoad with a context, which is passed on to l2 and
+// load
+func (t *tieredCache) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	return t.l1.GetOrLoadCtx(ctx, key, func(ctx context.Context, key Key) (Value, error) {
+		if value, ok, err := t.l2.GetCtx(ctx, key); err != nil {
+			return nil, err
+		} else if ok {
+			if value == NotFound {
+				return nil, ErrNotFound
+			}
+			return value, nil
+		}
+		value, err := load(ctx, key)
+		if err == nil {
+			err = t.l2.PutCtx(ctx, key, value)
+		} else if errors.Is(err, ErrNotFound) {
+			t.l2.PutNotFound(key)
+		}
+		return value, err
+	})
+}
+
+func (t *tieredCache) Del(key Key) Value {
+	value, _ := t.DelE(key)
+	return value
+}
+
+func (t *tieredCache) DelE(key Key) (Value, bool) {
+	value2, ok2 := t.l2.DelE(key)
+	if value, ok := t.l1.DelE(key); ok {
+		return value, true
+	}
+	return value2, ok2
+}
+
+// DelMulti returns the larger of the counts of the two tiers
+func (t *tieredCache) DelMulti(keys []Key) int {
+	n2 := t.l2.DelMulti(keys)
+	if n := t.l1.DelMulti(keys); n > n2 {
+		return n
+	}
+	return n2
+}
+
+// DelPrefix returns the larger of the counts of the two tiers
+func (t *tieredCache) DelPrefix(prefix string) int {
+	n2 := t.l2.DelPrefix(prefix)
+	if n := t.l1.DelPrefix(prefix); n > n2 {
+		return n
+	}
+	return n2
+}
+
+// DelFunc returns the larger of the counts of the two tiers
+func (t *tieredCache) DelFunc(fn func(key Key, value Value) bool) int {
+	n2 := t.l2.DelFunc(fn)
+	if n := t.l1.DelFunc(fn); n > n2 {
+		return n
+	}
+	return n2
+}
+
+func (t *tieredCache) PutTagged(key Key, value Value, tags ...string) {
+	t.l2.PutTagged(key, value, tags...)
+	t.l1.PutTagged(key, value, tags...)
+}
+
+// InvalidateTag returns the larger of the counts of the two tiers
+func (t *tieredCache) InvalidateTag(tag string) int {
+	n2 := t.l2.InvalidateTag(tag)
+	if n := t.l1.InvalidateTag(tag); n > n2 {
+		return n
+	}
+	return n2
+}
+
+// LockKey locks the key in l1, then in l2
+func (t *tieredCache) LockKey(key Key) func() {
+	unlock1 := t.l1.LockKey(key)
+	unlock2 := t.l2.LockKey(key)
+	return func() {
+		unlock2()
+		unlock1()
+	}
+}
+
+func (t *tieredCache) Len() int {
+	return len(t.Keys())
+}
+
+// Keys returns the keys of l1, then the keys of l2 missing from l1
+func (t *tieredCache) Keys() []Key {
+	var keys []Key
+	t.Range(func(key Key, value Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range visits the entries of l1, then those of l2 missing from l1
+func (t *tieredCache) Range(fn func(key Key, value Value) bool) {
+	seen := map[Key]struct{}{}
+	more := true
+	t.l1.Range(func(key Key, value Value) bool {
+		seen[key] = struct{}{}
+		more = fn(key, value)
+		return more
+	})
+	if !more {
+		return
+	}
+	t.l2.Range(func(key Key, value Value) bool {
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		return fn(key, value)
+	})
+}
+
+// Stats counts a hit of either tier as a hit and a miss of l2 as a miss,
+// the evictions, expirations and rejections are those of l1
+func (t *tieredCache) Stats() Stats {
+	stats := t.l1.Stats()
+	stats2 := t.l2.Stats()
+	stats.Hits += stats2.Hits
+	stats.Misses = stats2.Misses
+	stats.Len = t.Len()
+	return stats
+}
+
+func (t *tieredCache) SetBypass(bypass bool) {
+	t.l1.SetBypass(bypass)
+	t.l2.SetBypass(bypass)
+}
+
+func (t *tieredCache) Flush() {
+	t.l1.Flush()
+	t.l2.Flush()
+}
+
+func (t *tieredCache) Purge() {
+	t.l1.Purge()
+	t.l2.Purge()
+}
+
+// Namespace returns a tiered cache over the namespaces of both tiers
+func (t *tieredCache) Namespace(name string) Interface {
+	return &tieredCache{l1: t.l1.Namespace(name), l2: t.l2.Namespace(name)}
+}
+
+// Clone returns a tiered cache over the clones of both tiers
+func (t *tieredCache) Clone() Interface {
+	return &tieredCache{l1: t.l1.Clone(), l2: t.l2.Clone()}
+}
+
+// Close closes both tiers, it returns the error of l1 first
+func (t *tieredCache) Close() error {
+	err := t.l1.Close()
+	if err2 := t.l2.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheTiered(t *testing.T) {
+	l1 := NewCacheWithConfig(Config{MaxLen: 2})
+	l2 := NewCacheWithConfig(Config{MaxLen: 10})
+	cache := NewTiered(l1, l2)
+
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", "testvalue2", 5*time.Second)
+	cache.Put("testkey3", "testvalue3")
+	if l1.Len() != 2 || l2.Len() != 3 {
+		t.Fatalf("test tiers failed, expect %v and %v entries, got %v and %v", 2, 3, l1.Len(), l2.Len())
+	}
+
+	// the key evicted from l1 is found in l2 and promoted
+	if v, ok := cache.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if !l1.Contains("testkey1") {
+		t.Fatalf("test promoted key %s failed, expect %v, got %v", "testkey1", true, false)
+	}
+	if v, ok := cache.Get("testkey2"); !ok || v != "testvalue2" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey2", "testvalue2", v)
+	}
+	if d, _ := l1.TTL("testkey2"); d > 5*time.Second || d < 4*time.Second {
+		t.Fatalf("test promoted key %s ttl failed, expect about %v, got %v", "testkey2", 5*time.Second, d)
+	}
+
+	cache.Del("testkey1")
+	if cache.Contains("testkey1") || l2.Contains("testkey1") {
+		t.Fatalf("test deleted key %s failed, expect %v, got %v", "testkey1", false, true)
+	}
+
+	v, err := cache.GetOrLoad("testkey4", func(key Key) (Value, error) {
+		return "testvalue4", nil
+	})
+	if err != nil || v != "testvalue4" || !l2.Contains("testkey4") {
+		t.Fatalf("test loaded key %s failed, expect %v, got %v, %v", "testkey4", "testvalue4", v, err)
+	}
+
+	if v, loaded := cache.GetOrStore("testkey3", "testvalue5", time.Second); !loaded || v != "testvalue3" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey3", "testvalue3", v)
+	}
+}
+
+func TestCacheTieredBypass(t *testing.T) {
+	l1 := NewCacheWithConfig(Config{MaxLen: 1})
+	l2 := NewCacheWithConfig(Config{MaxLen: 10})
+	cache := NewTiered(l1, l2)
+
+	cache.Put("testkey1", "testvalue1")
+	cache.Put("testkey2", "testvalue2")
+	if cache.Len() != 2 {
+		t.Fatalf("test tiered len failed, expect %v, got %v with keys %v", 2, cache.Len(), cache.Keys())
+	}
+	if stats := cache.Stats(); stats.Len != 2 {
+		t.Fatalf("test tiered stats failed, expect %v keys, got %+v", 2, stats)
+	}
+
+	// bypassing skips both tiers
+	cache.SetBypass(true)
+	if v, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test bypassed key %s failed, expect %v, got %v", "testkey1", nil, v)
+	}
+	if v, ok := cache.Get("testkey2"); ok {
+		t.Fatalf("test bypassed key %s failed, expect %v, got %v", "testkey2", nil, v)
+	}
+	cache.SetBypass(false)
+	if v, ok := cache.Get("testkey1"); !ok || v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// frequencySketch is a count-min sketch of 4 rows of saturating 4 bits
+// counters, two per byte. All the counters are halved once the number of
+// increments reaches ten times the width, so that the estimates follow
+// the recent popularity of the keys instead of their whole history
+type frequencySketch struct {
+	rows    [4][]byte
+	mask    uint64
+	samples int
+	limit   int
+}
+
+var sketchSeeds = [4]uint64{0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xd6e8feb86659fd93}
+
+// newFrequencySketch sizes the sketch for about n distinct keys
+func newFrequencySketch(n int) *frequencySketch {
+	width := 64
+	for width < n && width < 1<<24 {
+		width <<= 1
+	}
+	s := &frequencySketch{mask: uint64(width - 1), limit: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]byte, width/2)
+	}
+	return s
+}
+
+// counter returns the byte and the shift of the counter of row i
+func (s *frequencySketch) counter(i int, h uint64) (*byte, uint) {
+	idx := mix64(h^sketchSeeds[i]) & s.mask
+	return &s.rows[i][idx/2], uint(idx%2) * 4
+}
+
+func (s *frequencySketch) increment(key Key) {
+	h := hashKey(key)
+	for i := range s.rows {
+		b, shift := s.counter(i, h)
+		if (*b>>shift)&0x0f < 0x0f {
+			*b += 1 << shift
+		}
+	}
+	if s.samples++; s.samples >= s.limit {
+		s.age()
+	}
+}
+
+func (s *frequencySketch) estimate(key Key) byte {
+	h := hashKey(key)
+	min := byte(0x0f)
+	for i := range s.rows {
+		b, shift := s.counter(i, h)
+		if c := (*b >> shift) & 0x0f; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// age halves all the counters
+func (s *frequencySketch) age() {
+	for i := range s.rows {
+		for j, b := range s.rows[i] {
+			s.rows[i][j] = (b >> 1) & 0x77
+		}
+	}
+	s.samples /= 2
+}
+
+// admit tells whether a new entry may take the place of the victim the
+// policy would evict for it, it is always admitted while there is room.
+// The lock must be held
+func (lru *lruCache) admit(entry *listEntry) bool {
+	if lru.admission == nil {
+		return true
+	}
+	if lru.hash.len() < lru.maxLen && (lru.maxWeight <= 0 || lru.weight+entry.weight <= lru.maxWeight) {
+		return true
+	}
+	victim := lru.policy.victim()
+	if victim == nil {
+		return true
+	}
+	return lru.admission.estimate(entry.key) > lru.admission.estimate(victim.Value.(*listEntry).key)
+}
+
+// reject drops a new entry that has not been admitted, the lock must be held
+func (lru *lruCache) reject(entry *listEntry) {
+	lru.stats.Rejections++
+	if lru.onEvicted != nil {
+		value, _ := lru.valueOf(entry)
+		lru.onEvicted(entry.key, value, ReasonRejected)
+	}
+	releaseEntry(entry)
+}
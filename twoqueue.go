@@ -0,0 +1,85 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "container/list"
+
+// twoQueueProtected is the share of the capacity for protected entries
+const twoQueueProtected = 0.75
+
+// twoQueuePolicy keeps the new entries in a probation queue and moves
+// them to a protected one on their next hit. When the protected queue is
+// full its least recently used entry is demoted back on probation, and
+// the victims are taken from the probation queue first
+type twoQueuePolicy struct {
+	lru       *lruCache
+	probation *list.List
+	protected *list.List
+	nodes     map[*list.Element]queueNode
+}
+
+func newTwoQueuePolicy(lru *lruCache) *twoQueuePolicy {
+	p := &twoQueuePolicy{lru: lru}
+	p.reset()
+	return p
+}
+
+func (p *twoQueuePolicy) adding(key Key) {}
+
+func (p *twoQueuePolicy) add(elem *list.Element) {
+	p.nodes[elem] = queueNode{lst: p.probation, elem: p.probation.PushFront(elem)}
+}
+
+func (p *twoQueuePolicy) hit(elem *list.Element) {
+	node, ok := p.nodes[elem]
+	if !ok {
+		return
+	}
+	if node.lst == p.protected {
+		p.protected.MoveToFront(node.elem)
+		return
+	}
+	p.probation.Remove(node.elem)
+	p.nodes[elem] = queueNode{lst: p.protected, elem: p.protected.PushFront(elem)}
+	if limit := int(float64(p.lru.capacity()) * twoQueueProtected); p.protected.Len() > limit {
+		demoted := p.protected.Remove(p.protected.Back()).(*list.Element)
+		p.nodes[demoted] = queueNode{lst: p.probation, elem: p.probation.PushFront(demoted)}
+	}
+}
+
+func (p *twoQueuePolicy) remove(elem *list.Element, reason EvictionReason) {
+	if node, ok := p.nodes[elem]; ok {
+		node.lst.Remove(node.elem)
+		delete(p.nodes, elem)
+	}
+}
+
+func (p *twoQueuePolicy) victim() *list.Element {
+	back := p.probation.Back()
+	if back == nil {
+		back = p.protected.Back()
+	}
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*list.Element)
+}
+
+func (p *twoQueuePolicy) reset() {
+	p.probation, p.protected = list.New(), list.New()
+	p.nodes = map[*list.Element]queueNode{}
+}
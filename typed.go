@@ -0,0 +1,140 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "time"
+
+// Cache is a type safe view of an Interface, keys and values go in and
+// come out with their own types, so callers need no type assertions. It
+// is a facade: the values are still boxed in the underlying cache, and
+// the operations without a typed counterpart are reached with Untyped.
+// The values of another type, put through Untyped, are seen as missing
+type Cache[K comparable, V any] struct {
+	c Interface
+}
+
+// NewTypedCache will create a typed cache with the configs. The callbacks
+// in the config still receive untyped keys and values
+func NewTypedCache[K comparable, V any](config Config) *Cache[K, V] {
+	return TypedCache[K, V](NewCacheWithConfig(config))
+}
+
+// TypedCache will create a typed view of c, c should only hold keys of
+// type K and values of type V
+func TypedCache[K comparable, V any](c Interface) *Cache[K, V] {
+	return &Cache[K, V]{c: Wrap(c)}
+}
+
+// Untyped returns the underlying cache, for the operations that have no
+// typed counterpart
+func (c *Cache[K, V]) Untyped() Interface {
+	return c.c
+}
+
+func (c *Cache[K, V]) Put(key K, value V) {
+	c.c.Put(key, value)
+}
+
+func (c *Cache[K, V]) PutWithTimeout(key K, value V, t time.Duration) {
+	c.c.PutWithTimeout(key, value, t)
+}
+
+func (c *Cache[K, V]) PutWithDeadline(key K, value V, deadline time.Time) {
+	c.c.PutWithDeadline(key, value, deadline)
+}
+
+// Get reports false as well when the value of the key is not a V
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.c.Get(key)
+	v, isV := typed[V](value)
+	return v, ok && isV
+}
+
+// Peek is Get without counting the read
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	value, ok := c.c.Peek(key)
+	v, isV := typed[V](value)
+	return v, ok && isV
+}
+
+func (c *Cache[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// GetOrStore replaces a value of another type with def, like it stores
+// def for a missing key
+func (c *Cache[K, V]) GetOrStore(key K, def V, t time.Duration) (V, bool) {
+	value, loaded := c.c.GetOrStore(key, def, t)
+	if v, ok := typed[V](value); ok {
+		return v, loaded
+	}
+	c.c.PutWithTimeout(key, def, t)
+	return def, false
+}
+
+// Del removes the key and reports whether it was in the cache, its value
+// is the zero V if it was of another type
+func (c *Cache[K, V]) Del(key K) (V, bool) {
+	value, ok := c.c.DelE(key)
+	v, _ := typed[V](value)
+	return v, ok
+}
+
+// Keys returns the keys of type K
+func (c *Cache[K, V]) Keys() []K {
+	var keys []K
+	c.Range(func(key K, value V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range visits the entries whose key is a K and value a V
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	c.c.Range(func(key Key, value Value) bool {
+		k, ok := key.(K)
+		if !ok {
+			return true
+		}
+		v, ok := typed[V](value)
+		if !ok {
+			return true
+		}
+		return fn(k, v)
+	})
+}
+
+func (c *Cache[K, V]) Len() int {
+	return c.c.Len()
+}
+
+func (c *Cache[K, V]) Close() error {
+	return c.c.Close()
+}
+
+// typed converts a cached value back to V, a nil value becomes the zero V
+// and the values of other types report false
+func typed[V any](value Value) (V, bool) {
+	if value == nil {
+		var zero V
+		return zero, true
+	}
+	v, ok := value.(V)
+	return v, ok
+}
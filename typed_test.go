@@ -0,0 +1,80 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+type testUser struct {
+	name string
+}
+
+func TestTypedCache(t *testing.T) {
+	cache := NewTypedCache[int, *testUser](Config{MaxLen: 2})
+
+	cache.Put(1, &testUser{"testuser1"})
+	cache.PutWithTimeout(2, nil, time.Minute)
+
+	user, ok := cache.Get(1)
+	if !ok || user.name != "testuser1" {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 1, "testuser1", user)
+	}
+	if user, ok := cache.Get(2); !ok || user != nil {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 2, nil, user)
+	}
+	if user, ok := cache.Get(3); ok || user != nil {
+		t.Fatalf("test key %v exist status failed, expect %v, got %v", 3, false, ok)
+	}
+
+	actual, loaded := cache.GetOrStore(1, &testUser{"testuser2"}, time.Minute)
+	if !loaded || actual.name != "testuser1" {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 1, "testuser1", actual)
+	}
+
+	if user, ok := cache.Del(1); !ok || user.name != "testuser1" {
+		t.Fatalf("test key %v delete failed, expect %v, got %v", 1, "testuser1", user)
+	}
+	if cache.Len() != 1 || cache.Untyped().Len() != 1 {
+		t.Fatalf("test len failed, expect %v, got %v", 1, cache.Len())
+	}
+
+	// a value of another type put through the untyped cache is no hit
+	cache.Untyped().Put(4, "testuser4")
+	if user, ok := cache.Get(4); ok || user != nil {
+		t.Fatalf("test key %v mismatched type failed, expect %v, got %v", 4, false, ok)
+	}
+	if user, ok := cache.Del(4); !ok || user != nil || cache.Untyped().Contains(4) {
+		t.Fatalf("test key %v mismatched delete failed, expect %v, got %v", 4, true, ok)
+	}
+
+	// GetOrStore replaces a value of another type
+	cache.Untyped().Put(5, "testuser5")
+	user5 := &testUser{"testuser5"}
+	if user, loaded := cache.GetOrStore(5, user5, time.Minute); loaded || user != user5 {
+		t.Fatalf("test key %v mismatched get or store failed, expect %v, got %v", 5, user5, user)
+	}
+	if user, ok := cache.Get(5); !ok || user != user5 {
+		t.Fatalf("test key %v get after replace failed, expect %v, got %v", 5, user5, user)
+	}
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Fatalf("test keys failed, expect %v, got %v", 2, keys)
+	}
+}
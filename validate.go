@@ -0,0 +1,100 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidConfig is wrapped by the errors of Validate and of the Options
+var ErrInvalidConfig = errors.New("cache: invalid config")
+
+// Validate returns an error for the configs NewCacheWithConfig would
+// silently replace or misread: non positive sizes, negative durations,
+// sub-millisecond lifetimes and fractions out of [0, 1). The zero values
+// of the optional fields are valid and mean their defaults, such as
+// DefaultCacheTime, FlateCompressor, DefaultEqual, PolicyLRU or the
+// system clock
+func (config Config) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...)
+	}
+	switch {
+	case config.MaxLen <= 0 && (config.Weigher == nil || config.MaxWeight <= 0) && config.MaxBytes <= 0:
+		return invalid("MaxLen %d is not positive", config.MaxLen)
+	case config.MaxBytes < 0:
+		return invalid("MaxBytes %d is negative", config.MaxBytes)
+	case config.MaxBytes > 0 && config.MaxWeight > 0:
+		return invalid("MaxBytes and MaxWeight are both set")
+	case config.MaxWeight < 0:
+		return invalid("MaxWeight %d is negative", config.MaxWeight)
+	case config.MaxWeight > 0 && config.Weigher == nil:
+		return invalid("MaxWeight is set without a Weigher")
+	case config.CacheTime != 0 && config.CacheTime < time.Millisecond:
+		return invalid("CacheTime %v is below a millisecond", config.CacheTime)
+	case config.InsertTTL < 0:
+		return invalid("InsertTTL %v is negative", config.InsertTTL)
+	case config.HitTTL < 0:
+		return invalid("HitTTL %v is negative", config.HitTTL)
+	case config.NegativeCacheTime < 0:
+		return invalid("NegativeCacheTime %v is negative", config.NegativeCacheTime)
+	case config.StaleWhileRevalidate < 0:
+		return invalid("StaleWhileRevalidate %v is negative", config.StaleWhileRevalidate)
+	case config.SweepInterval < 0:
+		return invalid("SweepInterval %v is negative", config.SweepInterval)
+	case config.PutDebounce < 0:
+		return invalid("PutDebounce %v is negative", config.PutDebounce)
+	case config.Shards < 0:
+		return invalid("Shards %d is negative", config.Shards)
+	case config.MaxLen > 0 && config.Shards > config.MaxLen:
+		return invalid("Shards %d is above MaxLen %d", config.Shards, config.MaxLen)
+	case config.ShardRebalance < 0:
+		return invalid("ShardRebalance %v is negative", config.ShardRebalance)
+	case config.ReadBuffer < 0:
+		return invalid("ReadBuffer %d is negative", config.ReadBuffer)
+	case config.CompressThreshold < 0:
+		return invalid("CompressThreshold %d is negative", config.CompressThreshold)
+	case config.WriteBehindBuffer < 0:
+		return invalid("WriteBehindBuffer %d is negative", config.WriteBehindBuffer)
+	case config.CallbackBuffer < 0:
+		return invalid("CallbackBuffer %d is negative", config.CallbackBuffer)
+	case config.CallbackRateLimit < 0:
+		return invalid("CallbackRateLimit %v is negative", config.CallbackRateLimit)
+	case config.EvictionSamples < 0:
+		return invalid("EvictionSamples %d is negative", config.EvictionSamples)
+	case config.Policy < PolicyLRU || config.Policy > PolicySample:
+		return invalid("Policy %d is unknown", config.Policy)
+	case config.TTLJitter < 0 || config.TTLJitter >= 1:
+		return invalid("TTLJitter %v is out of [0, 1)", config.TTLJitter)
+	case config.RefreshAhead < 0 || config.RefreshAhead >= 1:
+		return invalid("RefreshAhead %v is out of [0, 1)", config.RefreshAhead)
+	case config.RefreshAhead > 0 && config.RefreshLoader == nil:
+		return invalid("RefreshAhead is set without a RefreshLoader")
+	}
+	return nil
+}
+
+// NewCacheWithConfigChecked will create a cache with the configs, unless
+// they fail Validate
+func NewCacheWithConfigChecked(config Config) (Interface, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewCacheWithConfig(config), nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestConfigValidate(t *testing.T) {
+	weigher := func(key Key, value Value) int64 { return 1 }
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"default", Config{MaxLen: DefaultMaxLen}, true},
+		{"full", Config{MaxLen: 10, CacheTime: time.Minute, Shards: 4, TTLJitter: 0.1, RefreshAhead: 0.2, RefreshLoader: func(key Key) (Value, time.Duration, error) { return nil, 0, nil }}, true},
+		{"weighted", Config{Weigher: weigher, MaxWeight: 100}, true},
+		{"max bytes", Config{MaxBytes: 1 << 20}, true},
+		{"zero max len", Config{}, false},
+		{"negative max len", Config{MaxLen: -1}, false},
+		{"weight without weigher", Config{MaxLen: 10, MaxWeight: 100}, false},
+		{"negative max bytes", Config{MaxLen: 10, MaxBytes: -1}, false},
+		{"max bytes and weight", Config{Weigher: weigher, MaxWeight: 100, MaxBytes: 100}, false},
+		{"no expiration", Config{MaxLen: 10, CacheTime: NoExpiration}, false},
+		{"sub-millisecond", Config{MaxLen: 10, CacheTime: time.Microsecond}, false},
+		{"negative hit ttl", Config{MaxLen: 10, HitTTL: -time.Second}, false},
+		{"negative shards", Config{MaxLen: 10, Shards: -1}, false},
+		{"more shards than max len", Config{MaxLen: 4, Shards: 8}, false},
+		{"unknown policy", Config{MaxLen: 10, Policy: Policy(100)}, false},
+		{"jitter", Config{MaxLen: 10, TTLJitter: 1}, false},
+		{"refresh without loader", Config{MaxLen: 10, RefreshAhead: 0.5}, false},
+	}
+	for _, test := range tests {
+		err := test.config.Validate()
+		if (err == nil) != test.valid || err != nil && !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("test config %s failed, expect valid %v, got %v", test.name, test.valid, err)
+		}
+		cache, err := NewCacheWithConfigChecked(test.config)
+		if (cache != nil) != test.valid || (err == nil) != test.valid {
+			t.Fatalf("test new config %s failed, expect valid %v, got %v/%v", test.name, test.valid, cache, err)
+		}
+		if cache != nil {
+			cache.Close()
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+// The write-ahead log is a sequence of records, each one is a 4 bytes
+// big-endian length followed by a walRecord encoded with its own gob
+// encoder, so that logs appended by several processes stay readable.
+// Keys and values of custom types must be registered with gob.Register.
+//
+// Every record goes straight to Config.WAL with a single Write call, it
+// is up to the writer to buffer or fsync it. The log only grows, to
+// compact it start a new log and Range the live entries into it.
+
+type walOp uint8
+
+const (
+	walPut walOp = iota + 1
+	walDel
+	// walStats holds the Stats counters, only in snapshots
+	walStats
+)
+
+type walRecord struct {
+	Op       walOp
+	Key      Key
+	Value    Value
+	Deadline time.Time
+	Tags     []string
+	Stats    *Stats
+}
+
+// logWAL appends a record to the log, the lock must be held to keep the
+// records in the order they were applied
+func (lru *lruCache) logWAL(rec walRecord) {
+	if lru.wal == nil {
+		return
+	}
+	if err := writeWAL(lru.wal, rec); err != nil && lru.onWALError != nil {
+		lru.onWALError(err)
+	}
+}
+
+// writeWAL encodes a record to w with a single Write call
+func writeWAL(w io.Writer, rec walRecord) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(buf.Bytes(), uint32(buf.Len()-4))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReplayWAL applies the records read from r to the cache, puts whose
+// deadline has already passed are skipped. A record cut short at the end of
+// the log, like the one being written during a crash, ends the replay
+// silently. The replayed operations are not written to the cache's own log
+func (lru *lruCache) ReplayWAL(r io.Reader) error {
+	if lru.closed() {
+		return ErrClosed
+	}
+	lru.Lock()
+	defer lru.Unlock()
+	return readWAL(r, lru.replay)
+}
+
+// readWAL decodes the records from r and hands them to apply in order
+func readWAL(r io.Reader, apply func(rec walRecord)) error {
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(r, body); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var rec walRecord
+		if err := gob.NewDecoder(bytes.NewReader(body)).Decode(&rec); err != nil {
+			return err
+		}
+		apply(rec)
+	}
+}
+
+// replay applies a record, the lock must be held
+func (lru *lruCache) replay(rec walRecord) {
+	if rec.Op == walStats {
+		if lru.persistStats && rec.Stats != nil {
+			lru.stats.Hits += rec.Stats.Hits
+			lru.stats.Misses += rec.Stats.Misses
+			lru.stats.Evictions += rec.Stats.Evictions
+			lru.stats.Expirations += rec.Stats.Expirations
+			lru.stats.Rejections += rec.Stats.Rejections
+		}
+		return
+	}
+	if rec.Op == walPut && (rec.Deadline.IsZero() || rec.Deadline.After(lru.now())) {
+		value, compressed := lru.compress(rec.Value)
+		weight := lru.weigh(rec.Key, value)
+		lifetime := NoExpiration
+		if !rec.Deadline.IsZero() {
+			lifetime = rec.Deadline.Sub(lru.now())
+		}
+		lru.store(&listEntry{key: rec.Key, value: value, deadTime: rec.Deadline, lifetime: lifetime, compressed: compressed, weight: weight, tags: rec.Tags})
+		return
+	}
+	// a delete, or a put that has expired since and so replaced the
+	// earlier value with nothing
+	if elem, exists := lru.hash.get(rec.Key); exists {
+		if rec.Op == walDel {
+			lru.removeElem(elem, ReasonDeleted)
+		} else {
+			lru.expire(elem)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheReplayWAL(t *testing.T) {
+	var wal bytes.Buffer
+	onError := func(err error) { t.Fatalf("test wal write failed, got %v", err) }
+	cache := NewCacheWithConfig(Config{MaxLen: 4, WAL: &wal, OnWALError: onError})
+
+	cache.Put("testkey1", "testvalue1")
+	cache.PutWithTimeout("testkey2", "testvalue2", time.Second)
+	cache.Put("testkey3", "testvalue3")
+	cache.Put("testkey3", "testvalue4")
+	cache.PutInt(5, 5)
+	cache.Del("testkey1")
+	cache.Del("testkey6")
+
+	// the last record is torn by the crash, it must not break the replay
+	cache.Put("testkey7", "testvalue7")
+	crashed := wal.Bytes()[:wal.Len()-3]
+
+	time.Sleep(1200 * time.Millisecond)
+	var replayedWAL bytes.Buffer
+	replayed := NewCacheWithConfig(Config{MaxLen: 4, WAL: &replayedWAL})
+	if err := replayed.(Persister).ReplayWAL(bytes.NewReader(crashed)); err != nil {
+		t.Fatalf("test replay failed, got %v", err)
+	}
+
+	testCases := []struct {
+		key          Key
+		expectExists bool
+		expectValue  Value
+	}{
+		{"testkey1", false, nil},
+		{"testkey2", false, nil},
+		{"testkey3", true, "testvalue4"},
+		{int64(5), true, 5},
+		{"testkey7", false, nil},
+	}
+	for _, tc := range testCases {
+		val, ok := replayed.Get(tc.key)
+		if ok != tc.expectExists {
+			t.Fatalf("test key %v exist status failed, expect %v, got %v", tc.key, tc.expectExists, ok)
+		}
+		if tc.expectExists && val != tc.expectValue {
+			t.Fatalf("test key %v value failed, expect %v, got %v", tc.key, tc.expectValue, val)
+		}
+	}
+	if replayed.Len() != 2 {
+		t.Fatalf("test len failed, expect %v, got %v", 2, replayed.Len())
+	}
+	if replayedWAL.Len() != 0 {
+		t.Fatalf("test replay logging failed, expect empty log, got %v bytes", replayedWAL.Len())
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loader func loads the value of a key, with a zero timeout the value is
+// cached for the default lifetime
+type Loader func(key Key) (Value, time.Duration, error)
+
+// WarmupError collects the keys that failed to load during Warmup
+type WarmupError struct {
+	Errors map[Key]error
+}
+
+func (e *WarmupError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for key, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%v: %v", key, err))
+	}
+	sort.Strings(msgs)
+	return fmt.Sprintf("warmup failed for %d keys: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Warmup loads the keys with no more than parallelism concurrent loader
+// calls and caches the results. A key that appears several times is only
+// loaded once. It is best-effort: a failed key does not stop the others,
+// and all failures are returned together as a *WarmupError
+func (lru *lruCache) Warmup(keys []Key, loader Loader, parallelism int) error {
+	if lru.closed() {
+		return ErrClosed
+	}
+	return warmup(keys, loader, parallelism, func(key Key, value Value, t time.Duration, err error) {
+		if err == nil {
+			lru.put(key, value, t)
+		}
+		lru.audit("Warmup", key, err == nil)
+	})
+}
+
+// warmup runs the loader for the keys and hands every result to store
+func warmup(keys []Key, loader Loader, parallelism int, store func(key Key, value Value, t time.Duration, err error)) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	var lock sync.Mutex
+	errs := map[Key]error{}
+	seen := map[Key]bool{}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(key Key) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			value, t, err := loader(key)
+			if err != nil {
+				lock.Lock()
+				errs[key] = err
+				lock.Unlock()
+			}
+			store(key, value, t, err)
+		}(key)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return &WarmupError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheWarmup(t *testing.T) {
+	const parallelism = 3
+	var running, maxRunning, loads int32
+	loader := func(key Key) (Value, time.Duration, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&loads, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if key == 7 {
+			return nil, 0, errors.New("load failed")
+		}
+		return key.(int) * 10, 0, nil
+	}
+
+	keys := []Key{}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, i)
+	}
+	keys = append(keys, 1, 2, 3)
+
+	cache := NewCacheWithConfig(Config{MaxLen: 100})
+	err := cache.(Warmer).Warmup(keys, loader, parallelism)
+	werr, ok := err.(*WarmupError)
+	if !ok || len(werr.Errors) != 1 || werr.Errors[7] == nil {
+		t.Fatalf("test warmup error failed, expect a failure of key 7, got %v", err)
+	}
+	if maxRunning > parallelism {
+		t.Fatalf("test parallelism failed, expect at most %v, got %v", parallelism, maxRunning)
+	}
+	if loads != 20 {
+		t.Fatalf("test loads failed, expect %v, got %v", 20, loads)
+	}
+	if cache.Len() != 19 {
+		t.Fatalf("test len failed, expect %v, got %v", 19, cache.Len())
+	}
+	if val, ok := cache.Get(19); !ok || val != 190 {
+		t.Fatalf("test key %v value failed, expect %v, got %v", 19, 190, val)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "math"
+
+// Weigher returns the weight of an entry counted against MaxWeight, e.g.
+// the size of the value in bytes. The values compressed by
+// CompressThreshold are weighed as stored, by their compressed bytes
+type Weigher func(key Key, value Value) int64
+
+// weigh returns the weight of an entry, 0 without a weigher
+func (lru *lruCache) weigh(key Key, value Value) int64 {
+	if lru.weigher == nil {
+		return 0
+	}
+	return lru.weigher(key, value)
+}
+
+// evictOverweight evicts the oldest entries until the total weight fits
+// in maxWeight, the lock must be held
+func (lru *lruCache) evictOverweight() {
+	if lru.maxWeight <= 0 {
+		return
+	}
+	for lru.lst.Len() > 0 && lru.weight > lru.maxWeight {
+		lru.evictOldest()
+	}
+}
+
+// Weight returns the total weight of the resident entries
+func (lru *lruCache) Weight() int64 {
+	lru.Lock()
+	defer lru.Unlock()
+	return lru.weight
+}
+
+// unboundedLen is the entry limit of the caches bounded by weight only
+const unboundedLen = math.MaxInt
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+func TestCacheMaxWeight(t *testing.T) {
+	var evicted []Key
+	cb := func(key Key, value Value) {
+		evicted = append(evicted, key)
+	}
+	weigher := func(key Key, value Value) int64 {
+		return int64(len(value.(string)))
+	}
+	cache := NewCacheWithConfig(Config{Callback: cb, Weigher: weigher, MaxWeight: 10})
+
+	cache.Put("testkey1", "aaaa")
+	cache.Put("testkey2", "bbbb")
+	cache.Get("testkey1")
+	cache.Put("testkey3", "cccc")
+	if cache.(Resizer).Weight() != 8 || cache.Contains("testkey2") {
+		t.Fatalf("test weight failed, expect %v without testkey2, got %v with keys %v", 8, cache.(Resizer).Weight(), cache.Keys())
+	}
+
+	// growing a value evicts others to make room for it
+	cache.Put("testkey3", "cccccccc")
+	if cache.(Resizer).Weight() != 8 || cache.Len() != 1 {
+		t.Fatalf("test replaced weight failed, expect %v, got %v with keys %v", 8, cache.(Resizer).Weight(), cache.Keys())
+	}
+
+	// a value heavier than the budget does not stay
+	cache.Put("testkey4", "ddddddddddd")
+	if cache.(Resizer).Weight() != 0 || cache.Len() != 0 {
+		t.Fatalf("test overweight failed, expect %v, got %v with keys %v", 0, cache.(Resizer).Weight(), cache.Keys())
+	}
+
+	expect := []Key{"testkey2", "testkey1", "testkey3", "testkey4"}
+	if len(evicted) != len(expect) {
+		t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+	}
+	for i := range expect {
+		if evicted[i] != expect[i] {
+			t.Fatalf("test evicted keys failed, expect %v, got %v", expect, evicted)
+		}
+	}
+
+	cache.Put("testkey5", "eeee")
+	cache.Del("testkey5")
+	if cache.(Resizer).Weight() != 0 {
+		t.Fatalf("test deleted weight failed, expect %v, got %v", 0, cache.(Resizer).Weight())
+	}
+}
@@ -16,7 +16,10 @@ limitations under the License.
 
 package cache
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 func Wrap(c Interface) Interface {
 	if c != nil {
@@ -25,11 +28,69 @@ func Wrap(c Interface) Interface {
 	return &empty{}
 }
 
+// Middleware decorates a cache, e.g. to log, instrument or restrict its
+// operations
+type Middleware func(c Interface) Interface
+
+// Chain decorates c with mws, the first one is the outermost and sees the
+// calls first
+func Chain(c Interface, mws ...Middleware) Interface {
+	c = Wrap(c)
+	for i := len(mws) - 1; i >= 0; i-- {
+		c = mws[i](c)
+	}
+	return c
+}
+
 type empty struct{}
 
-func (e *empty) Put(key Key, value Value)                             {}
-func (e *empty) PutWithTimeout(key Key, value Value, t time.Duration) {}
-func (e *empty) Get(key Key) (Value, bool)                            { return nil, false }
-func (e *empty) Del(key Key) Value                                    { return nil }
-func (e *empty) Len() int                                             { return 0 }
-func (e *empty) Close()                                               {}
+func (e *empty) Put(key Key, value Value)                                 {}
+func (e *empty) PutWithTimeout(key Key, value Value, t time.Duration)     {}
+func (e *empty) PutWithDeadline(key Key, value Value, deadline time.Time) {}
+func (e *empty) PutCtx(ctx context.Context, key Key, value Value) error   { return ctx.Err() }
+func (e *empty) Get(key Key) (Value, bool)                                { return nil, false }
+func (e *empty) GetCtx(ctx context.Context, key Key) (Value, bool, error) {
+	return nil, false, ctx.Err()
+}
+func (e *empty) Peek(key Key) (Value, bool)                                   { return nil, false }
+func (e *empty) GetEntry(key Key) (Entry, bool)                               { return Entry{}, false }
+func (e *empty) Contains(key Key) bool                                        { return false }
+func (e *empty) TTL(key Key) (time.Duration, bool)                            { return 0, false }
+func (e *empty) Touch(key Key, d time.Duration) bool                          { return false }
+func (e *empty) GetOrStore(key Key, def Value, t time.Duration) (Value, bool) { return def, false }
+func (e *empty) GetOrLoad(key Key, load LoadFunc) (Value, error)              { return load(key) }
+func (e *empty) GetOrLoadCtx(ctx context.Context, key Key, load LoadCtxFunc) (Value, error) {
+	return load(ctx, key)
+}
+func (e *empty) LockKey(key Key) func()                                          { return func() {} }
+func (e *empty) Add(key Key, value Value) bool                                   { return false }
+func (e *empty) Replace(key Key, value Value) bool                               { return false }
+func (e *empty) CompareAndSwap(key Key, old, new Value) bool                     { return false }
+func (e *empty) IncrementInt64(key Key, delta int64) (int64, error)              { return delta, nil }
+func (e *empty) DecrementInt64(key Key, delta int64) (int64, error)              { return -delta, nil }
+func (e *empty) PutString(key string, value Value)                               {}
+func (e *empty) GetString(key string) (Value, bool)                              { return nil, false }
+func (e *empty) PutInt(key int64, value Value)                                   {}
+func (e *empty) GetInt(key int64) (Value, bool)                                  { return nil, false }
+func (e *empty) PutMulti(entries map[Key]Value)                                  {}
+func (e *empty) PutMultiWithTimeout(entries map[Key]Value, t time.Duration)      {}
+func (e *empty) PutNotFound(key Key)                                             {}
+func (e *empty) GetMulti(keys []Key) map[Key]Value                               { return map[Key]Value{} }
+func (e *empty) Del(key Key) Value                                               { return nil }
+func (e *empty) DelE(key Key) (Value, bool)                                      { return nil, false }
+func (e *empty) DelMulti(keys []Key) int                                         { return 0 }
+func (e *empty) DelPrefix(prefix string) int                                     { return 0 }
+func (e *empty) DelFunc(fn func(key Key, value Value) bool) int                  { return 0 }
+func (e *empty) PutTagged(key Key, value Value, tags ...string)                  {}
+func (e *empty) InvalidateTag(tag string) int                                    { return 0 }
+func (e *empty) Len() int                                                        { return 0 }
+func (e *empty) Keys() []Key                                                     { return nil }
+func (e *empty) Stats() Stats                                                    { return Stats{} }
+func (e *empty) Range(fn func(key Key, value Value) bool)                        {}
+func (e *empty) SetBypass(bypass bool)                                           {}
+func (e *empty) Flush()                                                          {}
+func (e *empty) Purge()                                                          {}
+func (e *empty) Close() error                                                    { return nil }
+func (e *empty) Namespace(name string) Interface                                 { return e }
+func (e *empty) Clone() Interface                                                { return e }
+func (e *empty) Merge(other Interface, conflict func(key Key, a, b Value) Value) {}
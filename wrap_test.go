@@ -0,0 +1,60 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"testing"
+
+	. "github.com/leopoldxx/cache"
+)
+
+// traced records the Gets it sees in calls
+type traced struct {
+	Interface
+	name  string
+	calls *[]string
+}
+
+func (t *traced) Get(key Key) (Value, bool) {
+	*t.calls = append(*t.calls, t.name)
+	return t.Interface.Get(key)
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(c Interface) Interface { return &traced{Interface: c, name: name, calls: &calls} }
+	}
+	cache := Chain(NewCache(), trace("outer"), trace("inner"))
+	defer cache.Close()
+	cache.Put("testkey1", "testvalue1")
+	if v, _ := cache.Get("testkey1"); v != "testvalue1" {
+		t.Fatalf("test key %s failed, expect %v, got %v", "testkey1", "testvalue1", v)
+	}
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "inner" {
+		t.Fatalf("test chain order failed, expect %v, got %v", []string{"outer", "inner"}, calls)
+	}
+
+	if Chain(nil) == nil || Chain(NewCache()) == nil {
+		t.Fatalf("test empty chain failed, expect a cache, got %v", nil)
+	}
+	cache = Chain(NewCache(), ReadOnly)
+	cache.Put("testkey1", "testvalue1")
+	if _, ok := cache.Get("testkey1"); ok {
+		t.Fatalf("test read only key %s failed, expect %v, got %v", "testkey1", false, ok)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 leopoldxx@gmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "sync"
+
+// DefaultWriteBehindBuffer is the number of store writes a write-behind
+// cache holds back before the puts and deletes start waiting for room
+const DefaultWriteBehindBuffer = 1024
+
+type storeOp struct {
+	key   Key
+	value Value
+	del   bool
+	// flushed is closed when the op is reached, for the markers of Flush
+	flushed chan struct{}
+}
+
+// writeBehind applies the writes to the store in a background goroutine,
+// in the order they were made
+type writeBehind struct {
+	lru   *lruCache
+	queue chan storeOp
+	done  chan struct{}
+
+	closed bool
+	sync.Mutex
+}
+
+func newWriteBehind(lru *lruCache, buffer int) *writeBehind {
+	wb := &writeBehind{
+		lru:   lru,
+		queue: make(chan storeOp, buffer),
+		done:  make(chan struct{}),
+	}
+	go wb.run()
+	return wb
+}
+
+func (wb *writeBehind) run() {
+	defer close(wb.done)
+	for op := range wb.queue {
+		wb.apply(op)
+	}
+}
+
+func (wb *writeBehind) apply(op storeOp) {
+	var err error
+	switch {
+	case op.flushed != nil:
+		close(op.flushed)
+	case op.del:
+		err = wb.lru.backing.Delete(op.key)
+	default:
+		err = wb.lru.backing.Save(op.key, op.value)
+	}
+	if err != nil {
+		wb.lru.storeError(op.key, err)
+	}
+}
+
+// push queues a write, it waits for room while the queue is full. Once
+// closed the writes are applied right away
+func (wb *writeBehind) push(op storeOp) {
+	wb.Lock()
+	defer wb.Unlock()
+	if wb.closed {
+		wb.apply(op)
+		return
+	}
+	wb.queue <- op
+}
+
+// flush waits until the writes queued so far have been applied
+func (wb *writeBehind) flush() {
+	flushed := make(chan struct{})
+	wb.push(storeOp{flushed: flushed})
+	<-flushed
+}
+
+// close waits until all the queued writes have been applied
+func (wb *writeBehind) close() {
+	wb.Lock()
+	if wb.closed {
+		wb.Unlock()
+		return
+	}
+	wb.closed = true
+	close(wb.queue)
+	wb.Unlock()
+	<-wb.done
+}
+
+// Flush waits until the writes queued for the store so far have been
+// applied, it returns right away unless WriteBehind is set
+func (lru *lruCache) Flush() {
+	if lru.writeBehind != nil {
+		lru.writeBehind.flush()
+	}
+}